@@ -0,0 +1,855 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/justikun/metadata-viewer/pkg/jpg"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/photoshop"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// outputFlags are shared by the commands that print metadata.
+type outputFlags struct {
+	format    string
+	json      bool
+	flat      bool
+	recursive bool
+	tags      string
+	verbose   bool
+}
+
+func (o *outputFlags) register(fset *flag.FlagSet) {
+	fset.StringVar(&o.format, "format", "text", "output format: text or json")
+	fset.BoolVar(&o.json, "json", false, "shorthand for -format json")
+	fset.BoolVar(&o.flat, "flat", false, "with -format json or -json, use flat Group:Tag keys instead of grouping by IFD")
+	fset.BoolVar(&o.recursive, "r", false, "descend into sub directories")
+	fset.StringVar(&o.tags, "tags", "", "comma separated tags, XMP properties, IPTC datasets and Photoshop resources to show, e.g. Make,Exif:ExposureTime,0x0132,dc:title,Keywords")
+	fset.BoolVar(&o.verbose, "v", false, "report progress and tag validation problems on stderr")
+}
+
+func (o *outputFlags) validate() error {
+	if o.format != "text" && o.format != "json" {
+		return fmt.Errorf("unknown output format %q", o.format)
+	}
+	if o.json {
+		o.format = "json"
+	}
+	return nil
+}
+
+// decodeAll decodes every file and reports failures on stderr.
+// Images that decoded partially are still returned.
+func decodeAll(files []string, o outputFlags) ([]*metadata.ImageData, bool) {
+	var images []*metadata.ImageData
+	failed := false
+	for _, path := range files {
+		if o.verbose {
+			fmt.Fprintf(os.Stderr, "decoding %s\n", path)
+		}
+		imgData, err := metadata.DecodeFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+		}
+		if imgData == nil {
+			continue
+		}
+		if err := metadata.CheckExtension(path, imgData.Format); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: warning: %v\n", path, err)
+		}
+		for _, warning := range imgData.Warnings {
+			fmt.Fprintf(os.Stderr, "%s: warning: %v\n", path, warning)
+		}
+		if o.verbose {
+			reportInvalidTags(os.Stderr, imgData)
+		}
+		images = append(images, filterTags(imgData, parseTagSelection(o.tags)))
+	}
+	return images, failed
+}
+
+func runRead(args []string) int {
+	var o outputFlags
+	fset := newFlagSet("read", "<files/dirs...>")
+	o.register(fset)
+	if code, ok := parseFlags(fset, args); !ok {
+		return code
+	}
+	if err := o.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return exitUsage
+	}
+
+	files, err := collectFiles(fset.Args(), o.recursive)
+	failed := err != nil
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	images, decodeFailed := decodeAll(files, o)
+	failed = failed || decodeFailed
+
+	if o.format == "json" {
+		opts := metadata.JSONOptions{Flat: o.flat, Indent: "  "}
+		if err := metadata.EncodeJSONList(os.Stdout, images, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	} else {
+		for _, imgData := range images {
+			printImage(os.Stdout, imgData)
+		}
+	}
+
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runDump(args []string) int {
+	var o outputFlags
+	fset := newFlagSet("dump", "<files/dirs...>")
+	fset.BoolVar(&o.recursive, "r", false, "descend into sub directories")
+	fset.BoolVar(&o.verbose, "v", false, "report progress and tag validation problems on stderr")
+	if code, ok := parseFlags(fset, args); !ok {
+		return code
+	}
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return exitUsage
+	}
+
+	files, err := collectFiles(fset.Args(), o.recursive)
+	failed := err != nil
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	images, decodeFailed := decodeAll(files, o)
+	for _, imgData := range images {
+		var segments []jpg.Segment
+		if imgData.Format == metadata.FormatJPEG {
+			// segments holds what was scanned before the error
+			if segments, err = scanSegments(imgData.ImagePath); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", imgData.ImagePath, err)
+				failed = true
+			}
+		}
+		dumpImage(os.Stdout, imgData, segments)
+	}
+
+	if failed || decodeFailed {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runStrip(args []string) int {
+	fset := newFlagSet("strip", "<files/dirs...>")
+	output := fset.String("o", "", "output file (single input only), default <name>.stripped<ext>")
+	keepICC := fset.Bool("keep-icc", true, "keep the ICC color profile")
+	recursive := fset.Bool("r", false, "descend into sub directories")
+	verbose := fset.Bool("v", false, "report every written file")
+	if code, ok := parseFlags(fset, args); !ok {
+		return code
+	}
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return exitUsage
+	}
+
+	files, err := collectFiles(fset.Args(), *recursive)
+	failed := err != nil
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if *output != "" && len(files) != 1 {
+		fmt.Fprintln(os.Stderr, "-o needs exactly one input file")
+		return exitUsage
+	}
+
+	for _, path := range files {
+		dst := *output
+		if dst == "" {
+			ext := filepath.Ext(path)
+			dst = strings.TrimSuffix(path, ext) + ".stripped" + ext
+		}
+		if err := stripFile(path, dst, *keepICC); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		if *verbose {
+			fmt.Fprintf(os.Stderr, "%s -> %s\n", path, dst)
+		}
+	}
+
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func stripFile(src, dst string, keepICC bool) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	format, err := metadata.Detect(in, info.Size())
+	if err != nil {
+		return err
+	}
+	if format != metadata.FormatJPEG {
+		return fmt.Errorf("only JPEG files can be stripped, not %s", format)
+	}
+
+	if dstInfo, err := os.Stat(dst); err == nil && os.SameFile(info, dstInfo) {
+		return errors.New("the output file is the input file")
+	}
+
+	// write next to dst and rename, so a failed strip never leaves a partial file
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := jpg.Strip(in, info.Size(), out, keepICC); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	if err := os.Rename(out.Name(), dst); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return nil
+}
+
+func runThumb(args []string) int {
+	fset := newFlagSet("thumb", "<files/dirs...>")
+	outDir := fset.String("o", "", "output directory, default the directory of each image")
+	recursive := fset.Bool("r", false, "descend into sub directories")
+	verbose := fset.Bool("v", false, "report progress on stderr")
+	if code, ok := parseFlags(fset, args); !ok {
+		return code
+	}
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return exitUsage
+	}
+
+	files, err := collectFiles(fset.Args(), *recursive)
+	failed := err != nil
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	for _, path := range files {
+		if *verbose {
+			fmt.Fprintf(os.Stderr, "decoding %s\n", path)
+		}
+		if err := writeThumbnails(os.Stdout, path, *outDir); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// writeThumbnails writes every thumbnail of the image at path to
+// <name>.<source>.jpg and lists them on w.
+func writeThumbnails(w io.Writer, path, outDir string) error {
+	imgData, err := metadata.DecodeFile(path)
+	if imgData == nil {
+		return err
+	}
+	// a partially decoded image may still hold thumbnails
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if outDir == "" {
+		outDir = filepath.Dir(path)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, thumb := range imgData.Thumbnails() {
+		data, err := thumb.Read(in)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		width, height, ok := metadata.JPEGSize(data)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s preview at %d is not a JPEG stream", thumb.Source, thumb.Offset))
+			continue
+		}
+		if thumb.Width == 0 {
+			thumb.Width, thumb.Height = width, height
+		}
+		slug := strings.ToLower(strings.ReplaceAll(thumb.Source, " ", "-"))
+		dst := filepath.Join(outDir, name+"."+slug+".jpg")
+		if err := os.WriteFile(dst, data, 0o644); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %s %dx%d, %d bytes at %d -> %s\n", path, thumb.Source, thumb.Width, thumb.Height, thumb.Length, thumb.Offset, dst)
+	}
+	return errors.Join(errs...)
+}
+
+func runTags(args []string) int {
+	fset := newFlagSet("tags", "")
+	ifd := fset.String("ifd", "", "only list the tags of one IFD: main, exif, gps, introp, panasonic, canon or mpf")
+	if code, ok := parseFlags(fset, args); !ok {
+		return code
+	}
+
+	ifdTypes := []metadata.IFDtype{metadata.IFDMAIN, metadata.IFDEXIF, metadata.IFDGPS, metadata.IFDINTROP, metadata.IFDPANASONIC, metadata.IFDCANON, metadata.IFDMPF}
+	if *ifd != "" {
+		ifdTypes = []metadata.IFDtype{metadata.IFDtype(*ifd)}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "IFD\tID\tNAME\tTYPES\tCOUNT\tDESCRIPTION")
+	listed := 0
+	for _, ifdType := range ifdTypes {
+		for _, def := range metadata.TagDefs(ifdType) {
+			count := "any"
+			if def.Count != metadata.CountAny {
+				count = fmt.Sprint(def.Count)
+			}
+			types := "any"
+			if len(def.Types) > 0 {
+				names := make([]string, len(def.Types))
+				for i, dt := range def.Types {
+					names[i] = dt.String()
+				}
+				types = strings.Join(names, "|")
+			}
+			fmt.Fprintf(tw, "%s\t0x%04X\t%s\t%s\t%s\t%s\n", def.IFD, def.ID, def.Name, types, count, def.Description)
+			listed++
+		}
+	}
+	tw.Flush()
+
+	if listed == 0 {
+		fmt.Fprintf(os.Stderr, "unknown IFD %q\n", *ifd)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// printImage writes the tags of imgData as an aligned table.
+func printImage(w io.Writer, imgData *metadata.ImageData) {
+	fmt.Fprintf(w, "%s (%s)\n", imgData.ImagePath, imgData.Format)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if jfif := imgData.JFIF; jfif != nil {
+		fmt.Fprintf(tw, "  JFIF\tVersion\t%d.%02d\n", jfif.VersionMajor, jfif.VersionMinor)
+		fmt.Fprintf(tw, "  JFIF\tDensity\t%dx%d (units %d)\n", jfif.XDensity, jfif.YDensity, jfif.DensityUnits)
+		if jfif.ThumbWidth > 0 {
+			fmt.Fprintf(tw, "  JFIF\tThumbnail\t%dx%d RGB\n", jfif.ThumbWidth, jfif.ThumbHeight)
+		}
+	}
+	if jfxx := imgData.JFXX; jfxx != nil {
+		fmt.Fprintf(tw, "  JFXX\tThumbnail\tformat 0x%02X, %d bytes at %d\n", jfxx.Format, len(jfxx.Data), jfxx.Offset)
+	}
+	if pngData := imgData.PNG; pngData != nil {
+		printPNG(tw, pngData)
+	}
+	if webp := imgData.WebP; webp != nil {
+		printWebP(tw, webp)
+	}
+	if heif := imgData.HEIF; heif != nil {
+		printHEIF(tw, heif)
+	}
+	if raf := imgData.RAF; raf != nil {
+		printRAF(tw, raf)
+	}
+	if profile := imgData.ICC; profile != nil {
+		printICC(tw, profile)
+	}
+	if ps := imgData.Photoshop; ps != nil {
+		printPhotoshop(tw, ps)
+	}
+	if mpf := imgData.MPF; mpf != nil {
+		for i, image := range mpf.Images {
+			fmt.Fprintf(tw, "  MPF\tImage %d\t%s, %d bytes at %d\n", i+1, image.TypeName(), image.Length, image.Offset)
+		}
+	}
+	for _, preview := range imgData.Previews {
+		size := "unknown size"
+		if preview.Width > 0 {
+			size = fmt.Sprintf("%dx%d", preview.Width, preview.Height)
+		}
+		fmt.Fprintf(tw, "  Preview\t%s\t%s, %d bytes at %d\n", preview.Source, size, preview.Length, preview.Offset)
+	}
+	for _, group := range imgData.TagGroups() {
+		for _, tag := range group.Tags {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", group.Name, tag.Name, tag.DataString())
+		}
+	}
+	if imgData.IPTC != nil {
+		for _, dataset := range imgData.IPTC.Datasets {
+			fmt.Fprintf(tw, "  IPTC\t%s\t%s\n", dataset.Name, strings.Join(dataset.Values, ", "))
+		}
+	}
+	if imgData.XMP != nil {
+		for _, prop := range imgData.XMP.Properties {
+			fmt.Fprintf(tw, "  XMP\t%s\t%s\n", prop.QualifiedName(), prop)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+func printPNG(w io.Writer, pngData *metadata.PNG) {
+	fmt.Fprintf(w, "  PNG\tImage Size\t%dx%d, %d bit, color type %d\n", pngData.Width, pngData.Height, pngData.BitDepth, pngData.ColorType)
+	if pngData.Time != nil {
+		fmt.Fprintf(w, "  PNG\tModify Date\t%s\n", pngData.Time.Format(time.RFC3339))
+	}
+	if phys := pngData.Phys; phys != nil {
+		fmt.Fprintf(w, "  PNG\tPixels Per Unit\t%dx%d (unit %d)\n", phys.PixelsPerUnitX, phys.PixelsPerUnitY, phys.Unit)
+	}
+	if pngData.Gamma != nil {
+		fmt.Fprintf(w, "  PNG\tGamma\t%g\n", *pngData.Gamma)
+	}
+	if c := pngData.Chromaticities; c != nil {
+		fmt.Fprintf(w, "  PNG\tWhite Point\t%g %g\n", c.WhiteX, c.WhiteY)
+		fmt.Fprintf(w, "  PNG\tPrimaries\t%g %g %g %g %g %g\n", c.RedX, c.RedY, c.GreenX, c.GreenY, c.BlueX, c.BlueY)
+	}
+	if pngData.SRGBIntent != nil {
+		fmt.Fprintf(w, "  PNG\tsRGB Intent\t%d\n", *pngData.SRGBIntent)
+	}
+	if icc := pngData.ICCProfile; icc != nil {
+		fmt.Fprintf(w, "  PNG\tICC Profile\t%s, %d bytes\n", icc.Name, len(icc.Profile))
+	}
+	for _, text := range pngData.Text {
+		fmt.Fprintf(w, "  PNG\t%s\t%s\n", text.Keyword, strings.ReplaceAll(text.Text, "\n", " "))
+	}
+}
+
+func printWebP(w io.Writer, webp *metadata.WebP) {
+	fmt.Fprintf(w, "  WebP\tCanvas Size\t%dx%d\n", webp.Width, webp.Height)
+	if webp.Extended {
+		var features []string
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"ICC", webp.Features.ICC}, {"Alpha", webp.Features.Alpha}, {"EXIF", webp.Features.EXIF},
+			{"XMP", webp.Features.XMP}, {"Animation", webp.Features.Animation},
+		} {
+			if f.set {
+				features = append(features, f.name)
+			}
+		}
+		fmt.Fprintf(w, "  WebP\tFeatures\t%s\n", strings.Join(features, ", "))
+	}
+	if webp.Features.Animation {
+		fmt.Fprintf(w, "  WebP\tFrames\t%d\n", webp.Frames)
+	}
+	if webp.LoopCount != nil {
+		fmt.Fprintf(w, "  WebP\tLoop Count\t%d\n", *webp.LoopCount)
+	}
+	if len(webp.ICCProfile) > 0 {
+		fmt.Fprintf(w, "  WebP\tICC Profile\t%d bytes\n", len(webp.ICCProfile))
+	}
+}
+
+func printHEIF(w io.Writer, heif *metadata.HEIF) {
+	fmt.Fprintf(w, "  HEIF\tBrands\t%s (%s)\n", heif.MajorBrand, strings.Join(heif.CompatibleBrands, ", "))
+	fmt.Fprintf(w, "  HEIF\tImage Size\t%dx%d\n", heif.Width, heif.Height)
+	for _, item := range heif.Items {
+		desc := item.Type
+		if item.ContentType != "" {
+			desc += " " + item.ContentType
+		}
+		if item.Width > 0 {
+			desc += fmt.Sprintf(" %dx%d", item.Width, item.Height)
+		}
+		if item.ID == heif.PrimaryItem {
+			desc += " (primary)"
+		}
+		fmt.Fprintf(w, "  HEIF\tItem %d\t%s\n", item.ID, desc)
+	}
+}
+
+func printRAF(w io.Writer, raf *metadata.RAF) {
+	fmt.Fprintf(w, "  RAF\tCamera\t%s (ID %s, format %s)\n", raf.Model, raf.CameraID, raf.FormatVersion)
+	fmt.Fprintf(w, "  RAF\tCFA\t%d bytes at %d\n", raf.CFALength, raf.CFAOffset)
+	if raf.RawWidth > 0 {
+		fmt.Fprintf(w, "  RAF\tRaw Image Size\t%dx%d\n", raf.RawWidth, raf.RawHeight)
+	}
+	if raf.CroppedWidth > 0 {
+		fmt.Fprintf(w, "  RAF\tCropped Size\t%dx%d at %d,%d\n", raf.CroppedWidth, raf.CroppedHeight, raf.CropLeft, raf.CropTop)
+	}
+	if len(raf.WBLevels) > 0 {
+		fmt.Fprintf(w, "  RAF\tWB GRGB Levels\t%v\n", raf.WBLevels)
+	}
+	for _, record := range raf.Records {
+		name := record.Name
+		if name == "" {
+			name = "Unknown"
+		}
+		fmt.Fprintf(w, "  RAF\tRecord 0x%04X\t%s, %d bytes\n", record.Tag, name, len(record.Data))
+	}
+}
+
+func printICC(w io.Writer, profile *metadata.ICCProfile) {
+	if profile.Description != "" {
+		fmt.Fprintf(w, "  ICC\tDescription\t%s\n", profile.Description)
+	}
+	fmt.Fprintf(w, "  ICC\tProfile\t%s %s (%s), PCS %s, version %s, %d bytes\n",
+		profile.ColorSpace, profile.Class, profile.ClassName(), profile.PCS, profile.Version, profile.Size)
+	fmt.Fprintf(w, "  ICC\tRendering Intent\t%d\n", profile.RenderingIntent)
+	if profile.Created != nil {
+		fmt.Fprintf(w, "  ICC\tCreated\t%s\n", profile.Created.Format(time.RFC3339))
+	}
+	if profile.Copyright != "" {
+		fmt.Fprintf(w, "  ICC\tCopyright\t%s\n", profile.Copyright)
+	}
+	if wp := profile.WhitePoint; wp != nil {
+		fmt.Fprintf(w, "  ICC\tWhite Point\t%.4f %.4f %.4f\n", wp[0], wp[1], wp[2])
+	}
+	signatures := make([]string, len(profile.Tags))
+	for i, tag := range profile.Tags {
+		signatures[i] = tag.Signature
+	}
+	fmt.Fprintf(w, "  ICC\tTags\t%s\n", strings.Join(signatures, " "))
+}
+
+func printPhotoshop(w io.Writer, ps *metadata.Photoshop) {
+	if res := ps.Resolution; res != nil {
+		fmt.Fprintf(w, "  Photoshop\tResolution\t%gx%g (units %d/%d)\n", res.XResolution, res.YResolution, res.XUnit, res.YUnit)
+	}
+	if q := ps.JPEGQuality; q != nil {
+		fmt.Fprintf(w, "  Photoshop\tJPEG Quality\t%d, %s", q.Quality, q.Format)
+		if q.Scans > 0 {
+			fmt.Fprintf(w, ", %d scans", q.Scans)
+		}
+		fmt.Fprintln(w)
+	}
+	if thumb := ps.Thumbnail; thumb != nil {
+		fmt.Fprintf(w, "  Photoshop\tThumbnail\t%dx%d, format %d, %d bytes\n", thumb.Width, thumb.Height, thumb.Format, len(thumb.Data))
+	}
+	if ps.Copyrighted != nil {
+		fmt.Fprintf(w, "  Photoshop\tCopyrighted\t%t\n", *ps.Copyrighted)
+	}
+	if ps.URL != "" {
+		fmt.Fprintf(w, "  Photoshop\tURL\t%s\n", ps.URL)
+	}
+	if ps.IPTCDigest != "" {
+		fmt.Fprintf(w, "  Photoshop\tIPTC Digest\t%s\n", ps.IPTCDigest)
+	}
+	for _, resource := range ps.Resources {
+		name := resource.Name
+		if name == "" {
+			name = "Unknown"
+		}
+		if resource.BlockName != "" {
+			name += fmt.Sprintf(" %q", resource.BlockName)
+		}
+		fmt.Fprintf(w, "  Photoshop\tResource 0x%04X\t%s, %d bytes\n", resource.ID, name, resource.Size)
+	}
+}
+
+// dumpImage writes the JPEG marker segments, if any, and every IFD with its
+// offset followed by the raw tag entries.
+func dumpImage(w io.Writer, imgData *metadata.ImageData, segments []jpg.Segment) {
+	fmt.Fprintf(w, "%s (%s)\n", imgData.ImagePath, imgData.Format)
+	if len(segments) > 0 {
+		fmt.Fprintf(w, "JPEG segments, %d entries\n", len(segments))
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, segment := range segments {
+			fmt.Fprintf(tw, "  0x%02X\t%s\tat %d", segment.Marker, segment.Name(), segment.Offset)
+			if !segment.Standalone() {
+				fmt.Fprintf(tw, ", %d bytes", segment.Length)
+			}
+			if segment.ScanLength > 0 {
+				fmt.Fprintf(tw, ", %d bytes of scan data", segment.ScanLength)
+			}
+			fmt.Fprintln(tw)
+		}
+		tw.Flush()
+	}
+	for _, ifd := range imgData.MetaData.IFDs {
+		fmt.Fprintf(w, "IFD%d at offset %d, %d entries\n", ifd.Index, ifd.Offset, len(ifd.Tags))
+		dumpTags(w, ifd.Tags)
+	}
+	for _, ifd := range imgData.MetaData.SubIFDs {
+		fmt.Fprintf(w, "SubIFD%d at offset %d, %d entries\n", ifd.Index, ifd.Offset, len(ifd.Tags))
+		dumpTags(w, ifd.Tags)
+	}
+	subIFDs := []metadata.TagGroup{
+		{Name: "Exif", Tags: imgData.MetaData.ExifTags},
+		{Name: "GPS", Tags: imgData.MetaData.GPStags},
+		{Name: "Interop", Tags: imgData.MetaData.IntropTags},
+		{Name: "MakerNote", Tags: imgData.MetaData.MakerNoteTags},
+		{Name: "MPF", Tags: imgData.MetaData.MPFTags},
+	}
+	for _, group := range subIFDs {
+		if len(group.Tags) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s IFD, %d entries\n", group.Name, len(group.Tags))
+		dumpTags(w, group.Tags)
+	}
+	fmt.Fprintln(w)
+}
+
+// scanSegments lists the marker segments of a JPEG file, from SOI to EOI.
+func scanSegments(path string) ([]jpg.Segment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return jpg.ScanSegments(f, 0, info.Size())
+}
+
+func dumpTags(w io.Writer, tags []metadata.IFDtag) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, tag := range tags {
+		fmt.Fprintf(tw, "  0x%04X\t%s\t%s[%d]\t%s\n", tag.ID, tag.Name, tag.DataType, tag.DataCount, tag.DataString())
+	}
+	tw.Flush()
+}
+
+// reportInvalidTags checks every known tag against its definition.
+func reportInvalidTags(w io.Writer, imgData *metadata.ImageData) {
+	for _, group := range imgData.TagGroups() {
+		for _, tag := range group.Tags {
+			def, ok := metadata.LookupTag(group.Type, tag.ID)
+			if imgData.Format == metadata.FormatRW2 && group.Name == "IFD0" {
+				// Panasonic redefines the low tag IDs of IFD0
+				if panasonicDef, found := metadata.LookupTag(metadata.IFDPANASONIC, tag.ID); found {
+					def, ok = panasonicDef, true
+				}
+			}
+			if !ok {
+				continue
+			}
+			if err := def.Validate(tag); err != nil {
+				fmt.Fprintf(w, "%s: %s: %v\n", imgData.ImagePath, group.Name, err)
+			}
+		}
+	}
+}
+
+// tagSelector is one entry of the -tags flag.
+type tagSelector struct {
+	group string // lower case group name, "" matches every group
+	tag   string // lower case tag name without spaces or a 0xXXXX id
+}
+
+func parseTagSelection(list string) []tagSelector {
+	var selection []tagSelector
+	for _, item := range strings.Split(list, ",") {
+		item = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(item), " ", ""))
+		if item == "" {
+			continue
+		}
+		sel := tagSelector{tag: item}
+		if group, tag, ok := strings.Cut(item, ":"); ok {
+			sel = tagSelector{group: group, tag: tag}
+		}
+		selection = append(selection, sel)
+	}
+	return selection
+}
+
+func (s tagSelector) matches(group string, tag metadata.IFDtag) bool {
+	if s.group != "" && s.group != strings.ToLower(group) {
+		return false
+	}
+	name := strings.ToLower(strings.ReplaceAll(tag.Name, " ", ""))
+	return s.tag == name || s.tag == strings.ToLower(fmt.Sprintf("0x%04X", tag.ID))
+}
+
+func selectTags(selection []tagSelector, group string, tags []metadata.IFDtag) []metadata.IFDtag {
+	var selected []metadata.IFDtag
+	for _, tag := range tags {
+		for _, sel := range selection {
+			if sel.matches(group, tag) {
+				selected = append(selected, tag)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// filterTags returns a copy of imgData holding only the selected tags.
+func filterTags(imgData *metadata.ImageData, selection []tagSelector) *metadata.ImageData {
+	if len(selection) == 0 {
+		return imgData
+	}
+	filtered := *imgData
+	md := &filtered.MetaData
+	md.MainTags = selectTags(selection, "IFD0", md.MainTags)
+	md.ExifTags = selectTags(selection, "Exif", md.ExifTags)
+	md.GPStags = selectTags(selection, "GPS", md.GPStags)
+	md.IntropTags = selectTags(selection, "Interop", md.IntropTags)
+	md.MakerNoteTags = selectTags(selection, "MakerNote", md.MakerNoteTags)
+	md.MPFTags = selectTags(selection, "MPF", md.MPFTags)
+
+	md.IFDs = make([]metadata.IFD, len(imgData.MetaData.IFDs))
+	for i, ifd := range imgData.MetaData.IFDs {
+		ifd.Tags = selectTags(selection, fmt.Sprintf("IFD%d", ifd.Index), ifd.Tags)
+		md.IFDs[i] = ifd
+	}
+	md.SubIFDs = make([]metadata.IFD, len(imgData.MetaData.SubIFDs))
+	for i, ifd := range imgData.MetaData.SubIFDs {
+		ifd.Tags = selectTags(selection, fmt.Sprintf("SubIFD%d", ifd.Index), ifd.Tags)
+		md.SubIFDs[i] = ifd
+	}
+
+	if imgData.XMP != nil {
+		packet := *imgData.XMP
+		packet.Properties = selectXMP(selection, packet.Properties)
+		filtered.XMP = &packet
+	}
+	if imgData.IPTC != nil {
+		// like Photoshop, IPTC is left out when none of its datasets is selected
+		filtered.IPTC = nil
+		if datasets := selectIPTC(selection, imgData.IPTC.Datasets); len(datasets) > 0 {
+			iptc := *imgData.IPTC
+			iptc.Datasets = datasets
+			filtered.IPTC = &iptc
+		}
+	}
+	if imgData.Photoshop != nil {
+		filtered.Photoshop = selectPhotoshop(selection, imgData.Photoshop)
+	}
+	return &filtered
+}
+
+// selectIPTC keeps the datasets named by their dataset name (e.g. Keywords or
+// IPTC:By-line) or by record:id after the IPTC group (e.g. IPTC:2:25).
+func selectIPTC(selection []tagSelector, datasets []metadata.IPTCDataset) []metadata.IPTCDataset {
+	var selected []metadata.IPTCDataset
+	for _, dataset := range datasets {
+		name := strings.ToLower(strings.ReplaceAll(dataset.Name, " ", ""))
+		id := fmt.Sprintf("%d:%d", dataset.Record, dataset.ID)
+		for _, sel := range selection {
+			if sel.group != "" && sel.group != "iptc" {
+				continue
+			}
+			if sel.tag == name || sel.group == "iptc" && sel.tag == id {
+				selected = append(selected, dataset)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// selectPhotoshop returns a copy of ps holding only the selected image
+// resources. A resource is selected by its 0xXXXX id or its name (e.g.
+// Photoshop:URL), and the decoded fields follow their resource. It returns nil
+// when nothing is selected, so the section is left out.
+func selectPhotoshop(selection []tagSelector, ps *metadata.Photoshop) *metadata.Photoshop {
+	selected := func(id uint16, names ...string) bool {
+		for _, sel := range selection {
+			if sel.group != "" && sel.group != "photoshop" {
+				continue
+			}
+			if sel.tag == strings.ToLower(fmt.Sprintf("0x%04X", id)) {
+				return true
+			}
+			for _, name := range names {
+				if name != "" && sel.tag == strings.ToLower(strings.ReplaceAll(name, " ", "")) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	filtered := &metadata.Photoshop{}
+	for _, resource := range ps.Resources {
+		if selected(resource.ID, resource.Name) {
+			filtered.Resources = append(filtered.Resources, resource)
+		}
+	}
+	if selected(photoshop.ResolutionResource, "Resolution", "Resolution Info") {
+		filtered.Resolution = ps.Resolution
+	}
+	if selected(photoshop.JPEGQualityResource, "JPEG Quality") {
+		filtered.JPEGQuality = ps.JPEGQuality
+	}
+	if selected(photoshop.ThumbnailResource, "Thumbnail") {
+		filtered.Thumbnail = ps.Thumbnail
+	}
+	if selected(photoshop.CopyrightResource, "Copyrighted", "Copyright Flag") {
+		filtered.Copyrighted = ps.Copyrighted
+	}
+	if selected(photoshop.URLResource, "URL") {
+		filtered.URL = ps.URL
+	}
+	if selected(photoshop.IPTCDigestResource, "IPTC Digest") {
+		filtered.IPTCDigest = ps.IPTCDigest
+	}
+	if filtered.Resources == nil && filtered.Resolution == nil && filtered.JPEGQuality == nil && filtered.Thumbnail == nil &&
+		filtered.Copyrighted == nil && filtered.URL == "" && filtered.IPTCDigest == "" {
+		return nil
+	}
+	return filtered
+}
+
+// selectXMP keeps the XMP properties named by prefix:name (e.g. dc:title or xmp:dc:title).
+func selectXMP(selection []tagSelector, props []xmp.Property) []xmp.Property {
+	var selected []xmp.Property
+	for _, prop := range props {
+		name := strings.ToLower(prop.QualifiedName())
+		for _, sel := range selection {
+			full := sel.tag
+			if sel.group != "" && sel.group != "xmp" {
+				full = sel.group + ":" + sel.tag
+			}
+			if full == name {
+				selected = append(selected, prop)
+				break
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/photoshop"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"no command", nil, exitUsage},
+		{"help", []string{"help"}, exitOK},
+		{"unknown command", []string{"nope"}, exitUsage},
+		{"read without files", []string{"read"}, exitUsage},
+		{"bad format", []string{"read", "-format", "xml", "a.jpg"}, exitUsage},
+		{"bad flag", []string{"dump", "-nope"}, exitUsage},
+		{"missing file", []string{"read", filepath.Join(t.TempDir(), "missing.jpg")}, exitFailure},
+		{"tags", []string{"tags", "-ifd", "gps"}, exitOK},
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout, os.Stderr = devNull, devNull
+
+	for _, tt := range tests {
+		if got := run(tt.args); got != tt.want {
+			t.Errorf("%s: run(%q) = %d, want %d", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestOutputFlagsValidate(t *testing.T) {
+	tests := []struct {
+		flags   outputFlags
+		want    string
+		wantErr bool
+	}{
+		{flags: outputFlags{format: "text"}, want: "text"},
+		{flags: outputFlags{format: "json"}, want: "json"},
+		{flags: outputFlags{format: "text", json: true}, want: "json"},
+		{flags: outputFlags{format: "yaml"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		o := tt.flags
+		err := o.validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%+v: validate() error = %v, want error %v", tt.flags, err, tt.wantErr)
+			continue
+		}
+		if err == nil && o.format != tt.want {
+			t.Errorf("%+v: format = %q, want %q", tt.flags, o.format, tt.want)
+		}
+	}
+}
+
+func TestStripFile(t *testing.T) {
+	exif := fixture.Exif(fixture.TIFF(binary.LittleEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon")}}))
+	image := fixture.JPEG(8, 8, exif)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(src, image, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.jpg")
+	if err := os.Link(src, link); err != nil {
+		t.Fatal(err)
+	}
+	png := filepath.Join(dir, "image.png")
+	if err := os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		src     string
+		dst     string
+		wantErr bool
+	}{
+		{name: "new file", src: src, dst: filepath.Join(dir, "out.jpg")},
+		{name: "output is the input", src: src, dst: src, wantErr: true},
+		{name: "output is a hard link to the input", src: src, dst: link, wantErr: true},
+		{name: "not a JPEG", src: png, dst: filepath.Join(dir, "out.png"), wantErr: true},
+		{name: "missing input", src: filepath.Join(dir, "missing.jpg"), dst: filepath.Join(dir, "missing.out.jpg"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := stripFile(tt.src, tt.dst, true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("stripFile() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := os.ReadFile(tt.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := fixture.JPEG(8, 8); !bytes.Equal(got, want) {
+				t.Errorf("stripped file = % x, want % x", got, want)
+			}
+		})
+	}
+
+	// the input is never touched and no temporary files are left
+	if got, _ := os.ReadFile(src); !bytes.Equal(got, image) {
+		t.Error("stripFile() changed the input file")
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == ".tmp" {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestWriteThumbnails(t *testing.T) {
+	thumb := fixture.JPEG(160, 120)
+	ifd1 := &fixture.IFD{Entries: []fixture.Entry{fixture.Pointer(0x0201, thumb), fixture.Long(0x0202, uint32(len(thumb)))}}
+	exif := fixture.Exif(fixture.TIFF(binary.LittleEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon")}, Next: ifd1}))
+	dir := t.TempDir()
+	src := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(src, fixture.JPEG(640, 480, exif), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := writeThumbnails(&out, src, ""); err != nil {
+		t.Fatalf("writeThumbnails() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "photo.ifd1.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, thumb) {
+		t.Errorf("thumbnail = % x, want % x", got, thumb)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("IFD1 160x120")) {
+		t.Errorf("listing = %q, want the IFD1 size read from the stream", out.String())
+	}
+
+	// a thumbnail pointer that does not lead to a JPEG stream is reported
+	ifd1.Entries = []fixture.Entry{fixture.Pointer(0x0201, []byte("not a JPEG")), fixture.Long(0x0202, 10)}
+	exif = fixture.Exif(fixture.TIFF(binary.LittleEndian, &fixture.IFD{Next: ifd1}))
+	if err := os.WriteFile(src, fixture.JPEG(640, 480, exif), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeThumbnails(&out, src, t.TempDir()); err == nil {
+		t.Error("writeThumbnails() of a broken thumbnail succeeded")
+	}
+}
+
+func TestFilterTags(t *testing.T) {
+	copyrighted := true
+	imgData := &metadata.ImageData{
+		MetaData: metadata.MetaData{MainTags: []metadata.IFDtag{{ID: 0x010F, Name: "Make"}, {ID: 0x0110, Name: "Model"}}},
+		IPTC: &metadata.IPTC{Datasets: []metadata.IPTCDataset{
+			{Record: 2, ID: 25, Name: "Keywords"},
+			{Record: 2, ID: 80, Name: "By-line"},
+			{Record: 2, ID: 120, Name: "Caption-Abstract"},
+		}},
+		Photoshop: &metadata.Photoshop{
+			Resources: []metadata.PhotoshopResource{
+				{ID: photoshop.URLResource, Name: "URL"},
+				{ID: photoshop.CopyrightResource, Name: "Copyright Flag"},
+				{ID: photoshop.ResolutionResource, Name: "Resolution Info"},
+			},
+			URL:         "https://example.com",
+			Copyrighted: &copyrighted,
+			Resolution:  &metadata.PhotoshopResolution{XResolution: 300},
+		},
+	}
+
+	tests := []struct {
+		tags          string
+		wantMain      int
+		wantIPTC      []string
+		wantResources []uint16
+	}{
+		{tags: "Make", wantMain: 1},
+		{tags: "keywords,by-line", wantIPTC: []string{"Keywords", "By-line"}},
+		{tags: "iptc:2:120", wantIPTC: []string{"Caption-Abstract"}},
+		{tags: "exif:Keywords"},
+		{tags: "photoshop:URL", wantResources: []uint16{photoshop.URLResource}},
+		{tags: "0x040A,Resolution Info", wantResources: []uint16{photoshop.CopyrightResource, photoshop.ResolutionResource}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tags, func(t *testing.T) {
+			filtered := filterTags(imgData, parseTagSelection(tt.tags))
+			if len(filtered.MetaData.MainTags) != tt.wantMain {
+				t.Errorf("MainTags = %v, want %d", filtered.MetaData.MainTags, tt.wantMain)
+			}
+			// sections without a selected entry are left out
+			if (filtered.IPTC != nil) != (tt.wantIPTC != nil) {
+				t.Errorf("IPTC = %v, want a section %v", filtered.IPTC, tt.wantIPTC != nil)
+			}
+			if (filtered.Photoshop != nil) != (tt.wantResources != nil) {
+				t.Errorf("Photoshop = %v, want a section %v", filtered.Photoshop, tt.wantResources != nil)
+			}
+			var iptc []string
+			if filtered.IPTC != nil {
+				for _, dataset := range filtered.IPTC.Datasets {
+					iptc = append(iptc, dataset.Name)
+				}
+			}
+			if !slices.Equal(iptc, tt.wantIPTC) {
+				t.Errorf("IPTC = %q, want %q", iptc, tt.wantIPTC)
+			}
+			ps := filtered.Photoshop
+			if ps == nil {
+				return
+			}
+			var ids []uint16
+			for _, resource := range ps.Resources {
+				ids = append(ids, resource.ID)
+			}
+			if !slices.Equal(ids, tt.wantResources) {
+				t.Errorf("Photoshop resources = %#04x, want %#04x", ids, tt.wantResources)
+			}
+			// the decoded fields follow their resource
+			if (ps.URL != "") != slices.Contains(ids, photoshop.URLResource) ||
+				(ps.Copyrighted != nil) != slices.Contains(ids, photoshop.CopyrightResource) ||
+				(ps.Resolution != nil) != slices.Contains(ids, photoshop.ResolutionResource) {
+				t.Errorf("Photoshop = %+v does not match the selected resources", ps)
+			}
+		})
+	}
+
+	// the input is left untouched
+	if len(imgData.IPTC.Datasets) != 3 || len(imgData.Photoshop.Resources) != 3 || imgData.Photoshop.URL == "" {
+		t.Error("filterTags() changed its input")
+	}
+}
@@ -0,0 +1,46 @@
+package fixture
+
+// Box returns an ISO-BMFF box of type typ holding payload.
+func Box(typ string, payload ...[]byte) []byte {
+	data := Concat(payload...)
+	return Concat(BE32(uint32(8+len(data))), []byte(typ), data)
+}
+
+// FullBox returns a box that starts with a version and 24 bit flags.
+func FullBox(typ string, version byte, flags uint32, payload ...[]byte) []byte {
+	header := BE32(flags)
+	header[0] = version
+	return Box(typ, append([][]byte{header}, payload...)...)
+}
+
+// Infe returns a version 2 item info entry. contentType is written for "mime"
+// items only.
+func Infe(id uint16, typ, name, contentType string) []byte {
+	payload := Concat(BE16(id), BE16(0), []byte(typ), []byte(name+"\x00"))
+	if typ == "mime" {
+		payload = Concat(payload, []byte(contentType+"\x00"))
+	}
+	return FullBox("infe", 2, 0, payload)
+}
+
+// Location is one item of an iloc box built by Iloc. Extents are offset,
+// length pairs.
+type Location struct {
+	ID      uint16
+	Method  uint16 // construction method, 0 file offsets, 1 idat
+	Base    uint32
+	Extents [][2]uint32
+}
+
+// Iloc returns a version 1 iloc box with 4 byte offsets, lengths and base
+// offsets.
+func Iloc(locations ...Location) []byte {
+	payload := Concat([]byte{0x44, 0x40}, BE16(uint16(len(locations))))
+	for _, loc := range locations {
+		payload = Concat(payload, BE16(loc.ID), BE16(loc.Method), BE16(0), BE32(loc.Base), BE16(uint16(len(loc.Extents))))
+		for _, extent := range loc.Extents {
+			payload = Concat(payload, BE32(extent[0]), BE32(extent[1]))
+		}
+	}
+	return FullBox("iloc", 1, 0, payload)
+}
@@ -0,0 +1,33 @@
+// Package fixture builds small image files in memory for the tests: TIFF and
+// BigTIFF structures, JPEG streams, ISO-BMFF boxes, ICC profiles, IPTC-IIM
+// datasets and Photoshop resources. The builders produce well-formed data
+// unless a test asks for a broken link explicitly.
+package fixture
+
+import (
+	"encoding/binary"
+)
+
+// BE16 returns v as 2 big-endian bytes.
+func BE16(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+// BE32 returns v as 4 big-endian bytes.
+func BE32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+// BE64 returns v as 8 big-endian bytes.
+func BE64(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+// Concat joins byte slices.
+func Concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return b
+}
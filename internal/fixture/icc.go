@@ -0,0 +1,32 @@
+package fixture
+
+// ICCTag is one tag of a profile built by ICC.
+type ICCTag struct {
+	Signature string
+	Data      []byte
+}
+
+// ICC returns an RGB display profile of the given major version, created
+// 2024-01-02 03:04:05, holding tags. The tag data follows the tag table in
+// order.
+func ICC(major byte, tags ...ICCTag) []byte {
+	table := BE32(uint32(len(tags)))
+	offset := 128 + 4 + 12*len(tags)
+	var data []byte
+	for _, tag := range tags {
+		table = Concat(table, []byte(tag.Signature), BE32(uint32(offset+len(data))), BE32(uint32(len(tag.Data))))
+		data = Concat(data, tag.Data)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+
+	header := Concat(
+		BE32(uint32(offset+len(data))), []byte("lcms"), []byte{major, 0x30, 0, 0},
+		[]byte("mntrRGB XYZ "),
+		BE16(2024), BE16(1), BE16(2), BE16(3), BE16(4), BE16(5),
+		[]byte("acspAPPL"), make([]byte, 20), BE32(0), make([]byte, 12), []byte("lcms"),
+	)
+	header = append(header, make([]byte, 128-len(header))...)
+	return Concat(header, table, data)
+}
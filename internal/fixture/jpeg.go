@@ -0,0 +1,20 @@
+package fixture
+
+// Segment returns a JPEG marker segment: 0xFF, marker, the length and payload.
+func Segment(marker byte, payload []byte) []byte {
+	return Concat([]byte{0xFF, marker}, BE16(uint16(len(payload)+2)), payload)
+}
+
+// Exif returns the APP1 Exif segment holding the TIFF structure tiff.
+func Exif(tiff []byte) []byte {
+	return Segment(0xE1, Concat([]byte("Exif\x00\x00"), tiff))
+}
+
+// JPEG returns a JPEG stream of a width x height baseline frame with segments
+// after SOI. The scan data is a few bytes of filler, enough for the parsers
+// but not for an image decoder.
+func JPEG(width, height uint16, segments ...[]byte) []byte {
+	sof := Segment(0xC0, Concat([]byte{8}, BE16(height), BE16(width), []byte{1, 1, 0x11, 0}))
+	sos := Segment(0xDA, []byte{1, 1, 0, 0, 63, 0})
+	return Concat([]byte{0xFF, 0xD8}, Concat(segments...), sof, sos, []byte{0x12, 0xFF, 0x00, 0x34}, []byte{0xFF, 0xD9})
+}
@@ -0,0 +1,25 @@
+package fixture
+
+// Dataset returns an IPTC-IIM dataset record:id holding value.
+func Dataset(record, id byte, value string) []byte {
+	return Concat([]byte{0x1C, record, id}, BE16(uint16(len(value))), []byte(value))
+}
+
+// Resource returns a Photoshop image resource block with the Pascal string
+// name, both the name and the data padded to an even size.
+func Resource(id uint16, name string, data []byte) []byte {
+	b := Concat([]byte("8BIM"), BE16(id), []byte{byte(len(name))}, []byte(name))
+	if len(name)%2 == 0 {
+		b = append(b, 0)
+	}
+	b = Concat(b, BE32(uint32(len(data))), data)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// APP13 returns the APP13 segment holding the Photoshop resource blocks.
+func APP13(resources ...[]byte) []byte {
+	return Segment(0xED, Concat([]byte("Photoshop 3.0\x00"), Concat(resources...)))
+}
@@ -0,0 +1,270 @@
+package fixture
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+// TIFF types used by the builders
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeUndefined = 7
+	typeLong8     = 16
+)
+
+// Entry is one IFD entry. Its value is encoded when the file is laid out, so
+// the same entries serve both byte orders.
+type Entry struct {
+	ID    uint16
+	Type  uint16
+	Count uint64
+	// encode returns the value bytes, blob and subs set the value to offsets
+	encode func(order binary.ByteOrder) []byte
+	blob   []byte
+	subs   []*IFD
+	// offset overrides the value with a raw offset, e.g. one past the end of the file
+	offset *uint64
+}
+
+// IFD is one directory. Next links the following IFD of the chain; NextOffset,
+// when set, is written instead so tests can point the chain anywhere.
+type IFD struct {
+	Entries    []Entry
+	Next       *IFD
+	NextOffset *uint64
+
+	offset uint64
+}
+
+// ASCII returns a NUL terminated ASCII entry.
+func ASCII(id uint16, s string) Entry {
+	b := append([]byte(s), 0)
+	return Entry{ID: id, Type: typeASCII, Count: uint64(len(b)), encode: func(binary.ByteOrder) []byte { return b }}
+}
+
+// Short returns a SHORT entry.
+func Short(id uint16, values ...uint16) Entry {
+	return Entry{ID: id, Type: typeShort, Count: uint64(len(values)), encode: func(order binary.ByteOrder) []byte {
+		b := make([]byte, 2*len(values))
+		for i, v := range values {
+			order.PutUint16(b[2*i:], v)
+		}
+		return b
+	}}
+}
+
+// Long returns a LONG entry.
+func Long(id uint16, values ...uint32) Entry {
+	return Entry{ID: id, Type: typeLong, Count: uint64(len(values)), encode: func(order binary.ByteOrder) []byte {
+		b := make([]byte, 4*len(values))
+		for i, v := range values {
+			order.PutUint32(b[4*i:], v)
+		}
+		return b
+	}}
+}
+
+// Long8 returns a BigTIFF LONG8 entry.
+func Long8(id uint16, values ...uint64) Entry {
+	return Entry{ID: id, Type: typeLong8, Count: uint64(len(values)), encode: func(order binary.ByteOrder) []byte {
+		b := make([]byte, 8*len(values))
+		for i, v := range values {
+			order.PutUint64(b[8*i:], v)
+		}
+		return b
+	}}
+}
+
+// Rational returns a RATIONAL entry from numerator, denominator pairs.
+func Rational(id uint16, values ...uint32) Entry {
+	e := Long(id, values...)
+	e.Type, e.Count = typeRational, uint64(len(values)/2)
+	return e
+}
+
+// Bytes returns an entry of type typ, BYTE or UNDEFINED, holding data.
+func Bytes(id uint16, typ uint16, data []byte) Entry {
+	return Entry{ID: id, Type: typ, Count: uint64(len(data)), encode: func(binary.ByteOrder) []byte { return data }}
+}
+
+// Undefined returns an UNDEFINED entry holding data.
+func Undefined(id uint16, data []byte) Entry {
+	return Bytes(id, typeUndefined, data)
+}
+
+// Pointer returns a LONG entry holding the offset of data, which is stored
+// after the IFDs, e.g. for JPEGInterchangeFormat.
+func Pointer(id uint16, data []byte) Entry {
+	return Entry{ID: id, Type: typeLong, Count: 1, blob: data}
+}
+
+// Sub returns a LONG entry holding the offset of the IFD sub, e.g. the Exif pointer.
+func Sub(id uint16, sub *IFD) Entry {
+	return Subs(id, sub)
+}
+
+// Subs returns a LONG entry holding the offsets of several IFDs, e.g. SubIFDs.
+func Subs(id uint16, subs ...*IFD) Entry {
+	return Entry{ID: id, Type: typeLong, Count: uint64(len(subs)), subs: subs}
+}
+
+// RawOffset returns a LONG entry holding offset as is.
+func RawOffset(id uint16, offset uint64) Entry {
+	return Entry{ID: id, Type: typeLong, Count: 1, offset: &offset}
+}
+
+// Offset returns a pointer to v for IFD.NextOffset.
+func Offset(v uint64) *uint64 {
+	return &v
+}
+
+// TIFF lays out a classic TIFF file with root as IFD0.
+func TIFF(order binary.ByteOrder, root *IFD) []byte {
+	return layout(order, 42, false, nil, root)
+}
+
+// CR2 lays out a Canon CR2 file: a classic TIFF header followed by "CR",
+// major version 2, minor version 0 and the offset of the raw IFD, left 0.
+func CR2(order binary.ByteOrder, root *IFD) []byte {
+	return layout(order, 42, false, []byte{'C', 'R', 2, 0, 0, 0, 0, 0}, root)
+}
+
+// TIFFVersion is TIFF with another header version, e.g. 0x4F52 for ORF.
+func TIFFVersion(order binary.ByteOrder, version uint16, root *IFD) []byte {
+	return layout(order, version, false, nil, root)
+}
+
+// BigTIFF lays out a BigTIFF file with root as IFD0.
+func BigTIFF(order binary.ByteOrder, root *IFD) []byte {
+	return layout(order, 43, true, nil, root)
+}
+
+// layout writes the header, extra header bytes and every IFD reachable from
+// root, followed by the out of line values and blobs.
+func layout(order binary.ByteOrder, version uint16, big bool, extra []byte, root *IFD) []byte {
+	headerSize, countSize, entrySize, offsetSize := uint64(8), uint64(2), uint64(12), uint64(4)
+	if big {
+		headerSize, countSize, entrySize, offsetSize = 16, 8, 20, 8
+	}
+	headerSize += uint64(len(extra))
+
+	// collect every IFD once, a chain or pointer may loop back on purpose
+	var ifds []*IFD
+	var visit func(ifd *IFD)
+	visit = func(ifd *IFD) {
+		for ifd != nil && !slices.Contains(ifds, ifd) {
+			ifds = append(ifds, ifd)
+			for _, e := range ifd.Entries {
+				for _, sub := range e.subs {
+					visit(sub)
+				}
+			}
+			ifd = ifd.Next
+		}
+	}
+	visit(root)
+
+	values := map[*Entry][]byte{}
+	pos := headerSize
+	for _, ifd := range ifds {
+		slices.SortStableFunc(ifd.Entries, func(a, b Entry) int { return int(a.ID) - int(b.ID) })
+		ifd.offset = pos
+		pos += countSize + entrySize*uint64(len(ifd.Entries)) + offsetSize
+		for i := range ifd.Entries {
+			e := &ifd.Entries[i]
+			size := 4 * len(e.subs)
+			if e.encode != nil {
+				values[e] = e.encode(order)
+				size = len(values[e])
+			}
+			if uint64(size) > offsetSize {
+				pos += uint64(size + size%2)
+			}
+		}
+	}
+	blobs := map[*Entry]uint64{}
+	for _, ifd := range ifds {
+		for i := range ifd.Entries {
+			if e := &ifd.Entries[i]; e.blob != nil {
+				blobs[e] = pos
+				pos += uint64(len(e.blob) + len(e.blob)%2)
+			}
+		}
+	}
+
+	b := make([]byte, pos)
+	if order == binary.BigEndian {
+		copy(b, "MM")
+	} else {
+		copy(b, "II")
+	}
+	order.PutUint16(b[2:], version)
+	if big {
+		order.PutUint16(b[4:], 8)
+		order.PutUint64(b[8:], ifds[0].offset)
+	} else {
+		order.PutUint32(b[4:], uint32(ifds[0].offset))
+	}
+	copy(b[headerSize-uint64(len(extra)):], extra)
+	putOffset := func(at uint64, v uint64) {
+		if big {
+			order.PutUint64(b[at:], v)
+		} else {
+			order.PutUint32(b[at:], uint32(v))
+		}
+	}
+
+	for _, ifd := range ifds {
+		at := ifd.offset
+		if big {
+			order.PutUint64(b[at:], uint64(len(ifd.Entries)))
+		} else {
+			order.PutUint16(b[at:], uint16(len(ifd.Entries)))
+		}
+		at += countSize
+		data := at + entrySize*uint64(len(ifd.Entries)) + offsetSize
+		for i := range ifd.Entries {
+			e := &ifd.Entries[i]
+			order.PutUint16(b[at:], e.ID)
+			order.PutUint16(b[at+2:], e.Type)
+			if big {
+				order.PutUint64(b[at+4:], e.Count)
+			} else {
+				order.PutUint32(b[at+4:], uint32(e.Count))
+			}
+			value := at + 4 + offsetSize
+			if e.subs != nil {
+				v := make([]byte, 4*len(e.subs))
+				for i, sub := range e.subs {
+					order.PutUint32(v[4*i:], uint32(sub.offset))
+				}
+				values[e] = v
+			}
+			switch {
+			case e.offset != nil:
+				order.PutUint32(b[value:], uint32(*e.offset))
+			case e.blob != nil:
+				order.PutUint32(b[value:], uint32(blobs[e]))
+				copy(b[blobs[e]:], e.blob)
+			case uint64(len(values[e])) > offsetSize:
+				putOffset(value, data)
+				copy(b[data:], values[e])
+				data += uint64(len(values[e]) + len(values[e])%2)
+			default:
+				copy(b[value:], values[e])
+			}
+			at += entrySize
+		}
+		switch {
+		case ifd.NextOffset != nil:
+			putOffset(at, *ifd.NextOffset)
+		case ifd.Next != nil:
+			putOffset(at, ifd.Next.offset)
+		}
+	}
+	return b
+}
@@ -1,122 +1,156 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"strings"
 
-	"github.com/justikun/metadata-viewer/pkg/jpg"
+	_ "github.com/justikun/metadata-viewer/pkg/cr3"
+	_ "github.com/justikun/metadata-viewer/pkg/heif"
+	_ "github.com/justikun/metadata-viewer/pkg/jpg"
 	"github.com/justikun/metadata-viewer/pkg/metadata"
+	_ "github.com/justikun/metadata-viewer/pkg/png"
+	_ "github.com/justikun/metadata-viewer/pkg/raf"
+	_ "github.com/justikun/metadata-viewer/pkg/tiff"
+	_ "github.com/justikun/metadata-viewer/pkg/webp"
 )
 
+// exit codes
+const (
+	exitOK      = 0 // every file was processed
+	exitFailure = 1 // at least one file failed
+	exitUsage   = 2 // bad command line
+)
+
+const usage = `Usage: metadata-viewer <command> [flags] <files/dirs...>
+
+Commands:
+  read   print the metadata of each image
+  dump   print every IFD with offsets, types and counts
+  strip  write a copy of each JPEG without its metadata
+  thumb  write the embedded thumbnails and previews of each image
+  tags   list the tags known to the tag registry
+
+Run 'metadata-viewer <command> -h' for the flags of a command.
+`
+
+type command struct {
+	name string
+	run  func(args []string) int
+}
+
+var commands = []command{
+	{"read", runRead},
+	{"dump", runDump},
+	{"strip", runStrip},
+	{"thumb", runThumb},
+	{"tags", runTags},
+}
+
 func main() {
-	//photos := [3]string{"test-photos/test-image-1.jpeg", "test-photos/test-image-2.tiff", "test-photos/test-image-3"}
+	os.Exit(run(os.Args[1:]))
+}
 
-	images, err := GetImageFiles("test-photos")
-	if err != nil {
-		fmt.Println(err)
+func run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitUsage
 	}
-
-	for _, image := range images {
-		images, err := parseImgData(image.ImagePath)
-		if err != nil {
-			fmt.Printf("Failed to parse image at %s", images.ImagePath)
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return exitOK
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
 		}
 	}
-
-	print(images[0].ImagePath)
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+	return exitUsage
 }
 
-func parseImgData(imgPath string) (metadata.ImageData, error) {
-	imgData := metadata.ImageData{}
-	imgData.ImagePath = imgPath
+// newFlagSet returns a flag set that reports usage errors instead of exiting.
+func newFlagSet(name, argsUsage string) *flag.FlagSet {
+	fset := flag.NewFlagSet(name, flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Usage: metadata-viewer %s [flags] %s\n", name, argsUsage)
+		fset.PrintDefaults()
+	}
+	return fset
+}
 
-	file, err := os.Open(imgPath)
+// parseFlags parses args and maps flag errors to an exit code. ok is false when
+// the command should return code immediately.
+func parseFlags(fset *flag.FlagSet, args []string) (code int, ok bool) {
+	err := fset.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK, false
+	}
 	if err != nil {
-		return imgData, fmt.Errorf("error: %v, is NOT a valid image path\n", imgPath)
+		return exitUsage, false
 	}
-	defer file.Close()
+	return exitOK, true
+}
 
-	for {
-		// find marker
-		marker := make([]byte, 2)
-		_, err = file.Read(marker)
+// collectFiles expands the path arguments into image files. Directories are
+// read one level deep, or fully when recursive is set.
+func collectFiles(paths []string, recursive bool) ([]string, error) {
+	var files []string
+	var errs []error
+	for _, path := range paths {
+		info, err := os.Stat(path)
 		if err != nil {
-			return imgData, err
+			errs = append(errs, err)
+			continue
 		}
-
-		if marker[0] != 0xFF {
-			return imgData, fmt.Errorf("Hex: %x. Not a valid marker\n", marker[0])
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
 		}
-
-		switch marker[1] {
-		case 0xD8: // SOI - start of image
-			println("case soi:")
-		case 0xD9: // EOI - end of image
-			return imgData, fmt.Errorf("case eoi")
-		case 0xE0: // APP0 - jfif marker
-			println("case app0:")
-			jpg.ParseAPP0(file, &imgData)
-			if err != nil {
-				fmt.Printf("Failed to parse APPO at %s\n", imgPath)
-			}
-		case 0xE1: // APP1
-			println("case app1:")
-			err := jpg.ParseAPP1(file, &imgData)
-			if err != nil {
-				fmt.Printf("Failed to parse APP1 at %s\n", imgPath)
-			}
-			println("APP1 DONE")
-			helperPrintData(imgData)
-			println("--------------")
-
-			return imgData, nil
-		case 0xDA: // SOS - image stream
-			return imgData, nil
-		default:
-			return imgData, fmt.Errorf("No marker found")
+		images, err := GetImageFiles(path, recursive)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, image := range images {
+			files = append(files, image.ImagePath)
 		}
 	}
+	return files, errors.Join(errs...)
 }
 
-func GetImageFiles(dirPath string) ([]metadata.ImageData, error) {
+// GetImageFiles returns the files of dirPath whose content is an image with a
+// registered decoder, whatever their extension.
+func GetImageFiles(dirPath string, recursive bool) ([]metadata.ImageData, error) {
 	var imageFiles []metadata.ImageData
 
-	allowedExtensions := map[string]struct{}{
-		".jpg":  {},
-		".jpeg": {},
-		".png":  {},
-		".tiff": {},
-		".tif":  {},
-		".webp": {},
-	}
-
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		return []metadata.ImageData{}, fmt.Errorf("Failed to read directory %s: %w", dirPath, err)
-	}
-	for _, entry := range entries {
+	err := filepath.WalkDir(dirPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 		if entry.IsDir() {
-			continue
+			if path != dirPath && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
 		}
-		fileName := entry.Name()
-		ext := strings.ToLower(filepath.Ext(fileName))
-		_, ok := allowedExtensions[ext]
-		if ok {
-			// construct absolute path
-			absPath := filepath.Join(dirPath, fileName)
-			image := metadata.ImageData{ImagePath: absPath, MetaData: metadata.MetaData{}}
-			imageFiles = append(imageFiles, image)
+		format, err := metadata.DetectFile(path)
+		if errors.Is(err, metadata.ErrFormat) || (err == nil && !metadata.CanDecode(format)) {
+			return nil
 		}
+		// unreadable files are kept so that decoding reports the error
+		image := metadata.ImageData{ImagePath: path, Format: format, MetaData: metadata.MetaData{}}
+		imageFiles = append(imageFiles, image)
+		return nil
+	})
+	if err != nil {
+		return imageFiles, fmt.Errorf("Failed to read directory %s: %w", dirPath, err)
 	}
 	return imageFiles, nil
 }
-
-func helperPrintData(data metadata.ImageData) {
-
-	fmt.Println("Image: ", data.ImagePath)
-
-	fmt.Println("")
-	fmt.Println("-----END OF Print Data-----")
-}
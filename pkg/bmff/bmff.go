@@ -0,0 +1,150 @@
+package bmff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Box is the header of one box of the ISO base media file format
+// (ISO/IEC 14496-12) used by HEIF, AVIF and CR3.
+type Box struct {
+	Type       string
+	UUID       [16]byte // extended type of "uuid" boxes
+	Offset     int64    // offset of the box header
+	Size       int64    // size of the whole box including the header
+	HeaderSize int64
+}
+
+// DataOffset returns the offset of the box payload.
+func (b Box) DataOffset() int64 { return b.Offset + b.HeaderSize }
+
+// DataSize returns the size of the box payload.
+func (b Box) DataSize() int64 { return b.Size - b.HeaderSize }
+
+// End returns the offset just past the box.
+func (b Box) End() int64 { return b.Offset + b.Size }
+
+// ReadBoxes returns the boxes stored back to back between start and end.
+func ReadBoxes(r io.ReaderAt, start, end int64) ([]Box, error) {
+	var boxes []Box
+	for offset := start; offset+8 <= end; {
+		box, err := ReadBox(r, offset, end)
+		if err != nil {
+			return boxes, err
+		}
+		boxes = append(boxes, box)
+		offset = box.End()
+	}
+	return boxes, nil
+}
+
+// ReadBox reads the box header at offset. end bounds the box, a size of 0
+// means the box extends to end.
+func ReadBox(r io.ReaderAt, offset, end int64) (Box, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return Box{}, fmt.Errorf("failed to read box header at %d: %w", offset, err)
+	}
+	box := Box{
+		Type:       string(header[4:8]),
+		Offset:     offset,
+		Size:       int64(binary.BigEndian.Uint32(header[0:4])),
+		HeaderSize: 8,
+	}
+	switch box.Size {
+	case 0:
+		// last box of the file
+		box.Size = end - offset
+	case 1:
+		// 64 bit size follows the type
+		largeSize := make([]byte, 8)
+		if _, err := r.ReadAt(largeSize, offset+8); err != nil {
+			return Box{}, fmt.Errorf("failed to read size of %q box at %d: %w", box.Type, offset, err)
+		}
+		size := binary.BigEndian.Uint64(largeSize)
+		if size > uint64(end-offset) {
+			return Box{}, fmt.Errorf("%q box at %d: size %d runs past the end", box.Type, offset, size)
+		}
+		box.Size = int64(size)
+		box.HeaderSize = 16
+	}
+	if box.Type == "uuid" {
+		if _, err := r.ReadAt(box.UUID[:], offset+box.HeaderSize); err != nil {
+			return Box{}, fmt.Errorf("failed to read uuid box type at %d: %w", offset, err)
+		}
+		box.HeaderSize += 16
+	}
+	if box.Size < box.HeaderSize || box.Size > end-offset {
+		return Box{}, fmt.Errorf("%q box at %d: invalid size %d", box.Type, offset, box.Size)
+	}
+	return box, nil
+}
+
+// Children returns the boxes inside box. skip is the number of payload bytes
+// before the first child, e.g. 4 for the version and flags of a full box.
+func Children(r io.ReaderAt, box Box, skip int64) ([]Box, error) {
+	return ReadBoxes(r, box.DataOffset()+skip, box.End())
+}
+
+// Find returns the first box of the given type.
+func Find(boxes []Box, boxType string) (Box, bool) {
+	for _, box := range boxes {
+		if box.Type == boxType {
+			return box, true
+		}
+	}
+	return Box{}, false
+}
+
+// ReadData returns the payload of box.
+func ReadData(r io.ReaderAt, box Box) ([]byte, error) {
+	if box.DataSize() > maxBoxData {
+		return nil, fmt.Errorf("%q box at %d is larger than %d bytes", box.Type, box.Offset, maxBoxData)
+	}
+	data := make([]byte, box.DataSize())
+	if _, err := r.ReadAt(data, box.DataOffset()); err != nil {
+		return nil, fmt.Errorf("failed to read %q box at %d: %w", box.Type, box.Offset, err)
+	}
+	return data, nil
+}
+
+// maxBoxData limits how much of a box ReadData loads into memory.
+const maxBoxData = 64 << 20
+
+// FileType is the ftyp box.
+type FileType struct {
+	MajorBrand       string
+	MinorVersion     uint32
+	CompatibleBrands []string
+}
+
+// HasBrand reports whether brand is the major brand or a compatible brand.
+func (f FileType) HasBrand(brand string) bool {
+	return f.MajorBrand == brand || slices.Contains(f.CompatibleBrands, brand)
+}
+
+// ReadFileType reads the ftyp box that must start the file.
+func ReadFileType(r io.ReaderAt, size int64) (FileType, error) {
+	box, err := ReadBox(r, 0, size)
+	if err != nil {
+		return FileType{}, err
+	}
+	if box.Type != "ftyp" {
+		return FileType{}, errors.New("missing ftyp box")
+	}
+	data, err := ReadData(r, box)
+	if err != nil {
+		return FileType{}, err
+	}
+	if len(data) < 8 {
+		return FileType{}, errors.New("ftyp box shorter than 8 bytes")
+	}
+	ftyp := FileType{MajorBrand: string(data[0:4]), MinorVersion: binary.BigEndian.Uint32(data[4:8])}
+	for i := 8; i+4 <= len(data); i += 4 {
+		ftyp.CompatibleBrands = append(ftyp.CompatibleBrands, string(data[i:i+4]))
+	}
+	return ftyp, nil
+}
@@ -0,0 +1,86 @@
+package bmff
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+)
+
+func TestReadBox(t *testing.T) {
+	uuid := [16]byte{0x85, 0xC0, 0xB6, 0x87, 0x82, 0x0F, 0x11, 0xE0, 0x81, 0x11, 0xF4, 0xCE, 0x46, 0x2B, 0x6A, 0x48}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Box
+		wantErr bool
+	}{
+		{name: "plain", data: fixture.Box("free", make([]byte, 4)), want: Box{Type: "free", Size: 12, HeaderSize: 8}},
+		{name: "to the end", data: fixture.Concat(fixture.BE32(0), []byte("mdat"), make([]byte, 10)), want: Box{Type: "mdat", Size: 18, HeaderSize: 8}},
+		{name: "large size", data: fixture.Concat(fixture.BE32(1), []byte("mdat"), fixture.BE64(20), make([]byte, 4)), want: Box{Type: "mdat", Size: 20, HeaderSize: 16}},
+		{name: "uuid", data: fixture.Box("uuid", uuid[:], []byte{1, 2}), want: Box{Type: "uuid", UUID: uuid, Size: 26, HeaderSize: 24}},
+		{name: "large size past the end", data: fixture.Concat(fixture.BE32(1), []byte("mdat"), fixture.BE64(1<<62)), wantErr: true},
+		{name: "large size smaller than the header", data: fixture.Concat(fixture.BE32(1), []byte("mdat"), fixture.BE64(8)), wantErr: true},
+		{name: "size smaller than the header", data: fixture.Concat(fixture.BE32(4), []byte("free")), wantErr: true},
+		{name: "size past the end", data: fixture.Concat(fixture.BE32(100), []byte("free")), wantErr: true},
+		{name: "uuid cut short", data: fixture.Concat(fixture.BE32(24), []byte("uuid"), uuid[:4]), wantErr: true},
+		{name: "short header", data: []byte("\x00\x00\x00"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box, err := ReadBox(bytes.NewReader(tt.data), 0, int64(len(tt.data)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadBox() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && box != tt.want {
+				t.Errorf("ReadBox() = %+v, want %+v", box, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadBoxes(t *testing.T) {
+	data := fixture.Concat(fixture.Box("ftyp", []byte("heic")), fixture.Box("free"), fixture.BE32(100), []byte("mdat"))
+	boxes, err := ReadBoxes(bytes.NewReader(data), 0, int64(len(data)))
+	if err == nil {
+		t.Error("ReadBoxes() of a truncated box succeeded")
+	}
+	// the boxes before the broken one are returned with the error
+	if len(boxes) != 2 || boxes[1].Type != "free" || boxes[1].Offset != 12 {
+		t.Errorf("ReadBoxes() = %+v, want ftyp and free", boxes)
+	}
+}
+
+func TestReadFileType(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    FileType
+		wantErr bool
+	}{
+		{
+			name: "brands",
+			data: fixture.Box("ftyp", []byte("heic"), fixture.BE32(0), []byte("mif1heic")),
+			want: FileType{MajorBrand: "heic", CompatibleBrands: []string{"mif1", "heic"}},
+		},
+		{name: "no compatible brands", data: fixture.Box("ftyp", []byte("avif"), fixture.BE32(1)), want: FileType{MajorBrand: "avif", MinorVersion: 1}},
+		{name: "short", data: fixture.Box("ftyp", []byte("heic")), wantErr: true},
+		{name: "not ftyp", data: fixture.Box("moov", make([]byte, 8)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ftyp, err := ReadFileType(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadFileType() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(ftyp, tt.want) {
+				t.Errorf("ReadFileType() = %+v, want %+v", ftyp, tt.want)
+			}
+		})
+	}
+	if ftyp := (FileType{MajorBrand: "mif1", CompatibleBrands: []string{"avif"}}); !ftyp.HasBrand("avif") || ftyp.HasBrand("heic") {
+		t.Error("HasBrand() does not check the compatible brands")
+	}
+}
@@ -0,0 +1,421 @@
+package bmff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Item is one entry of the item information box (infe).
+type Item struct {
+	ID          uint32
+	Type        string // e.g. "hvc1", "av01", "grid", "Exif" or "mime"
+	Name        string
+	ContentType string // MIME type of "mime" items
+}
+
+// Extent is one piece of an item's data.
+type Extent struct {
+	Offset uint64
+	Length uint64
+}
+
+// Location is the item location box (iloc) entry of an item.
+type Location struct {
+	ConstructionMethod uint8 // 0 file offsets, 1 offsets into idat
+	BaseOffset         uint64
+	Extents            []Extent
+}
+
+// Reference is one entry of the item reference box (iref), e.g. a "cdsc"
+// reference from an Exif item to the image it describes.
+type Reference struct {
+	Type string
+	From uint32
+	To   []uint32
+}
+
+// Property is one box of the item property container (ipco).
+type Property struct {
+	Box  Box
+	Data []byte // payload of the property box
+}
+
+// Meta is the content of a HEIF meta box.
+type Meta struct {
+	Handler     string
+	PrimaryItem uint32
+	Items       []Item
+	Locations   map[uint32]Location
+	References  []Reference
+	Properties  []Property
+	// Associations maps item IDs to the indexes of their properties in Properties.
+	Associations map[uint32][]int
+	idat         Box
+	hasIdat      bool
+}
+
+// ReadMeta parses the meta box, a full box holding the item boxes.
+func ReadMeta(r io.ReaderAt, box Box) (*Meta, error) {
+	children, err := Children(r, box, 4)
+	if err != nil {
+		return nil, err
+	}
+	meta := &Meta{Locations: map[uint32]Location{}, Associations: map[uint32][]int{}}
+	for _, child := range children {
+		switch child.Type {
+		case "hdlr", "pitm", "iinf", "iloc", "iref":
+		case "iprp":
+			if err := meta.readProperties(r, child); err != nil {
+				return nil, err
+			}
+			continue
+		case "idat":
+			meta.idat, meta.hasIdat = child, true
+			continue
+		default:
+			continue
+		}
+
+		data, err := ReadData(r, child)
+		if err != nil {
+			return nil, err
+		}
+		p := newParser(data)
+		version, _ := p.fullBox()
+		switch child.Type {
+		case "hdlr":
+			p.skip(4) // pre_defined
+			meta.Handler = p.fourCC()
+		case "pitm":
+			meta.PrimaryItem = p.id(version == 0)
+		case "iinf":
+			err = meta.readItemInfo(p, version)
+		case "iloc":
+			err = meta.readLocations(p, version)
+		case "iref":
+			err = meta.readReferences(p, version)
+		}
+		if err == nil {
+			err = p.err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%q box at %d: %w", child.Type, child.Offset, err)
+		}
+	}
+	return meta, nil
+}
+
+func (m *Meta) readItemInfo(p *parser, version uint8) error {
+	count := p.id(version == 0)
+	boxes, err := ReadBoxes(bytes.NewReader(p.buf), int64(p.pos), int64(len(p.buf)))
+	if err != nil {
+		return err
+	}
+	for _, box := range boxes {
+		if box.Type != "infe" || len(m.Items) == int(count) {
+			continue
+		}
+		infe := newParser(p.buf[box.DataOffset():box.End()])
+		infeVersion, _ := infe.fullBox()
+		var item Item
+		if infeVersion < 2 {
+			// item_ID, protection index, name, content type
+			item.ID = uint32(infe.u16())
+			infe.skip(2)
+			item.Name = infe.cstring()
+			item.ContentType = infe.cstring()
+		} else {
+			item.ID = infe.id(infeVersion == 2)
+			infe.skip(2)
+			item.Type = infe.fourCC()
+			item.Name = infe.cstring()
+			if item.Type == "mime" {
+				item.ContentType = infe.cstring()
+			}
+		}
+		if infe.err != nil {
+			return fmt.Errorf("infe box at %d: %w", box.Offset, infe.err)
+		}
+		m.Items = append(m.Items, item)
+	}
+	return nil
+}
+
+func (m *Meta) readLocations(p *parser, version uint8) error {
+	if version > 2 {
+		return fmt.Errorf("unsupported version %d", version)
+	}
+	sizes := p.u8()
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0F)
+	sizes = p.u8()
+	baseOffsetSize, indexSize := int(sizes>>4), 0
+	if version > 0 {
+		indexSize = int(sizes & 0x0F)
+	}
+	count := p.id(version < 2)
+	for range count {
+		if p.err != nil {
+			break
+		}
+		id := p.id(version < 2)
+		var loc Location
+		if version > 0 {
+			loc.ConstructionMethod = uint8(p.u16() & 0x0F)
+		}
+		p.skip(2) // data_reference_index
+		loc.BaseOffset = p.uintN(baseOffsetSize)
+		extents := p.u16()
+		for range extents {
+			p.uintN(indexSize)
+			loc.Extents = append(loc.Extents, Extent{Offset: p.uintN(offsetSize), Length: p.uintN(lengthSize)})
+		}
+		m.Locations[id] = loc
+	}
+	return nil
+}
+
+func (m *Meta) readReferences(p *parser, version uint8) error {
+	boxes, err := ReadBoxes(bytes.NewReader(p.buf), int64(p.pos), int64(len(p.buf)))
+	if err != nil {
+		return err
+	}
+	for _, box := range boxes {
+		ref := newParser(p.buf[box.DataOffset():box.End()])
+		reference := Reference{Type: box.Type, From: ref.id(version == 0)}
+		count := ref.u16()
+		for range count {
+			reference.To = append(reference.To, ref.id(version == 0))
+		}
+		if ref.err != nil {
+			return fmt.Errorf("%q reference at %d: %w", box.Type, box.Offset, ref.err)
+		}
+		m.References = append(m.References, reference)
+	}
+	return nil
+}
+
+// readProperties reads the property container and the associations of iprp.
+func (m *Meta) readProperties(r io.ReaderAt, iprp Box) error {
+	children, err := Children(r, iprp, 0)
+	if err != nil {
+		return err
+	}
+	if ipco, ok := Find(children, "ipco"); ok {
+		boxes, err := Children(r, ipco, 0)
+		if err != nil {
+			return err
+		}
+		for _, box := range boxes {
+			data, err := ReadData(r, box)
+			if err != nil {
+				return err
+			}
+			m.Properties = append(m.Properties, Property{Box: box, Data: data})
+		}
+	}
+	for _, child := range children {
+		if child.Type != "ipma" {
+			continue
+		}
+		data, err := ReadData(r, child)
+		if err != nil {
+			return err
+		}
+		p := newParser(data)
+		version, flags := p.fullBox()
+		count := p.u32()
+		for range count {
+			if p.err != nil {
+				break
+			}
+			id := p.id(version < 1)
+			associations := p.u8()
+			for range associations {
+				// the top bit marks essential properties, indexes are 1 based
+				var index int
+				if flags&1 != 0 {
+					index = int(p.u16() & 0x7FFF)
+				} else {
+					index = int(p.u8() & 0x7F)
+				}
+				if index > 0 {
+					m.Associations[id] = append(m.Associations[id], index-1)
+				}
+			}
+		}
+		if p.err != nil {
+			return fmt.Errorf("ipma box at %d: %w", child.Offset, p.err)
+		}
+	}
+	return nil
+}
+
+// Item returns the item with the given ID.
+func (m *Meta) Item(id uint32) (Item, bool) {
+	for _, item := range m.Items {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+// ItemProperty returns the first property of the given type associated with item id.
+func (m *Meta) ItemProperty(id uint32, propertyType string) (Property, bool) {
+	for _, index := range m.Associations[id] {
+		if index < len(m.Properties) && m.Properties[index].Box.Type == propertyType {
+			return m.Properties[index], true
+		}
+	}
+	return Property{}, false
+}
+
+// ImageSize returns the width and height from the ispe property of item id.
+func (m *Meta) ImageSize(id uint32) (width, height uint32, ok bool) {
+	prop, ok := m.ItemProperty(id, "ispe")
+	if !ok || len(prop.Data) < 12 {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint32(prop.Data[4:8]), binary.BigEndian.Uint32(prop.Data[8:12]), true
+}
+
+// ItemExtents returns the absolute file ranges holding the data of item id.
+// Items stored in idat resolve to ranges inside the idat payload.
+func (m *Meta) ItemExtents(id uint32) ([]Extent, error) {
+	loc, ok := m.Locations[id]
+	if !ok {
+		return nil, fmt.Errorf("item %d has no location", id)
+	}
+	base := loc.BaseOffset
+	switch loc.ConstructionMethod {
+	case 0:
+	case 1:
+		if !m.hasIdat {
+			return nil, fmt.Errorf("item %d is stored in a missing idat box", id)
+		}
+		base += uint64(m.idat.DataOffset())
+	default:
+		return nil, fmt.Errorf("item %d uses unsupported construction method %d", id, loc.ConstructionMethod)
+	}
+	extents := make([]Extent, len(loc.Extents))
+	for i, extent := range loc.Extents {
+		extents[i] = Extent{Offset: base + extent.Offset, Length: extent.Length}
+	}
+	return extents, nil
+}
+
+// ReadItem returns the data of item id.
+func (m *Meta) ReadItem(r io.ReaderAt, id uint32) ([]byte, error) {
+	extents, err := m.ItemExtents(id)
+	if err != nil {
+		return nil, err
+	}
+	var total uint64
+	for _, extent := range extents {
+		// compare before adding, a crafted length could wrap the sum around
+		if extent.Length > maxBoxData-total {
+			return nil, fmt.Errorf("item %d is larger than %d bytes", id, maxBoxData)
+		}
+		total += extent.Length
+	}
+	data := make([]byte, 0, total)
+	for _, extent := range extents {
+		buf := make([]byte, extent.Length)
+		if _, err := r.ReadAt(buf, int64(extent.Offset)); err != nil {
+			return nil, fmt.Errorf("failed to read item %d: %w", id, err)
+		}
+		data = append(data, buf...)
+	}
+	return data, nil
+}
+
+// parser reads big endian fields from a box payload. The first error sticks
+// and later reads return zero values.
+type parser struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func newParser(buf []byte) *parser { return &parser{buf: buf} }
+
+func (p *parser) next(n int) []byte {
+	if p.err != nil {
+		return nil
+	}
+	if n > len(p.buf)-p.pos {
+		p.err = errors.New("unexpected end of box")
+		return nil
+	}
+	b := p.buf[p.pos : p.pos+n]
+	p.pos += n
+	return b
+}
+
+func (p *parser) skip(n int) { p.next(n) }
+
+func (p *parser) u8() uint8 {
+	if b := p.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (p *parser) u16() uint16 {
+	if b := p.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (p *parser) u32() uint32 {
+	if b := p.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// uintN reads an n byte unsigned integer, n is 0, 4 or 8 in iloc.
+func (p *parser) uintN(n int) uint64 {
+	var v uint64
+	for _, c := range p.next(n) {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// id reads a 16 bit item ID when short is set, otherwise a 32 bit one.
+func (p *parser) id(short bool) uint32 {
+	if short {
+		return uint32(p.u16())
+	}
+	return p.u32()
+}
+
+// fullBox reads the version and flags of a full box.
+func (p *parser) fullBox() (version uint8, flags uint32) {
+	v := p.u32()
+	return uint8(v >> 24), v & 0x00FFFFFF
+}
+
+func (p *parser) fourCC() string {
+	return string(p.next(4))
+}
+
+// cstring reads a NUL terminated UTF-8 string. A missing terminator ends the
+// string at the end of the box.
+func (p *parser) cstring() string {
+	if p.err != nil {
+		return ""
+	}
+	rest := p.buf[p.pos:]
+	s, _, found := bytes.Cut(rest, []byte{0})
+	p.pos += len(s)
+	if found {
+		p.pos++
+	}
+	return string(s)
+}
@@ -0,0 +1,158 @@
+package bmff
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+)
+
+// meta returns a meta box holding children after the handler box.
+func meta(children ...[]byte) []byte {
+	hdlr := fixture.FullBox("hdlr", 0, 0, fixture.BE32(0), []byte("pict"), make([]byte, 12), []byte{0})
+	return fixture.FullBox("meta", 0, 0, append([][]byte{hdlr}, children...)...)
+}
+
+// readMeta parses the meta box at the start of data.
+func readMeta(t *testing.T, data []byte) (*Meta, error) {
+	t.Helper()
+	r := bytes.NewReader(data)
+	box, err := ReadBox(r, 0, int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ReadMeta(r, box)
+}
+
+func TestReadMeta(t *testing.T) {
+	data := meta(
+		fixture.FullBox("pitm", 0, 0, fixture.BE16(1)),
+		fixture.FullBox("iinf", 0, 0, fixture.BE16(3),
+			fixture.Infe(1, "hvc1", "", ""),
+			fixture.Infe(2, "Exif", "", ""),
+			fixture.Infe(3, "mime", "XMP", "application/rdf+xml")),
+		fixture.Iloc(
+			fixture.Location{ID: 1, Base: 1000, Extents: [][2]uint32{{0, 500}}},
+			fixture.Location{ID: 2, Method: 1, Extents: [][2]uint32{{0, 4}, {4, 6}}},
+			fixture.Location{ID: 3, Method: 1, Base: 10, Extents: [][2]uint32{{0, 5}}}),
+		fixture.FullBox("iref", 0, 0, fixture.Box("cdsc", fixture.BE16(2), fixture.BE16(1), fixture.BE16(1))),
+		fixture.Box("iprp",
+			fixture.Box("ipco", fixture.FullBox("ispe", 0, 0, fixture.BE32(4032), fixture.BE32(3024)), fixture.Box("colr", []byte("nclx"))),
+			fixture.FullBox("ipma", 0, 0, fixture.BE32(2), fixture.BE16(1), []byte{2, 0x81, 0x02}, fixture.BE16(2), []byte{1, 0x02})),
+		fixture.Box("idat", []byte("0123456789hello")),
+	)
+	m, err := readMeta(t, data)
+	if err != nil {
+		t.Fatalf("ReadMeta() error = %v", err)
+	}
+
+	if m.Handler != "pict" || m.PrimaryItem != 1 {
+		t.Errorf("Handler, PrimaryItem = %q, %d, want pict, 1", m.Handler, m.PrimaryItem)
+	}
+	wantItems := []Item{{ID: 1, Type: "hvc1"}, {ID: 2, Type: "Exif"}, {ID: 3, Type: "mime", Name: "XMP", ContentType: "application/rdf+xml"}}
+	if !reflect.DeepEqual(m.Items, wantItems) {
+		t.Errorf("Items = %+v, want %+v", m.Items, wantItems)
+	}
+	wantLocations := map[uint32]Location{
+		1: {BaseOffset: 1000, Extents: []Extent{{0, 500}}},
+		2: {ConstructionMethod: 1, Extents: []Extent{{0, 4}, {4, 6}}},
+		3: {ConstructionMethod: 1, BaseOffset: 10, Extents: []Extent{{0, 5}}},
+	}
+	if !reflect.DeepEqual(m.Locations, wantLocations) {
+		t.Errorf("Locations = %+v, want %+v", m.Locations, wantLocations)
+	}
+	if wantRefs := []Reference{{Type: "cdsc", From: 2, To: []uint32{1}}}; !reflect.DeepEqual(m.References, wantRefs) {
+		t.Errorf("References = %+v, want %+v", m.References, wantRefs)
+	}
+	if wantAssoc := map[uint32][]int{1: {0, 1}, 2: {1}}; !reflect.DeepEqual(m.Associations, wantAssoc) {
+		t.Errorf("Associations = %v, want %v", m.Associations, wantAssoc)
+	}
+
+	if width, height, ok := m.ImageSize(1); !ok || width != 4032 || height != 3024 {
+		t.Errorf("ImageSize(1) = %d, %d, %v, want 4032, 3024, true", width, height, ok)
+	}
+	if _, _, ok := m.ImageSize(2); ok {
+		t.Error("ImageSize(2) found an ispe the item is not associated with")
+	}
+	if _, ok := m.ItemProperty(2, "colr"); !ok {
+		t.Error("ItemProperty(2, colr) not found")
+	}
+	for id, want := range map[uint32]string{2: "0123456789", 3: "hello"} {
+		if got, err := m.ReadItem(bytes.NewReader(data), id); err != nil || string(got) != want {
+			t.Errorf("ReadItem(%d) = %q, %v, want %q", id, got, err, want)
+		}
+	}
+}
+
+func TestReadMetaLargeIndexes(t *testing.T) {
+	// flag 1 switches ipma to 15 bit property indexes
+	data := meta(fixture.Box("iprp",
+		fixture.Box("ipco", fixture.Box("free"), fixture.Box("colr", []byte("nclx"))),
+		fixture.FullBox("ipma", 0, 1, fixture.BE32(1), fixture.BE16(7), []byte{1}, fixture.BE16(0x8002))))
+	m, err := readMeta(t, data)
+	if err != nil {
+		t.Fatalf("ReadMeta() error = %v", err)
+	}
+	if _, ok := m.ItemProperty(7, "colr"); !ok {
+		t.Errorf("Associations = %v, want item 7 to colr", m.Associations)
+	}
+}
+
+func TestReadMetaCorrupt(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "iloc version 3", data: meta(fixture.FullBox("iloc", 3, 0, []byte{0x44, 0x40}, fixture.BE16(0)))},
+		{name: "iloc cut short", data: meta(fixture.FullBox("iloc", 1, 0, []byte{0x44, 0x40}, fixture.BE16(2), fixture.BE16(1)))},
+		{name: "infe cut short", data: meta(fixture.FullBox("iinf", 0, 0, fixture.BE16(1), fixture.FullBox("infe", 2, 0, fixture.BE16(1))))},
+		{name: "iref cut short", data: meta(fixture.FullBox("iref", 0, 0, fixture.Box("cdsc", fixture.BE16(2), fixture.BE16(3), fixture.BE16(1))))},
+		{name: "ipma cut short", data: meta(fixture.Box("iprp", fixture.FullBox("ipma", 0, 0, fixture.BE32(1000), fixture.BE16(1), []byte{1, 1})))},
+		{name: "pitm cut short", data: meta(fixture.FullBox("pitm", 0, 0, []byte{1}))},
+		{name: "child past the end", data: meta(fixture.BE32(100), []byte("iinf"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readMeta(t, tt.data); err == nil {
+				t.Error("ReadMeta() succeeded")
+			}
+		})
+	}
+}
+
+func TestReadItem(t *testing.T) {
+	data := []byte("0123456789")
+
+	tests := []struct {
+		name     string
+		location *Location
+		want     string
+		wantErr  bool
+	}{
+		{name: "file offsets", location: &Location{BaseOffset: 2, Extents: []Extent{{0, 3}, {6, 2}}}, want: "23489"},
+		{name: "no location", wantErr: true},
+		{name: "missing idat", location: &Location{ConstructionMethod: 1, Extents: []Extent{{0, 1}}}, wantErr: true},
+		{name: "item offsets", location: &Location{ConstructionMethod: 2}, wantErr: true},
+		{name: "extent past the end", location: &Location{Extents: []Extent{{8, 4}}}, wantErr: true},
+		{name: "larger than the limit", location: &Location{Extents: []Extent{{0, maxBoxData}, {0, 1}}}, wantErr: true},
+		// the sum of the lengths wraps around to 1 without the overflow check
+		{name: "extent sum overflow", location: &Location{Extents: []Extent{{0, math.MaxUint64}, {0, 2}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Meta{Locations: map[uint32]Location{}}
+			if tt.location != nil {
+				m.Locations[1] = *tt.location
+			}
+			got, err := m.ReadItem(bytes.NewReader(data), 1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadItem() error = %v, want error %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ReadItem() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
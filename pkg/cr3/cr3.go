@@ -0,0 +1,149 @@
+package cr3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/justikun/metadata-viewer/pkg/bmff"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/tiff"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// uuid box types used by Canon
+var (
+	canonUUID   = [16]byte{0x85, 0xc0, 0xb6, 0x87, 0x82, 0x0f, 0x11, 0xe0, 0x81, 0x11, 0xf4, 0xce, 0x46, 0x2b, 0x6a, 0x48}
+	previewUUID = [16]byte{0xea, 0xf4, 0x2b, 0x5e, 0x1c, 0x98, 0x4b, 0x88, 0xb9, 0xfb, 0xb7, 0xdc, 0x40, 0x6e, 0x4d, 0x16}
+	xmpUUID     = [16]byte{0xbe, 0x7a, 0xcf, 0xcb, 0x97, 0xa9, 0x42, 0xe8, 0x9c, 0x71, 0x99, 0x94, 0x91, 0xe3, 0xaf, 0xac}
+)
+
+// cmtBoxes maps the boxes of the Canon uuid box to the IFD their TIFF
+// structure starts with.
+var cmtBoxes = map[string]metadata.IFDtype{
+	"CMT1": metadata.IFDMAIN,
+	"CMT2": metadata.IFDEXIF,
+	"CMT3": metadata.IFDCANON,
+	"CMT4": metadata.IFDGPS,
+}
+
+func init() {
+	metadata.RegisterFormat(metadata.FormatCR3, "????ftypcrx ", Decode)
+}
+
+// Decode reads the CMT boxes, the XMP packet and the JPEG previews of a CR3
+// file. A damaged preview or XMP packet is recorded in imgData.Warnings.
+func Decode(r io.ReaderAt, size int64, imgData *metadata.ImageData) error {
+	boxes, err := bmff.ReadBoxes(r, 0, size)
+	if err != nil {
+		return err
+	}
+	moov, ok := bmff.Find(boxes, "moov")
+	if !ok {
+		return errors.New("missing moov box")
+	}
+	moovBoxes, err := bmff.Children(r, moov, 0)
+	if err != nil {
+		return fmt.Errorf("moov box: %w", err)
+	}
+	canon, ok := findUUID(moovBoxes, canonUUID)
+	if !ok {
+		return errors.New("missing Canon uuid box")
+	}
+	canonBoxes, err := bmff.Children(r, canon, 0)
+	if err != nil {
+		return fmt.Errorf("Canon uuid box: %w", err)
+	}
+
+	for _, box := range canonBoxes {
+		ifdType, ok := cmtBoxes[box.Type]
+		if !ok {
+			continue
+		}
+		// keep the offsets absolute to the file
+		section := io.NewSectionReader(r, 0, box.End())
+		if err := tiff.ParseAs(imgData, section, box.DataOffset(), ifdType); err != nil {
+			return fmt.Errorf("%s box at %d: %w", box.Type, box.Offset, err)
+		}
+	}
+
+	// a damaged preview or XMP packet loses only itself
+	if thmb, ok := bmff.Find(canonBoxes, "THMB"); ok {
+		if preview, err := readPreview(r, thmb, 4, 8, 16); err != nil {
+			imgData.AddWarning(err)
+		} else {
+			imgData.Previews = append(imgData.Previews, preview)
+		}
+	}
+	if uuid, ok := findUUID(boxes, previewUUID); ok {
+		// the PRVW box follows 8 bytes of unknown purpose
+		prvwBoxes, err := bmff.Children(r, uuid, 8)
+		if err != nil {
+			imgData.AddWarning(fmt.Errorf("preview uuid box: %w", err))
+		}
+		if prvw, ok := bmff.Find(prvwBoxes, "PRVW"); ok {
+			if preview, err := readPreview(r, prvw, 6, 12, 16); err != nil {
+				imgData.AddWarning(err)
+			} else {
+				imgData.Previews = append(imgData.Previews, preview)
+			}
+		}
+	}
+
+	if uuid, ok := findUUID(boxes, xmpUUID); ok {
+		if err := parseXMP(r, uuid, imgData); err != nil {
+			imgData.AddWarning(fmt.Errorf("XMP uuid box: %w", err))
+		}
+	}
+	return nil
+}
+
+// parseXMP reads the XMP packet held by the XMP uuid box.
+func parseXMP(r io.ReaderAt, box bmff.Box, imgData *metadata.ImageData) error {
+	data, err := bmff.ReadData(r, box)
+	if err != nil {
+		return err
+	}
+	xmpPacket, err := xmp.Parse(data)
+	if err != nil {
+		return err
+	}
+	imgData.XMP = xmpPacket
+	return nil
+}
+
+// readPreview reads the header of a THMB or PRVW box. sizeAt is the offset of
+// the width and height, lengthAt the offset of the JPEG length and dataAt the
+// offset of the JPEG stream in the box payload.
+func readPreview(r io.ReaderAt, box bmff.Box, sizeAt, lengthAt, dataAt int64) (metadata.Preview, error) {
+	header := make([]byte, dataAt+2)
+	if box.DataSize() < int64(len(header)) {
+		return metadata.Preview{}, fmt.Errorf("%s box at %d is too short", box.Type, box.Offset)
+	}
+	if _, err := r.ReadAt(header, box.DataOffset()); err != nil {
+		return metadata.Preview{}, fmt.Errorf("failed to read %s box at %d: %w", box.Type, box.Offset, err)
+	}
+	if header[dataAt] != 0xFF || header[dataAt+1] != 0xD8 {
+		return metadata.Preview{}, fmt.Errorf("%s box at %d does not hold a JPEG stream", box.Type, box.Offset)
+	}
+	preview := metadata.Preview{
+		Source: "CR3 " + box.Type,
+		Width:  uint32(binary.BigEndian.Uint16(header[sizeAt:])),
+		Height: uint32(binary.BigEndian.Uint16(header[sizeAt+2:])),
+		Offset: box.DataOffset() + dataAt,
+		Length: int64(binary.BigEndian.Uint32(header[lengthAt:])),
+	}
+	// some writers pad the stream, never read past the box
+	preview.Length = min(preview.Length, box.DataSize()-dataAt)
+	return preview, nil
+}
+
+func findUUID(boxes []bmff.Box, uuid [16]byte) (bmff.Box, bool) {
+	for _, box := range boxes {
+		if box.Type == "uuid" && box.UUID == uuid {
+			return box, true
+		}
+	}
+	return bmff.Box{}, false
+}
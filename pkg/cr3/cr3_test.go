@@ -0,0 +1,142 @@
+package cr3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+var (
+	ftyp       = fixture.Box("ftyp", []byte("crx "), fixture.BE32(1), []byte("crx isom"))
+	jpegStream = []byte{0xFF, 0xD8, 0xFF, 0xD9}
+)
+
+// tiffBox returns a CMT box holding a TIFF structure with entries in IFD0.
+func tiffBox(typ string, entries ...fixture.Entry) []byte {
+	return fixture.Box(typ, fixture.TIFF(binary.LittleEndian, &fixture.IFD{Entries: entries}))
+}
+
+// thmb returns a THMB box of a width x height preview holding stream; length
+// is the stream length the box records.
+func thmb(width, height uint16, length uint32, stream []byte) []byte {
+	return fixture.Box("THMB", fixture.BE32(0), fixture.BE16(width), fixture.BE16(height), fixture.BE32(length), make([]byte, 4), stream)
+}
+
+// prvw returns the preview uuid box holding a PRVW box.
+func prvw(width, height uint16, length uint32, stream []byte) []byte {
+	box := fixture.Box("PRVW", make([]byte, 6), fixture.BE16(width), fixture.BE16(height), make([]byte, 2), fixture.BE32(length), stream)
+	return fixture.Box("uuid", previewUUID[:], make([]byte, 8), box)
+}
+
+// cr3 returns a CR3 file whose Canon uuid box holds canon, followed by the
+// top level boxes extra.
+func cr3(canon [][]byte, extra ...[]byte) []byte {
+	moov := fixture.Box("moov", fixture.Box("uuid", canonUUID[:], fixture.Concat(canon...)))
+	return fixture.Concat(ftyp, moov, fixture.Concat(extra...))
+}
+
+func TestDecode(t *testing.T) {
+	packet := []byte(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="1"/></rdf:RDF>`)
+	data := cr3([][]byte{
+		tiffBox("CMT1", fixture.ASCII(0x010F, "Canon"), fixture.ASCII(0x0110, "Canon EOS R5")),
+		tiffBox("CMT2", fixture.Rational(0x829A, 1, 250)),
+		tiffBox("CMT3", fixture.Short(0x0001, 0, 1)),
+		tiffBox("CMT4", fixture.ASCII(0x0001, "N")),
+		thmb(160, 120, uint32(len(jpegStream)), jpegStream),
+	},
+		// the PRVW length includes padding past the box, it is cut to the box
+		prvw(1620, 1080, 1000, jpegStream),
+		fixture.Box("uuid", xmpUUID[:], packet),
+		fixture.Box("mdat", make([]byte, 16)),
+	)
+
+	imgData, err := metadata.Decode(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if imgData.Format != metadata.FormatCR3 {
+		t.Errorf("Format = %q, want %q", imgData.Format, metadata.FormatCR3)
+	}
+	groups := []struct {
+		name string
+		tags []metadata.IFDtag
+		want int
+	}{
+		{"MainTags", imgData.MetaData.MainTags, 2},
+		{"ExifTags", imgData.MetaData.ExifTags, 1},
+		{"MakerNoteTags", imgData.MetaData.MakerNoteTags, 1},
+		{"GPStags", imgData.MetaData.GPStags, 1},
+	}
+	for _, g := range groups {
+		if len(g.tags) != g.want {
+			t.Errorf("%s = %v, want %d tags", g.name, g.tags, g.want)
+		}
+	}
+	if rating, _ := imgData.XMP.Get(xmp.NSXMP, "Rating"); rating.Value != "1" {
+		t.Errorf("xmp:Rating = %q, want 1", rating.Value)
+	}
+
+	if len(imgData.Previews) != 2 {
+		t.Fatalf("Previews = %+v, want THMB and PRVW", imgData.Previews)
+	}
+	for i, want := range []struct {
+		source        string
+		width, height uint32
+	}{{"CR3 THMB", 160, 120}, {"CR3 PRVW", 1620, 1080}} {
+		preview := imgData.Previews[i]
+		if preview.Source != want.source || preview.Width != want.width || preview.Height != want.height {
+			t.Errorf("Previews[%d] = %+v, want %s %dx%d", i, preview, want.source, want.width, want.height)
+		}
+		stream, err := preview.Read(bytes.NewReader(data))
+		if err != nil || !bytes.Equal(stream, jpegStream) {
+			t.Errorf("Previews[%d].Read() = % x, %v, want % x", i, stream, err, jpegStream)
+		}
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	canon := tiffBox("CMT1", fixture.ASCII(0x010F, "Canon"))
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantErr      bool
+		wantWarnings int
+	}{
+		{name: "no moov box", data: fixture.Concat(ftyp, fixture.Box("mdat")), wantErr: true},
+		{name: "no Canon uuid box", data: fixture.Concat(ftyp, fixture.Box("moov", fixture.Box("trak"))), wantErr: true},
+		{name: "bad CMT1", data: cr3([][]byte{fixture.Box("CMT1", []byte("XX*\x00\x08\x00\x00\x00"))}), wantErr: true},
+		{name: "box past the end", data: fixture.Concat(ftyp, fixture.BE32(100), []byte("moov")), wantErr: true},
+		// a damaged preview or XMP packet keeps the CMT tags
+		{name: "THMB too short", data: cr3([][]byte{canon, fixture.Box("THMB", make([]byte, 10))}), wantWarnings: 1},
+		{name: "THMB without JPEG", data: cr3([][]byte{canon, thmb(160, 120, 4, []byte("GIF8"))}), wantWarnings: 1},
+		{name: "PRVW without JPEG", data: cr3([][]byte{canon}, prvw(1620, 1080, 4, []byte("GIF8"))), wantWarnings: 1},
+		{name: "bad XMP", data: cr3([][]byte{canon}, fixture.Box("uuid", xmpUUID[:], []byte("<x/>"))), wantWarnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := &metadata.ImageData{}
+			err := Decode(bytes.NewReader(tt.data), int64(len(tt.data)), imgData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(imgData.MetaData.MainTags) != 1 || imgData.MetaData.MainTags[0].Data != "Canon" {
+				t.Errorf("MainTags = %v, want Make Canon", imgData.MetaData.MainTags)
+			}
+			if len(imgData.Previews) != 0 || imgData.XMP != nil {
+				t.Errorf("got previews %v and XMP %v, want neither", imgData.Previews, imgData.XMP)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package heif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/justikun/metadata-viewer/pkg/bmff"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/tiff"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// major brands of HEIF and AVIF files
+var (
+	heifBrands = []string{"heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1"}
+	avifBrands = []string{"avif", "avis"}
+)
+
+func init() {
+	for _, brand := range heifBrands {
+		metadata.RegisterFormat(metadata.FormatHEIF, "????ftyp"+brand, Decode)
+	}
+	for _, brand := range avifBrands {
+		metadata.RegisterFormat(metadata.FormatAVIF, "????ftyp"+brand, Decode)
+	}
+}
+
+// Decode reads the meta box of a HEIF or AVIF file: the item list, the
+// primary image size, the Exif item and XMP items. Only a missing or broken
+// meta box is an error, damaged Exif and XMP items are recorded in
+// imgData.Warnings.
+func Decode(r io.ReaderAt, size int64, imgData *metadata.ImageData) error {
+	ftyp, err := bmff.ReadFileType(r, size)
+	if err != nil {
+		return err
+	}
+	if ftyp.HasBrand("avif") || ftyp.HasBrand("avis") {
+		// mif1 files are AVIF when they carry an AV1 brand
+		if !ftyp.HasBrand("heic") && !ftyp.HasBrand("heix") {
+			imgData.Format = metadata.FormatAVIF
+		}
+	}
+	imgData.HEIF = &metadata.HEIF{MajorBrand: ftyp.MajorBrand, CompatibleBrands: ftyp.CompatibleBrands}
+
+	boxes, err := bmff.ReadBoxes(r, 0, size)
+	if err != nil {
+		// a truncated box, usually a partial mdat at the end, does not hide
+		// the boxes before it
+		imgData.AddWarning(err)
+	}
+	metaBox, ok := bmff.Find(boxes, "meta")
+	if !ok {
+		return errors.New("missing meta box")
+	}
+	meta, err := bmff.ReadMeta(r, metaBox)
+	if err != nil {
+		return fmt.Errorf("meta box: %w", err)
+	}
+
+	heif := imgData.HEIF
+	heif.PrimaryItem = meta.PrimaryItem
+	heif.Width, heif.Height, _ = meta.ImageSize(meta.PrimaryItem)
+	for _, item := range meta.Items {
+		heifItem := metadata.HEIFItem{ID: item.ID, Type: item.Type, Name: item.Name, ContentType: item.ContentType}
+		heifItem.Width, heifItem.Height, _ = meta.ImageSize(item.ID)
+		heif.Items = append(heif.Items, heifItem)
+	}
+
+	if exifItem, ok := findExif(meta); ok {
+		if err := parseExif(r, meta, exifItem, imgData); err != nil {
+			imgData.AddWarning(fmt.Errorf("Exif item %d: %w", exifItem.ID, err))
+		}
+	}
+	for _, item := range meta.Items {
+		if item.Type != "mime" || item.ContentType != "application/rdf+xml" {
+			continue
+		}
+		data, err := meta.ReadItem(r, item.ID)
+		if err != nil {
+			imgData.AddWarning(fmt.Errorf("XMP item %d: %w", item.ID, err))
+			continue
+		}
+		xmpPacket, err := xmp.Parse(data)
+		if err != nil {
+			imgData.AddWarning(fmt.Errorf("XMP item %d: %w", item.ID, err))
+			continue
+		}
+		if imgData.XMP == nil {
+			imgData.XMP = xmpPacket
+		} else {
+			imgData.XMP.Merge(xmpPacket)
+		}
+	}
+	return nil
+}
+
+// findExif returns the Exif item that describes the primary image, or the
+// first Exif item when none references it.
+func findExif(meta *bmff.Meta) (bmff.Item, bool) {
+	var first bmff.Item
+	found := false
+	for _, item := range meta.Items {
+		if item.Type != "Exif" {
+			continue
+		}
+		for _, ref := range meta.References {
+			if ref.Type == "cdsc" && ref.From == item.ID && slices.Contains(ref.To, meta.PrimaryItem) {
+				return item, true
+			}
+		}
+		if !found {
+			first, found = item, true
+		}
+	}
+	return first, found
+}
+
+// parseExif passes the TIFF structure of an Exif item to the TIFF parser.
+func parseExif(r io.ReaderAt, meta *bmff.Meta, item bmff.Item, imgData *metadata.ImageData) error {
+	extents, err := meta.ItemExtents(item.ID)
+	if err != nil {
+		return err
+	}
+	if len(extents) == 1 {
+		// keep the offsets absolute to the file
+		return parseExifPayload(r, int64(extents[0].Offset), int64(extents[0].Length), imgData)
+	}
+	data, err := meta.ReadItem(r, item.ID)
+	if err != nil {
+		return err
+	}
+	return parseExifPayload(bytes.NewReader(data), 0, int64(len(data)), imgData)
+}
+
+// parseExifPayload reads the Exif item of length bytes at start in r. The
+// item starts with the 4 byte offset from its end to the Exif block, which
+// usually skips the "Exif\0\0" identifier as well.
+func parseExifPayload(r io.ReaderAt, start, length int64, imgData *metadata.ImageData) error {
+	if length < 4 {
+		return errors.New("item too short for the TIFF header offset")
+	}
+	b := make([]byte, 4)
+	if _, err := r.ReadAt(b, start); err != nil {
+		return fmt.Errorf("failed to read TIFF header offset: %w", err)
+	}
+	headerOffset := int64(binary.BigEndian.Uint32(b))
+	if headerOffset > length-4 {
+		return fmt.Errorf("TIFF header offset %d runs past the item", headerOffset)
+	}
+	return tiff.ParseExif(imgData, r, start+4+headerOffset, length-4-headerOffset)
+}
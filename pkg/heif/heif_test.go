@@ -0,0 +1,136 @@
+package heif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+var (
+	exifPayload = fixture.Concat(fixture.BE32(6), []byte("Exif\x00\x00"),
+		fixture.TIFF(binary.BigEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Apple")}}))
+	xmpPayload = []byte(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="3"/></rdf:RDF>`)
+)
+
+// heic returns a HEIF file of brand with a 4032x3024 primary image, an Exif
+// item and an XMP item whose data follow the meta box in mdat. extra boxes
+// are appended to the file.
+func heic(brand string, exif []byte, extra ...[]byte) []byte {
+	ftyp := fixture.Box("ftyp", []byte(brand), fixture.BE32(0), []byte("mif1"+brand))
+	build := func(mdat uint32) []byte {
+		return fixture.FullBox("meta", 0, 0,
+			fixture.FullBox("hdlr", 0, 0, fixture.BE32(0), []byte("pict"), make([]byte, 12), []byte{0}),
+			fixture.FullBox("pitm", 0, 0, fixture.BE16(1)),
+			fixture.FullBox("iinf", 0, 0, fixture.BE16(3),
+				fixture.Infe(1, "hvc1", "", ""),
+				fixture.Infe(2, "Exif", "", ""),
+				fixture.Infe(3, "mime", "XMP", "application/rdf+xml")),
+			fixture.Iloc(
+				fixture.Location{ID: 2, Base: mdat, Extents: [][2]uint32{{0, uint32(len(exif))}}},
+				fixture.Location{ID: 3, Base: mdat, Extents: [][2]uint32{{uint32(len(exif)), uint32(len(xmpPayload))}}}),
+			fixture.FullBox("iref", 0, 0, fixture.Box("cdsc", fixture.BE16(2), fixture.BE16(1), fixture.BE16(1))),
+			fixture.Box("iprp",
+				fixture.Box("ipco", fixture.FullBox("ispe", 0, 0, fixture.BE32(4032), fixture.BE32(3024))),
+				fixture.FullBox("ipma", 0, 0, fixture.BE32(1), fixture.BE16(1), []byte{1, 0x81})))
+	}
+	// the offsets do not change the size of the meta box
+	mdat := uint32(len(ftyp)+len(build(0))) + 8
+	return fixture.Concat(ftyp, build(mdat), fixture.Box("mdat", exif, xmpPayload), fixture.Concat(extra...))
+}
+
+func decode(t *testing.T, data []byte) (*metadata.ImageData, error) {
+	t.Helper()
+	return metadata.Decode(bytes.NewReader(data), int64(len(data)))
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         []byte
+		wantFormat   metadata.Format
+		wantWarnings int
+	}{
+		{name: "HEIC", data: heic("heic", exifPayload), wantFormat: metadata.FormatHEIF},
+		{name: "AVIF", data: heic("avif", exifPayload), wantFormat: metadata.FormatAVIF},
+		// the header offset 0 leaves the Exif identifier in front of the TIFF header
+		{name: "Exif identifier after offset 0", data: heic("heic", fixture.Concat(fixture.BE32(0), exifPayload[4:])), wantFormat: metadata.FormatHEIF},
+		{
+			// a partial mdat at the end of a download
+			name:         "truncated trailing box",
+			data:         heic("heic", exifPayload, fixture.BE32(1000), []byte("mdat")),
+			wantFormat:   metadata.FormatHEIF,
+			wantWarnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData, err := decode(t, tt.data)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if imgData.Format != tt.wantFormat {
+				t.Errorf("Format = %q, want %q", imgData.Format, tt.wantFormat)
+			}
+			heif := imgData.HEIF
+			if heif.PrimaryItem != 1 || heif.Width != 4032 || heif.Height != 3024 {
+				t.Errorf("PrimaryItem, Width, Height = %d, %d, %d, want 1, 4032, 3024", heif.PrimaryItem, heif.Width, heif.Height)
+			}
+			if len(heif.Items) != 3 || heif.Items[2].ContentType != "application/rdf+xml" {
+				t.Errorf("Items = %+v, want hvc1, Exif and XMP", heif.Items)
+			}
+			if len(imgData.MetaData.MainTags) != 1 || imgData.MetaData.MainTags[0].Data != "Apple" {
+				t.Errorf("MainTags = %v, want Make Apple", imgData.MetaData.MainTags)
+			}
+			if rating, _ := imgData.XMP.Get(xmp.NSXMP, "Rating"); rating.Value != "3" {
+				t.Errorf("xmp:Rating = %q, want 3", rating.Value)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	ftyp := fixture.Box("ftyp", []byte("heic"), fixture.BE32(0))
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantErr      bool
+		wantWarnings int
+	}{
+		{name: "no meta box", data: fixture.Concat(ftyp, fixture.Box("mdat")), wantErr: true},
+		{name: "broken meta box", data: fixture.Concat(ftyp, fixture.FullBox("meta", 0, 0, fixture.FullBox("iloc", 3, 0))), wantErr: true},
+		// a damaged Exif item keeps the items and the XMP packet
+		{name: "TIFF header offset past the item", data: heic("heic", fixture.Concat(fixture.BE32(1000), make([]byte, 8))), wantWarnings: 1},
+		{name: "Exif item too short", data: heic("heic", []byte{0, 0}), wantWarnings: 1},
+		{name: "bad TIFF header", data: heic("heic", fixture.Concat(fixture.BE32(0), []byte("XX*\x00\x08\x00\x00\x00"))), wantWarnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := &metadata.ImageData{}
+			err := Decode(bytes.NewReader(tt.data), int64(len(tt.data)), imgData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(imgData.HEIF.Items) != 3 {
+				t.Errorf("Items = %+v, want hvc1, Exif and XMP", imgData.HEIF.Items)
+			}
+			if rating, _ := imgData.XMP.Get(xmp.NSXMP, "Rating"); rating.Value != "3" {
+				t.Errorf("xmp:Rating = %q, want 3", rating.Value)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
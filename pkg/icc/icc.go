@@ -0,0 +1,162 @@
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+const (
+	headerSize   = 128
+	tagEntrySize = 12
+	signature    = "acsp"
+)
+
+// Parse decodes the header and the tag table of an ICC profile (ICC.1:2022)
+// and reads the desc, cprt and wtpt tags.
+func Parse(data []byte) (*metadata.ICCProfile, error) {
+	if len(data) < headerSize+4 {
+		return nil, fmt.Errorf("profile of %d bytes is shorter than its header", len(data))
+	}
+	size := binary.BigEndian.Uint32(data[0:4])
+	if size < headerSize+4 || int64(size) > int64(len(data)) {
+		return nil, fmt.Errorf("profile size %d does not match the %d bytes read", size, len(data))
+	}
+	data = data[:size]
+	if string(data[36:40]) != signature {
+		return nil, errors.New("missing acsp profile signature")
+	}
+
+	profile := &metadata.ICCProfile{
+		Size:            size,
+		CMM:             fourCC(data[4:8]),
+		Version:         fmt.Sprintf("%d.%d.%d", data[8], data[9]>>4, data[9]&0x0F),
+		Class:           fourCC(data[12:16]),
+		ColorSpace:      fourCC(data[16:20]),
+		PCS:             fourCC(data[20:24]),
+		Created:         dateTime(data[24:36]),
+		Platform:        fourCC(data[40:44]),
+		RenderingIntent: binary.BigEndian.Uint32(data[64:68]),
+		Creator:         fourCC(data[80:84]),
+		Data:            data,
+	}
+
+	count := binary.BigEndian.Uint32(data[headerSize:])
+	if uint64(count) > uint64(len(data)-headerSize-4)/tagEntrySize {
+		return nil, fmt.Errorf("tag table of %d entries runs past the profile", count)
+	}
+	for i := range int(count) {
+		entry := data[headerSize+4+i*tagEntrySize:]
+		tag := metadata.ICCTag{
+			Signature: string(entry[0:4]),
+			Offset:    binary.BigEndian.Uint32(entry[4:8]),
+			Size:      binary.BigEndian.Uint32(entry[8:12]),
+		}
+		if uint64(tag.Offset)+uint64(tag.Size) > uint64(size) {
+			return nil, fmt.Errorf("%q tag runs past the profile", tag.Signature)
+		}
+		profile.Tags = append(profile.Tags, tag)
+
+		tagData := data[tag.Offset : tag.Offset+tag.Size]
+		switch tag.Signature {
+		case "desc":
+			profile.Description = text(tagData)
+		case "cprt":
+			profile.Copyright = text(tagData)
+		case "wtpt":
+			profile.WhitePoint = xyz(tagData)
+		}
+	}
+	return profile, nil
+}
+
+// fourCC returns a signature field without its padding, "" when it is unset.
+func fourCC(b []byte) string {
+	return strings.TrimRight(string(b), " \x00")
+}
+
+// dateTime decodes a dateTimeNumber: year, month, day, hours, minutes and
+// seconds as 16 bit values in UTC.
+func dateTime(b []byte) *time.Time {
+	v := func(i int) int { return int(binary.BigEndian.Uint16(b[2*i:])) }
+	if v(0) == 0 {
+		return nil
+	}
+	t := time.Date(v(0), time.Month(v(1)), v(2), v(3), v(4), v(5), 0, time.UTC)
+	return &t
+}
+
+// text decodes the textDescriptionType (desc) and textType (text) of ICC v2
+// profiles and the multiLocalizedUnicodeType (mluc) of v4 profiles. mluc tags
+// return the English record, or the first one when there is none.
+func text(b []byte) string {
+	if len(b) < 8 {
+		return ""
+	}
+	switch string(b[0:4]) {
+	case "desc":
+		// ASCII count including the NUL, then the ASCII description
+		if len(b) < 12 {
+			return ""
+		}
+		n := min(int(binary.BigEndian.Uint32(b[8:12])), len(b)-12)
+		return strings.TrimRight(string(b[12:12+n]), "\x00")
+	case "text":
+		return strings.TrimRight(string(b[8:]), "\x00")
+	case "mluc":
+		if len(b) < 16 {
+			return ""
+		}
+		records := int(binary.BigEndian.Uint32(b[8:12]))
+		recordSize := int(binary.BigEndian.Uint32(b[12:16]))
+		if recordSize < 12 {
+			return ""
+		}
+		var best string
+		for i := range records {
+			record := 16 + i*recordSize
+			if record+12 > len(b) {
+				break
+			}
+			length := int(binary.BigEndian.Uint32(b[record+4:]))
+			offset := int(binary.BigEndian.Uint32(b[record+8:]))
+			if offset > len(b) || length > len(b)-offset {
+				continue
+			}
+			s := utf16BE(b[offset : offset+length])
+			if string(b[record:record+2]) == "en" {
+				return s
+			}
+			if i == 0 {
+				best = s
+			}
+		}
+		return best
+	}
+	return ""
+}
+
+func utf16BE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// xyz decodes the first value of an XYZType tag, three s15Fixed16Number values.
+func xyz(b []byte) *[3]float64 {
+	if len(b) < 20 || string(b[0:4]) != "XYZ " {
+		return nil
+	}
+	var v [3]float64
+	for i := range v {
+		v[i] = float64(int32(binary.BigEndian.Uint32(b[8+4*i:]))) / 65536
+	}
+	return &v
+}
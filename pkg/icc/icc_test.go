@@ -0,0 +1,147 @@
+package icc
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+)
+
+// mluc returns a multiLocalizedUnicodeType tag with one record per language
+// and text pair.
+func mluc(pairs ...string) []byte {
+	count := len(pairs) / 2
+	records := fixture.Concat([]byte("mluc"), make([]byte, 4), fixture.BE32(uint32(count)), fixture.BE32(12))
+	var texts []byte
+	for i := 0; i < len(pairs); i += 2 {
+		var s []byte
+		for _, u := range utf16.Encode([]rune(pairs[i+1])) {
+			s = binary.BigEndian.AppendUint16(s, u)
+		}
+		offset := 16 + 12*count + len(texts)
+		records = fixture.Concat(records, []byte(pairs[i]), []byte("US"), fixture.BE32(uint32(len(s))), fixture.BE32(uint32(offset)))
+		texts = fixture.Concat(texts, s)
+	}
+	return fixture.Concat(records, texts)
+}
+
+// desc returns a v2 textDescriptionType tag.
+func desc(s string) []byte {
+	return fixture.Concat([]byte("desc"), make([]byte, 4), fixture.BE32(uint32(len(s)+1)), []byte(s), []byte{0}, make([]byte, 79))
+}
+
+// xyzTag returns an XYZType tag holding one value.
+func xyzTag(x, y, z int32) []byte {
+	return fixture.Concat([]byte("XYZ "), make([]byte, 4), fixture.BE32(uint32(x)), fixture.BE32(uint32(y)), fixture.BE32(uint32(z)))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            []byte
+		wantVersion     string
+		wantDescription string
+		wantCopyright   string
+	}{
+		{
+			name: "v2",
+			data: fixture.ICC(2,
+				fixture.ICCTag{Signature: "desc", Data: desc("sRGB IEC61966-2.1")},
+				fixture.ICCTag{Signature: "cprt", Data: fixture.Concat([]byte("text"), make([]byte, 4), []byte("Copyright HP\x00"))},
+				fixture.ICCTag{Signature: "wtpt", Data: xyzTag(63190, 65536, 54061)}),
+			wantVersion:     "2.3.0",
+			wantDescription: "sRGB IEC61966-2.1",
+			wantCopyright:   "Copyright HP",
+		},
+		{
+			name: "v4 English record",
+			data: fixture.ICC(4,
+				fixture.ICCTag{Signature: "desc", Data: mluc("de", "Anzeige P3", "en", "Display P3")},
+				fixture.ICCTag{Signature: "cprt", Data: mluc("fr", "Droits réservés")},
+				fixture.ICCTag{Signature: "wtpt", Data: xyzTag(63190, 65536, 54061)}),
+			wantVersion:     "4.3.0",
+			wantDescription: "Display P3",
+			wantCopyright:   "Droits réservés",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := Parse(tt.data)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if profile.Version != tt.wantVersion || profile.Description != tt.wantDescription || profile.Copyright != tt.wantCopyright {
+				t.Errorf("Version, Description, Copyright = %q, %q, %q, want %q, %q, %q",
+					profile.Version, profile.Description, profile.Copyright, tt.wantVersion, tt.wantDescription, tt.wantCopyright)
+			}
+			if profile.CMM != "lcms" || profile.Class != "mntr" || profile.ColorSpace != "RGB" || profile.PCS != "XYZ" || profile.Platform != "APPL" {
+				t.Errorf("header = %+v", profile)
+			}
+			if profile.ClassName() != "Display Device" {
+				t.Errorf("ClassName() = %q, want Display Device", profile.ClassName())
+			}
+			if want := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC); profile.Created == nil || !profile.Created.Equal(want) {
+				t.Errorf("Created = %v, want %v", profile.Created, want)
+			}
+			if wp := profile.WhitePoint; wp == nil || wp[1] != 1 || wp[0] < 0.964 || wp[0] > 0.965 {
+				t.Errorf("WhitePoint = %v, want D50", wp)
+			}
+			if len(profile.Tags) != 3 || int(profile.Size) != len(tt.data) {
+				t.Errorf("Tags, Size = %+v, %d, want 3 tags and %d bytes", profile.Tags, profile.Size, len(tt.data))
+			}
+		})
+	}
+}
+
+func TestParseCorrupt(t *testing.T) {
+	valid := fixture.ICC(4, fixture.ICCTag{Signature: "desc", Data: mluc("en", "P3")})
+	// patch returns valid with b written at offset
+	patch := func(offset int, b []byte) []byte {
+		data := append([]byte(nil), valid...)
+		copy(data[offset:], b)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "shorter than the header", data: valid[:100]},
+		{name: "size past the data", data: patch(0, fixture.BE32(uint32(len(valid)+1)))},
+		{name: "size smaller than the header", data: patch(0, fixture.BE32(64))},
+		{name: "no acsp signature", data: patch(36, []byte("xxxx"))},
+		{name: "tag table past the profile", data: patch(128, fixture.BE32(1<<30))},
+		{name: "tag past the profile", data: patch(136, fixture.BE32(uint32(len(valid))))},
+		// offset + size would wrap around in 32 bits
+		{name: "tag offset overflow", data: patch(136, fixture.BE32(0xFFFFFFF0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data); err == nil {
+				t.Error("Parse() succeeded")
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "first record without English", data: mluc("de", "Farbe", "fr", "Couleur"), want: "Farbe"},
+		{name: "record past the tag", data: fixture.Concat([]byte("mluc"), make([]byte, 4), fixture.BE32(1), fixture.BE32(12), []byte("enUS"), fixture.BE32(100), fixture.BE32(28)), want: ""},
+		{name: "record size too small", data: fixture.Concat([]byte("mluc"), make([]byte, 4), fixture.BE32(1), fixture.BE32(4)), want: ""},
+		{name: "desc count past the tag", data: fixture.Concat([]byte("desc"), make([]byte, 4), fixture.BE32(100), []byte("sRGB")), want: "sRGB"},
+		{name: "unknown type", data: fixture.Concat([]byte("curv"), make([]byte, 8)), want: ""},
+		{name: "short", data: []byte("text"), want: ""},
+	}
+	for _, tt := range tests {
+		if got := text(tt.data); got != tt.want {
+			t.Errorf("%s: text() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package iptc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+// tagMarker starts every IIM dataset.
+const tagMarker = 0x1C
+
+// utf8Escape is the ISO 2022 escape sequence dataset 1:90 holds for UTF-8.
+const utf8Escape = "\x1B%G"
+
+// value kinds of the datasets
+const (
+	kindText = iota
+	kindUint16
+	kindCharset
+	kindBinary
+)
+
+type datasetDef struct {
+	name       string
+	repeatable bool
+	kind       int
+}
+
+// datasets holds the Envelope (1) and Application (2) records of IIM 4.2.
+var datasets = map[[2]uint8]datasetDef{
+	{1, 0}:   {"Envelope Record Version", false, kindUint16},
+	{1, 5}:   {"Destination", true, kindText},
+	{1, 20}:  {"File Format", false, kindUint16},
+	{1, 22}:  {"File Format Version", false, kindUint16},
+	{1, 30}:  {"Service Identifier", false, kindText},
+	{1, 40}:  {"Envelope Number", false, kindText},
+	{1, 50}:  {"Product ID", true, kindText},
+	{1, 60}:  {"Envelope Priority", false, kindText},
+	{1, 70}:  {"Date Sent", false, kindText},
+	{1, 80}:  {"Time Sent", false, kindText},
+	{1, 90}:  {"Coded Character Set", false, kindCharset},
+	{1, 100}: {"Unique Name Of Object", false, kindText},
+	{2, 0}:   {"Application Record Version", false, kindUint16},
+	{2, 3}:   {"Object Type Reference", false, kindText},
+	{2, 4}:   {"Object Attribute Reference", true, kindText},
+	{2, 5}:   {"Object Name", false, kindText},
+	{2, 7}:   {"Edit Status", false, kindText},
+	{2, 10}:  {"Urgency", false, kindText},
+	{2, 12}:  {"Subject Reference", true, kindText},
+	{2, 15}:  {"Category", false, kindText},
+	{2, 20}:  {"Supplemental Categories", true, kindText},
+	{2, 22}:  {"Fixture Identifier", false, kindText},
+	{2, 25}:  {"Keywords", true, kindText},
+	{2, 26}:  {"Content Location Code", true, kindText},
+	{2, 27}:  {"Content Location Name", true, kindText},
+	{2, 30}:  {"Release Date", false, kindText},
+	{2, 35}:  {"Release Time", false, kindText},
+	{2, 37}:  {"Expiration Date", false, kindText},
+	{2, 38}:  {"Expiration Time", false, kindText},
+	{2, 40}:  {"Special Instructions", false, kindText},
+	{2, 42}:  {"Action Advised", false, kindText},
+	{2, 45}:  {"Reference Service", true, kindText},
+	{2, 47}:  {"Reference Date", true, kindText},
+	{2, 50}:  {"Reference Number", true, kindText},
+	{2, 55}:  {"Date Created", false, kindText},
+	{2, 60}:  {"Time Created", false, kindText},
+	{2, 62}:  {"Digital Creation Date", false, kindText},
+	{2, 63}:  {"Digital Creation Time", false, kindText},
+	{2, 65}:  {"Originating Program", false, kindText},
+	{2, 70}:  {"Program Version", false, kindText},
+	{2, 75}:  {"Object Cycle", false, kindText},
+	{2, 80}:  {"By-line", true, kindText},
+	{2, 85}:  {"By-line Title", true, kindText},
+	{2, 90}:  {"City", false, kindText},
+	{2, 92}:  {"Sub-location", false, kindText},
+	{2, 95}:  {"Province-State", false, kindText},
+	{2, 100}: {"Country-Primary Location Code", false, kindText},
+	{2, 101}: {"Country-Primary Location Name", false, kindText},
+	{2, 103}: {"Original Transmission Reference", false, kindText},
+	{2, 105}: {"Headline", false, kindText},
+	{2, 110}: {"Credit", false, kindText},
+	{2, 115}: {"Source", false, kindText},
+	{2, 116}: {"Copyright Notice", false, kindText},
+	{2, 118}: {"Contact", true, kindText},
+	{2, 120}: {"Caption-Abstract", false, kindText},
+	{2, 121}: {"Local Caption", false, kindText},
+	{2, 122}: {"Writer-Editor", true, kindText},
+	{2, 125}: {"Rasterized Caption", false, kindBinary},
+	{2, 130}: {"Image Type", false, kindText},
+	{2, 131}: {"Image Orientation", false, kindText},
+	{2, 135}: {"Language Identifier", false, kindText},
+}
+
+// Parse decodes the IIM datasets in data. Text is UTF-8 when dataset 1:90
+// says so and ISO 8859-1 otherwise, as IIM specifies.
+func Parse(data []byte) (*metadata.IPTC, error) {
+	iptc := &metadata.IPTC{}
+	index := map[[2]uint8]int{}
+	for pos := 0; pos < len(data); {
+		if data[pos] != tagMarker {
+			// Photoshop pads the resource with zeros
+			if len(bytes.Trim(data[pos:], "\x00")) == 0 {
+				break
+			}
+			return nil, fmt.Errorf("missing dataset marker at %d", pos)
+		}
+		if pos+5 > len(data) {
+			return nil, fmt.Errorf("dataset header at %d runs past the data", pos)
+		}
+		record, id := data[pos+1], data[pos+2]
+		size := int(binary.BigEndian.Uint16(data[pos+3:]))
+		pos += 5
+		if size&0x8000 != 0 {
+			// extended dataset, the low bits give the size of the length field
+			n := size & 0x7FFF
+			if n > 4 || pos+n > len(data) {
+				return nil, fmt.Errorf("dataset %d:%d has an invalid extended length", record, id)
+			}
+			size = 0
+			for _, c := range data[pos : pos+n] {
+				size = size<<8 | int(c)
+			}
+			pos += n
+		}
+		if size < 0 || size > len(data)-pos {
+			return nil, fmt.Errorf("dataset %d:%d runs past the data", record, id)
+		}
+		value := data[pos : pos+size]
+		pos += size
+
+		key := [2]uint8{record, id}
+		def, ok := datasets[key]
+		if !ok {
+			def = datasetDef{name: fmt.Sprintf("Unknown_%d:%d", record, id)}
+		}
+		if def.kind == kindCharset && string(value) == utf8Escape {
+			iptc.CharacterSet = "UTF-8"
+		}
+		text := decode(value, def.kind, iptc.CharacterSet)
+
+		if i, ok := index[key]; ok {
+			iptc.Datasets[i].Values = append(iptc.Datasets[i].Values, text)
+			continue
+		}
+		index[key] = len(iptc.Datasets)
+		iptc.Datasets = append(iptc.Datasets, metadata.IPTCDataset{
+			Record: record, ID: id, Name: def.name, Repeatable: def.repeatable, Values: []string{text},
+		})
+	}
+	return iptc, nil
+}
+
+// decode turns a dataset value into a string.
+func decode(value []byte, kind int, charset string) string {
+	switch kind {
+	case kindUint16:
+		if len(value) == 2 {
+			return strconv.Itoa(int(binary.BigEndian.Uint16(value)))
+		}
+	case kindCharset:
+		if string(value) == utf8Escape {
+			return "UTF-8"
+		}
+		return fmt.Sprintf("%q", value)
+	case kindBinary:
+		return fmt.Sprintf("(%d bytes binary data)", len(value))
+	}
+	if charset == "UTF-8" && utf8.Valid(value) {
+		return string(value)
+	}
+	return latin1(value)
+}
+
+// latin1 decodes ISO 8859-1 text.
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
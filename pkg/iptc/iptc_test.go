@@ -0,0 +1,125 @@
+package iptc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		wantCharset string
+		want        []metadata.IPTCDataset
+	}{
+		{
+			name: "UTF-8",
+			data: fixture.Concat(
+				fixture.Dataset(1, 90, utf8Escape),
+				fixture.Dataset(2, 0, "\x00\x04"),
+				fixture.Dataset(2, 25, "Zürich"),
+				fixture.Dataset(2, 80, "Jane Doe"),
+				fixture.Dataset(2, 25, "lake"),
+				fixture.Dataset(2, 120, "Sunset über dem See"),
+			),
+			wantCharset: "UTF-8",
+			want: []metadata.IPTCDataset{
+				{Record: 1, ID: 90, Name: "Coded Character Set", Values: []string{"UTF-8"}},
+				{Record: 2, ID: 0, Name: "Application Record Version", Values: []string{"4"}},
+				{Record: 2, ID: 25, Name: "Keywords", Repeatable: true, Values: []string{"Zürich", "lake"}},
+				{Record: 2, ID: 80, Name: "By-line", Repeatable: true, Values: []string{"Jane Doe"}},
+				{Record: 2, ID: 120, Name: "Caption-Abstract", Values: []string{"Sunset über dem See"}},
+			},
+		},
+		{
+			// without 1:90 the text is ISO 8859-1
+			name: "Latin-1",
+			data: fixture.Concat(fixture.Dataset(2, 90, "Z\xfcrich"), fixture.Dataset(2, 116, "\xa9 2024")),
+			want: []metadata.IPTCDataset{
+				{Record: 2, ID: 90, Name: "City", Values: []string{"Zürich"}},
+				{Record: 2, ID: 116, Name: "Copyright Notice", Values: []string{"© 2024"}},
+			},
+		},
+		{
+			name:        "invalid UTF-8 falls back to Latin-1",
+			data:        fixture.Concat(fixture.Dataset(1, 90, utf8Escape), fixture.Dataset(2, 5, "caf\xe9")),
+			wantCharset: "UTF-8",
+			want: []metadata.IPTCDataset{
+				{Record: 1, ID: 90, Name: "Coded Character Set", Values: []string{"UTF-8"}},
+				{Record: 2, ID: 5, Name: "Object Name", Values: []string{"café"}},
+			},
+		},
+		{
+			name: "binary, unknown and other character sets",
+			data: fixture.Concat(fixture.Dataset(1, 90, "\x1b-A"), fixture.Dataset(2, 125, "\x00\x01\x02"), fixture.Dataset(2, 200, "x")),
+			want: []metadata.IPTCDataset{
+				{Record: 1, ID: 90, Name: "Coded Character Set", Values: []string{`"\x1b-A"`}},
+				{Record: 2, ID: 125, Name: "Rasterized Caption", Values: []string{"(3 bytes binary data)"}},
+				{Record: 2, ID: 200, Name: "Unknown_2:200", Values: []string{"x"}},
+			},
+		},
+		{
+			// a 4 byte length field follows the size with its top bit set
+			name: "extended dataset",
+			data: fixture.Concat([]byte{0x1C, 2, 120, 0x80, 0x04}, fixture.BE32(5), []byte("hello")),
+			want: []metadata.IPTCDataset{{Record: 2, ID: 120, Name: "Caption-Abstract", Values: []string{"hello"}}},
+		},
+		{
+			name: "zero padding",
+			data: fixture.Concat(fixture.Dataset(2, 105, "Headline"), make([]byte, 3)),
+			want: []metadata.IPTCDataset{{Record: 2, ID: 105, Name: "Headline", Values: []string{"Headline"}}},
+		},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.data)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got.CharacterSet != tt.wantCharset {
+				t.Errorf("CharacterSet = %q, want %q", got.CharacterSet, tt.wantCharset)
+			}
+			if !reflect.DeepEqual(got.Datasets, tt.want) {
+				t.Errorf("Datasets = %+v\nwant %+v", got.Datasets, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCorrupt(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "missing marker", data: fixture.Concat(fixture.Dataset(2, 5, "a"), []byte{0x1D, 2, 5, 0, 0})},
+		{name: "header cut short", data: []byte{0x1C, 2, 5, 0}},
+		{name: "value past the data", data: fixture.Concat([]byte{0x1C, 2, 5}, fixture.BE16(10), []byte("abc"))},
+		{name: "extended length field too long", data: fixture.Concat([]byte{0x1C, 2, 120, 0x80, 0x05}, make([]byte, 5))},
+		{name: "extended length field past the data", data: []byte{0x1C, 2, 120, 0x80, 0x04, 0x00}},
+		{name: "extended value past the data", data: fixture.Concat([]byte{0x1C, 2, 120, 0x80, 0x04}, fixture.BE32(1<<20))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data); err == nil {
+				t.Error("Parse() succeeded")
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	iptc, err := Parse(fixture.Concat(fixture.Dataset(2, 25, "a"), fixture.Dataset(2, 25, "b")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keywords, ok := iptc.Get(2, 25); !ok || !reflect.DeepEqual(keywords.Values, []string{"a", "b"}) {
+		t.Errorf("Get(2, 25) = %+v, %v, want keywords a and b", keywords, ok)
+	}
+	if _, ok := iptc.Get(2, 80); ok {
+		t.Error("Get(2, 80) found a missing dataset")
+	}
+}
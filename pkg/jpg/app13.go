@@ -0,0 +1,24 @@
+package jpg
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/photoshop"
+)
+
+// ParseAPP13 reads the Photoshop image resources held in the APP13 payload
+// that starts at offset in r and is length bytes long.
+func ParseAPP13(r io.ReaderAt, offset int64, length int64, imgData *metadata.ImageData) error {
+	payload := make([]byte, length)
+	if _, err := r.ReadAt(payload, offset); err != nil {
+		return fmt.Errorf("failed to read APP13: %w", err)
+	}
+	if len(payload) < len(photoshop.Identifier) || string(payload[:len(photoshop.Identifier)]) != photoshop.Identifier {
+		// other APP13 payloads carry no metadata we understand
+		return nil
+	}
+	n := len(photoshop.Identifier)
+	return photoshop.Decode(payload[n:], offset+int64(n), imgData)
+}
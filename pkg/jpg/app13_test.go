@@ -0,0 +1,53 @@
+package jpg
+
+import (
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/photoshop"
+)
+
+func TestParseAPP13(t *testing.T) {
+	iptc := fixture.Resource(photoshop.IPTCResource, "", fixture.Concat(fixture.Dataset(1, 90, "\x1b%G"), fixture.Dataset(2, 80, "Jürgen")))
+	url := fixture.Resource(photoshop.URLResource, "", []byte("https://example.com"))
+
+	tests := []struct {
+		name          string
+		segments      [][]byte
+		wantByline    string
+		wantResources int
+		wantURL       string
+		wantWarnings  int
+	}{
+		{name: "IPTC", segments: [][]byte{fixture.APP13(iptc)}, wantByline: "Jürgen", wantResources: 1},
+		{name: "other APP13 payload", segments: [][]byte{fixture.Segment(0xED, []byte("Adobe_CM\x00"))}},
+		{name: "broken resource list", segments: [][]byte{fixture.APP13(iptc, []byte("8BIX"))}, wantWarnings: 1},
+		// a large resource section is split over several segments
+		{name: "two segments", segments: [][]byte{fixture.APP13(url), fixture.APP13(iptc)}, wantByline: "Jürgen", wantResources: 2, wantURL: "https://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := decode(t, fixture.JPEG(8, 8, tt.segments...))
+			byline := ""
+			if imgData.IPTC != nil {
+				if dataset, ok := imgData.IPTC.Get(2, 80); ok {
+					byline = dataset.Values[0]
+				}
+			}
+			if byline != tt.wantByline {
+				t.Errorf("By-line = %q, want %q", byline, tt.wantByline)
+			}
+			var resources int
+			var url string
+			if imgData.Photoshop != nil {
+				resources, url = len(imgData.Photoshop.Resources), imgData.Photoshop.URL
+			}
+			if resources != tt.wantResources || url != tt.wantURL {
+				t.Errorf("Photoshop = %+v, want %d resources and URL %q", imgData.Photoshop, tt.wantResources, tt.wantURL)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package jpg
+
+import (
+	"fmt"
+	"io"
+	"slices"
+)
+
+// ICC profiles larger than one segment are split across APP2 segments that
+// each start with
+//
+//	"ICC_PROFILE\x00"
+//	1 byte sequence number, starting at 1
+//	1 byte number of chunks
+//	chunk data
+const (
+	iccIdentifier = "ICC_PROFILE\x00"
+	iccHeaderSize = len(iccIdentifier) + 2
+)
+
+func isICC(r io.ReaderAt, offset int64, length int64) bool {
+	if length < int64(iccHeaderSize) {
+		return false
+	}
+	identifier := make([]byte, len(iccIdentifier))
+	if _, err := r.ReadAt(identifier, offset); err != nil {
+		return false
+	}
+	return string(identifier) == iccIdentifier
+}
+
+// iccChunks collects the chunks of the ICC profile by sequence number.
+type iccChunks struct {
+	count  int
+	chunks map[int][]byte
+}
+
+func newICCChunks() *iccChunks {
+	return &iccChunks{chunks: map[int][]byte{}}
+}
+
+// add stores the chunk held in the APP2 payload at offset.
+func (c *iccChunks) add(r io.ReaderAt, offset int64, length int64) error {
+	segment := make([]byte, length)
+	if _, err := r.ReadAt(segment, offset); err != nil {
+		return fmt.Errorf("failed to read ICC profile chunk: %w", err)
+	}
+	seq, count := int(segment[len(iccIdentifier)]), int(segment[len(iccIdentifier)+1])
+	if count == 0 || seq == 0 || seq > count {
+		return fmt.Errorf("ICC profile chunk %d of %d is out of range", seq, count)
+	}
+	if c.count != 0 && c.count != count {
+		return fmt.Errorf("ICC profile chunk count changed from %d to %d", c.count, count)
+	}
+	if _, ok := c.chunks[seq]; ok {
+		return fmt.Errorf("duplicate ICC profile chunk %d", seq)
+	}
+	c.count = count
+	c.chunks[seq] = segment[iccHeaderSize:]
+	return nil
+}
+
+// profile returns the chunks joined in sequence order, nil when there are none.
+func (c *iccChunks) profile() ([]byte, error) {
+	if len(c.chunks) == 0 {
+		return nil, nil
+	}
+	if len(c.chunks) != c.count {
+		return nil, fmt.Errorf("got %d of %d ICC profile chunks", len(c.chunks), c.count)
+	}
+	var chunks [][]byte
+	for seq := 1; seq <= c.count; seq++ {
+		chunks = append(chunks, c.chunks[seq])
+	}
+	return slices.Concat(chunks...), nil
+}
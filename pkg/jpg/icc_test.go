@@ -0,0 +1,52 @@
+package jpg
+
+import (
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+)
+
+// iccChunk returns an APP2 segment holding chunk seq of count.
+func iccChunk(seq, count byte, data []byte) []byte {
+	return fixture.Segment(0xE2, fixture.Concat([]byte(iccIdentifier), []byte{seq, count}, data))
+}
+
+func TestICCChunks(t *testing.T) {
+	desc := fixture.Concat([]byte("desc"), make([]byte, 4), fixture.BE32(5), []byte("sRGB\x00"))
+	profile := fixture.ICC(2, fixture.ICCTag{Signature: "desc", Data: desc})
+	first, second := profile[:100], profile[100:]
+
+	tests := []struct {
+		name         string
+		segments     [][]byte
+		wantProfile  bool
+		wantWarnings int
+	}{
+		{name: "one chunk", segments: [][]byte{iccChunk(1, 1, profile)}, wantProfile: true},
+		{name: "two chunks", segments: [][]byte{iccChunk(1, 2, first), iccChunk(2, 2, second)}, wantProfile: true},
+		{name: "chunks out of order", segments: [][]byte{iccChunk(2, 2, second), iccChunk(1, 2, first)}, wantProfile: true},
+		{name: "missing chunk", segments: [][]byte{iccChunk(1, 2, first)}, wantWarnings: 1},
+		{name: "duplicate chunk", segments: [][]byte{iccChunk(1, 2, first), iccChunk(1, 2, first), iccChunk(2, 2, second)}, wantProfile: true, wantWarnings: 1},
+		{name: "sequence 0", segments: [][]byte{iccChunk(0, 1, profile), iccChunk(1, 1, profile)}, wantProfile: true, wantWarnings: 1},
+		{name: "sequence past the count", segments: [][]byte{iccChunk(3, 2, second)}, wantWarnings: 1},
+		{name: "count 0", segments: [][]byte{iccChunk(1, 0, profile)}, wantWarnings: 1},
+		// the second chunk is dropped, so the first stays incomplete
+		{name: "count changed", segments: [][]byte{iccChunk(1, 2, first), iccChunk(2, 3, second)}, wantWarnings: 2},
+		{name: "not a profile", segments: [][]byte{iccChunk(1, 1, []byte("profile"))}, wantWarnings: 1},
+		{name: "no profile", segments: [][]byte{jfifSegment}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := decode(t, fixture.JPEG(8, 8, tt.segments...))
+			if (imgData.ICC != nil) != tt.wantProfile {
+				t.Fatalf("ICC = %+v, want a profile %v", imgData.ICC, tt.wantProfile)
+			}
+			if tt.wantProfile && imgData.ICC.Description != "sRGB" {
+				t.Errorf("Description = %q, want sRGB", imgData.ICC.Description)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
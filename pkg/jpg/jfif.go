@@ -0,0 +1,105 @@
+package jpg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+// ParseAPP0 reads the APP0 payload that starts at offset in r and is length
+// bytes long. Both the JFIF header and the JFXX extension use APP0.
+func ParseAPP0(r io.ReaderAt, offset int64, length int64, imgData *metadata.ImageData) error {
+	segmentReader := io.NewSectionReader(r, offset, length)
+	br := metadata.NewBinaryReader(segmentReader, binary.BigEndian)
+
+	// check identifier
+	identifier, err := br.ReadBytes(5)
+	if err != nil {
+		return fmt.Errorf("APP0: Failed to read identifier: %w", err)
+	}
+	switch string(identifier) {
+	case "JFIF\x00":
+		return parseJFIF(br, imgData)
+	case "JFXX\x00":
+		return parseJFXX(br, offset+5, length-5, imgData)
+	default:
+		// other APP0 payloads (e.g. AVI1 in motion JPEG) carry no metadata
+		return nil
+	}
+}
+
+func parseJFIF(br *metadata.BinaryReader, imgData *metadata.ImageData) error {
+	jfif := &metadata.JFIF{}
+	header, err := br.ReadBytes(9)
+	if err != nil {
+		return fmt.Errorf("APP0: Failed to read JFIF header: %w", err)
+	}
+	jfif.VersionMajor = header[0]
+	jfif.VersionMinor = header[1]
+	jfif.DensityUnits = header[2]                        // 00 - no units / 01 - DPI / 02 - Dots per centimeter
+	jfif.XDensity = binary.BigEndian.Uint16(header[3:5]) // horizontal pixel density
+	jfif.YDensity = binary.BigEndian.Uint16(header[5:7]) // vertical pixel density
+	jfif.ThumbWidth = header[7]                          // 00 is no thumbnail
+	jfif.ThumbHeight = header[8]                         // 00 is no thumbnail
+
+	// w * h * 3 = x bytes
+	thumbnBytes := int(jfif.ThumbWidth) * int(jfif.ThumbHeight) * 3
+	jfif.Thumbnail, err = readOptional(br, thumbnBytes)
+	if err != nil {
+		return fmt.Errorf("APP0: Failed to read thumbnail data: %w", err)
+	}
+	imgData.JFIF = jfif
+	return nil
+}
+
+// parseJFXX reads the extension code and the thumbnail that follows it.
+// dataStart and length describe the payload after the "JFXX\x00" identifier.
+func parseJFXX(br *metadata.BinaryReader, dataStart int64, length int64, imgData *metadata.ImageData) error {
+	code, err := br.ReadUint8()
+	if err != nil {
+		return fmt.Errorf("APP0: Failed to read JFXX extension code: %w", err)
+	}
+	jfxx := &metadata.JFXX{Format: code}
+
+	switch code {
+	case metadata.JFXXJPEG:
+		// the rest of the segment is a complete JPEG stream
+		jfxx.Offset = dataStart + 1
+		jfxx.Data, err = readOptional(br, int(length-1))
+	case metadata.JFXXPalette, metadata.JFXXRGB:
+		if jfxx.Width, err = br.ReadUint8(); err != nil {
+			return fmt.Errorf("APP0: Failed to read JFXX thumbnail width: %w", err)
+		}
+		if jfxx.Height, err = br.ReadUint8(); err != nil {
+			return fmt.Errorf("APP0: Failed to read JFXX thumbnail height: %w", err)
+		}
+		bytesPerPixel := 3
+		headerSize := int64(3)
+		if code == metadata.JFXXPalette {
+			bytesPerPixel = 1
+			headerSize += 768
+			if jfxx.Palette, err = br.ReadBytes(768); err != nil {
+				return fmt.Errorf("APP0: Failed to read JFXX palette: %w", err)
+			}
+		}
+		jfxx.Offset = dataStart + headerSize
+		jfxx.Data, err = readOptional(br, int(jfxx.Width)*int(jfxx.Height)*bytesPerPixel)
+	default:
+		// unknown extension, Format records the code and there is no thumbnail to read
+	}
+	if err != nil {
+		return fmt.Errorf("APP0: Failed to read JFXX thumbnail: %w", err)
+	}
+	imgData.JFXX = jfxx
+	return nil
+}
+
+// readOptional reads count bytes, a count of 0 yields nil.
+func readOptional(br *metadata.BinaryReader, count int) ([]byte, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+	return br.ReadBytes(count)
+}
@@ -0,0 +1,84 @@
+package jpg
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+// decode runs Decode on data.
+func decode(t *testing.T, data []byte) *metadata.ImageData {
+	t.Helper()
+	imgData := &metadata.ImageData{}
+	if err := Decode(bytes.NewReader(data), int64(len(data)), imgData); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	return imgData
+}
+
+func TestParseAPP0(t *testing.T) {
+	// the APP0 payload starts after SOI, the marker and the length
+	const payload = 6
+	thumb := fixture.JPEG(1, 1)
+
+	tests := []struct {
+		name         string
+		payload      string
+		wantJFIF     *metadata.JFIF
+		wantJFXX     *metadata.JFXX
+		wantWarnings int
+	}{
+		{
+			name:     "JFIF 72 dpi",
+			payload:  "JFIF\x00\x01\x02\x01\x00\x48\x00\x48\x00\x00",
+			wantJFIF: &metadata.JFIF{VersionMajor: 1, VersionMinor: 2, DensityUnits: 1, XDensity: 72, YDensity: 72},
+		},
+		{
+			name:    "JFIF RGB thumbnail",
+			payload: "JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x01\x02\xff\x00\x00\x00\xff\x00",
+			wantJFIF: &metadata.JFIF{VersionMajor: 1, VersionMinor: 1, XDensity: 1, YDensity: 1, ThumbWidth: 1, ThumbHeight: 2,
+				Thumbnail: []byte{0xff, 0, 0, 0, 0xff, 0}},
+		},
+		{name: "JFIF thumbnail cut short", payload: "JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x02\x02\xff", wantWarnings: 1},
+		{name: "JFIF header cut short", payload: "JFIF\x00\x01\x01", wantWarnings: 1},
+		{
+			name:     "JFXX JPEG",
+			payload:  "JFXX\x00\x10" + string(thumb),
+			wantJFXX: &metadata.JFXX{Format: metadata.JFXXJPEG, Data: thumb, Offset: payload + 6},
+		},
+		{
+			name:     "JFXX RGB",
+			payload:  "JFXX\x00\x13\x02\x01\x01\x02\x03\x04\x05\x06",
+			wantJFXX: &metadata.JFXX{Format: metadata.JFXXRGB, Width: 2, Height: 1, Data: []byte{1, 2, 3, 4, 5, 6}, Offset: payload + 8},
+		},
+		{
+			name:     "JFXX palette",
+			payload:  "JFXX\x00\x11\x01\x01" + string(make([]byte, 768)) + "\x07",
+			wantJFXX: &metadata.JFXX{Format: metadata.JFXXPalette, Width: 1, Height: 1, Palette: make([]byte, 768), Data: []byte{7}, Offset: payload + 8 + 768},
+		},
+		{
+			name:     "JFXX unknown extension",
+			payload:  "JFXX\x00\x42\x01\x02",
+			wantJFXX: &metadata.JFXX{Format: 0x42},
+		},
+		{name: "JFXX RGB cut short", payload: "JFXX\x00\x13\x02\x02\x01", wantWarnings: 1},
+		{name: "AVI1", payload: "AVI1\x00\x00\x00\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := decode(t, fixture.JPEG(8, 8, fixture.Segment(0xE0, []byte(tt.payload))))
+			if !reflect.DeepEqual(imgData.JFIF, tt.wantJFIF) {
+				t.Errorf("JFIF = %+v, want %+v", imgData.JFIF, tt.wantJFIF)
+			}
+			if !reflect.DeepEqual(imgData.JFXX, tt.wantJFXX) {
+				t.Errorf("JFXX = %+v, want %+v", imgData.JFXX, tt.wantJFXX)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -2,157 +2,181 @@ package jpg
 
 import (
 	"bytes"
-	"encoding/binary"
-	"errors"
 	"fmt"
 	"io"
-	"os"
+	"slices"
 
+	"github.com/justikun/metadata-viewer/pkg/icc"
 	"github.com/justikun/metadata-viewer/pkg/metadata"
 	"github.com/justikun/metadata-viewer/pkg/tiff"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
 )
 
-func ParseAPP0(file *os.File, imgData *metadata.ImageData) error {
-	// get data length
-	dataLengthB := make([]byte, 2)
-	_, err := file.Read(dataLengthB)
-	if err != nil {
-		return fmt.Errorf("Failed to read data length")
-	}
-	dataLength := int(binary.BigEndian.Uint16(dataLengthB)) - 2
+// APP1 payload identifiers
+const (
+	exifIdentifier = "Exif\x00\x00"
+	xmpIdentifier  = "http://ns.adobe.com/xap/1.0/\x00"
+)
 
-	// update main file app1Reader
-	buf := make([]byte, dataLength)
-	_, err = file.Read(buf)
+func init() {
+	metadata.RegisterFormat(metadata.FormatJPEG, "\xff\xd8", Decode)
+}
 
-	// create new app1Reader
-	segmentReader := bytes.NewReader(buf)
+// Decode walks the marker segments of the JPEG stream in r up to the start of
+// the image data and fills imgData from the segments it understands.
+func Decode(r io.ReaderAt, size int64, imgData *metadata.ImageData) error {
+	return DecodeEmbedded(r, 0, size, imgData)
+}
 
-	// check JFIF identifier
-	identifier := make([]byte, 5)
-	_, err = segmentReader.Read(identifier)
-	if err != nil {
+// DecodeEmbedded is Decode for a JPEG stream of length bytes stored at offset
+// inside another container, e.g. the preview of a RAF file. Offsets, such as
+// those of the Exif IFDs, stay absolute to r.
+func DecodeEmbedded(r io.ReaderAt, offset int64, length int64, imgData *metadata.ImageData) error {
+	extXMP := newExtendedXMP(length)
+	iccProfile := newICCChunks()
+	if err := walkSegments(io.NewSectionReader(r, 0, offset+length), offset, offset+length, imgData, extXMP, iccProfile); err != nil {
 		return err
 	}
-	if string(identifier) != "JFIF\x00" {
-		return fmt.Errorf("Not JFIF compliant")
-	}
-
-	// get version
-	version, err := segmentReader.Read(make([]byte, 2))
-	if err != nil {
-		return fmt.Errorf("APP0: Failed to read version")
-	}
-	units, err := segmentReader.Read(make([]byte, 1)) // 00 - no units / 01 - DPI / 02 - Dots per centimeter
-	if err != nil {
-		return fmt.Errorf("APP0: Failed to read units")
-	}
-	xDensity, err := segmentReader.Read(make([]byte, 2)) // horizontal pixel density
-	if err != nil {
-		return fmt.Errorf("APP0: Failed to read xDensity")
-	}
-	yDensity, err := segmentReader.Read(make([]byte, 2)) // vertical pixel density
-	if err != nil {
-		return fmt.Errorf("APP0: Failed to read yDensity")
+	if err := extXMP.merge(imgData); err != nil {
+		imgData.AddWarning(err)
 	}
-	thumbnW, err := segmentReader.Read(make([]byte, 1)) // 00 is no thumbnail
+	profile, err := iccProfile.profile()
 	if err != nil {
-		return fmt.Errorf("APP0: Failed to read thumbnail width")
+		imgData.AddWarning(err)
+		return nil
 	}
-	thumbnH, err := segmentReader.Read(make([]byte, 1)) // 00 is no thumbnail
-	if err != nil {
-		return fmt.Errorf("APP0: Failed to read thumbnail height")
+	if profile == nil {
+		return nil
 	}
-	// w * h * 3 = x bytes
-	thumbnBytes := thumbnW * thumbnH * 3
-	thumbnData, err := segmentReader.Read(make([]byte, thumbnBytes))
-	if err != nil {
-		return fmt.Errorf("APP0: Failed to read thumbnail data")
+	if imgData.ICC, err = icc.Parse(profile); err != nil {
+		imgData.AddWarning(fmt.Errorf("ICC profile: %w", err))
 	}
-
-	fmt.Printf("\n\n\nSpitting Data:%v%v%v%v%v%v%v%v\n\n\n", thumbnData, version, units, xDensity, yDensity, thumbnW, thumbnH, identifier)
-	// There can be other non standard data past this part
-	// I have not parsed it yet
-	// I have pre-read this data with the main file app1Reader in order to continue
-
-	// TODO: Read non standard data
-	// TOOD: Save Data to an objetc
-	//
 	return nil
 }
 
-func ParseAPP1(file *os.File, imgData *metadata.ImageData) error {
-	// check APP1 payload size
-	buff := make([]byte, 2)
-	_, err := io.ReadFull(file, buff)
-	if err != nil {
-		return err
+// walkSegments parses the metadata segments between start and end. Only a
+// missing SOI is an error, segments that fail to parse and a broken marker
+// structure after them are recorded in imgData.Warnings.
+func walkSegments(r io.ReaderAt, start int64, end int64, imgData *metadata.ImageData, extXMP *extendedXMP, iccProfile *iccChunks) error {
+	segments, headerErr := readHeader(r, start, end)
+	if len(segments) == 0 {
+		return headerErr
+	}
+	for _, segment := range segments {
+		payloadStart, payloadLength := segment.PayloadOffset(), segment.PayloadLength()
+		var err error
+		switch segment.Marker {
+		case 0xE0: // APP0 - jfif marker
+			err = ParseAPP0(r, payloadStart, payloadLength, imgData)
+		case 0xE1: // APP1
+			if isExtendedXMP(r, payloadStart, payloadLength) {
+				err = extXMP.add(r, payloadStart, payloadLength)
+			} else {
+				err = ParseAPP1(r, payloadStart, payloadLength, imgData)
+			}
+		case 0xE2: // APP2
+			switch {
+			case isICC(r, payloadStart, payloadLength):
+				err = iccProfile.add(r, payloadStart, payloadLength)
+			case isMPF(r, payloadStart, payloadLength):
+				err = ParseMPF(r, start, end, payloadStart, payloadLength, imgData)
+			}
+		case 0xED: // APP13
+			err = ParseAPP13(r, payloadStart, payloadLength, imgData)
+		}
+		if err != nil {
+			// a damaged segment loses only its own metadata
+			imgData.AddWarning(fmt.Errorf("%s at %d: %w", segment.Name(), segment.Offset, err))
+		}
+	}
+	if headerErr != nil {
+		// a broken marker ends the walk, the segments before it are kept
+		imgData.AddWarning(headerErr)
 	}
+	return nil
+}
 
-	// read entire APP1 and advance main file reader
-	payloadSize := binary.BigEndian.Uint16(buff)
-	app1Data := make([]byte, payloadSize-2)
-	file.Read(app1Data)
-
-	app1Reader := bytes.NewReader(app1Data)
+// ParseAPP1 reads the APP1 payload that starts at offset in r and is length bytes long.
+// Offsets inside the Exif TIFF structure are kept absolute to r.
+func ParseAPP1(r io.ReaderAt, offset int64, length int64, imgData *metadata.ImageData) error {
+	// bound reads to the end of this segment
+	app1Reader := io.NewSectionReader(r, 0, offset+length)
 
-	// check identifier
-	identifier := make([]byte, 6)
-	_, err = app1Reader.Read(identifier)
+	// check identifier, the longest one is the 29 byte XMP namespace
+	identifier := make([]byte, min(length, int64(len(xmpIdentifier))))
+	_, err := app1Reader.ReadAt(identifier, offset)
 	if err != nil {
 		return err
 	}
-	payloadIdentifier := string(identifier)
 
-	var endian binary.ByteOrder
-	switch payloadIdentifier {
-	case "Exif\x00\x00":
-		endian = binary.BigEndian
-	case "http://ns.adobe.com/xap/1.0/\x00":
-		endian = binary.LittleEndian
+	switch {
+	case bytes.HasPrefix(identifier, []byte(exifIdentifier)):
+		tiffHeaderStart := offset + int64(len(exifIdentifier))
+		return tiff.Parse(imgData, app1Reader, tiffHeaderStart)
+	case bytes.Equal(identifier, []byte(xmpIdentifier)):
+		packetStart := offset + int64(len(xmpIdentifier))
+		packet := make([]byte, length-int64(len(xmpIdentifier)))
+		if _, err := app1Reader.ReadAt(packet, packetStart); err != nil {
+			return fmt.Errorf("failed to read XMP packet: %w", err)
+		}
+		xmpPacket, err := xmp.Parse(packet)
+		if err != nil {
+			return err
+		}
+		imgData.XMP = xmpPacket
+		return nil
 	default:
-		return errors.New("Payload identifier not found")
+		// other APP1 payloads carry no metadata we understand
+		return nil
 	}
+}
 
-	tiffHeaderStart, err := app1Reader.Seek(0, io.SeekCurrent)
-	if err != nil {
-		return fmt.Errorf("error setting tiff header start")
-	}
-	fmt.Printf("tiff header in app1 starts at: %d\n", tiffHeaderStart)
-	// read tiff header
-	tiffHeader := make([]byte, 8)
-	_, err = app1Reader.Read(tiffHeader)
-	if err != nil {
-		return fmt.Errorf("Error reading tiff header")
+// Strip copies the JPEG stream in r to w without its metadata segments
+// (APP1-APP13, APP15 and COM). The JFIF APP0 and Adobe APP14 segments are
+// kept, and so are the ICC profile chunks in APP2 when keepICC is set, since
+// they affect how the image is displayed. The copy ends at EOI, dropping
+// trailing data such as the secondary images of MPF files with their Exif.
+func Strip(r io.ReaderAt, size int64, w io.Writer, keepICC bool) error {
+	segments, err := ScanSegments(r, 0, size)
+	if len(segments) == 0 || err != nil && !slices.ContainsFunc(segments, isSOS) {
+		return err
 	}
-
-	// check endianness (byte order)
-	endianString := string(tiffHeader[:2])
-	switch endianString {
-	case "MM":
-		endian = binary.BigEndian
-	case "II":
-		endian = binary.LittleEndian
-	default:
-		return errors.New("Endianess not found")
+	// a stream cut short in its image data is copied up to its end
+	end := size
+	if last := segments[len(segments)-1]; last.Marker == markerEOI {
+		end = last.End()
+	}
+	for _, segment := range segments {
+		if segment.Marker == markerSOS || segment.Marker == markerEOI {
+			// copy the image stream as it is
+			_, err := io.Copy(w, io.NewSectionReader(r, segment.Offset, end-segment.Offset))
+			return err
+		}
+		if !keepSegment(r, segment, keepICC) {
+			continue
+		}
+		if _, err := io.Copy(w, io.NewSectionReader(r, segment.Offset, segment.End()-segment.Offset)); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// wrap app1Reader into BinaryReader
-	br := metadata.NewBinaryReader(app1Reader, endian)
+// keepSegment decides whether Strip copies the segment with the given marker.
+func keepSegment(r io.ReaderAt, segment Segment, keepICC bool) bool {
+	switch marker := segment.Marker; {
+	case marker == 0xFE: // COM
+		return false
+	case marker == 0xE2 && keepICC:
+		return isICC(r, segment.PayloadOffset(), segment.PayloadLength())
+	case marker == 0xEE: // APP14 Adobe, its transform flag selects the color conversion
+		return true
+	case marker >= 0xE1 && marker <= 0xEF: // APP1 - APP15
+		return false
+	}
+	return true
+}
 
-	// check version - TIFF magic number 42
-	versionNumber := endian.Uint16(tiffHeader[2:4])
-	if versionNumber != 42 {
-		return errors.New("invalid version number")
-	}
-	// update file main reader to offset of the first IFD (Image File Directory)
-	ifdOffset := endian.Uint32(tiffHeader[4:8])
-	if _, err = file.Seek(int64(ifdOffset), io.SeekStart); err != nil {
-		return fmt.Errorf("failed to seek to offset %d: %v", ifdOffset, err)
-	}
-	err = tiff.ParseIFD(imgData, br, tiffHeaderStart, metadata.IFDMAIN, endian)
-	if err != nil {
-		return err
-	}
-	return nil
+func isSOS(segment Segment) bool {
+	return segment.Marker == markerSOS
 }
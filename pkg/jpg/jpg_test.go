@@ -0,0 +1,85 @@
+package jpg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+)
+
+var (
+	jfifSegment  = fixture.Segment(0xE0, []byte("JFIF\x00\x01\x02\x01\x00\x48\x00\x48\x00\x00"))
+	iccSegment   = fixture.Segment(0xE2, []byte("ICC_PROFILE\x00\x01\x01profile"))
+	adobeSegment = fixture.Segment(0xEE, []byte("Adobe\x00\x64\x00\x00\x00\x00\x01"))
+	xmpSegment   = fixture.Segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>"))
+	comSegment   = fixture.Segment(0xFE, []byte("made by hand"))
+	exifSegment  = fixture.Exif(fixture.TIFF(binary.BigEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon")}}))
+)
+
+func TestStrip(t *testing.T) {
+	all := fixture.JPEG(8, 8, jfifSegment, exifSegment, iccSegment, xmpSegment, adobeSegment, comSegment)
+	stripped := fixture.JPEG(8, 8, jfifSegment, adobeSegment)
+	tests := []struct {
+		name    string
+		data    []byte
+		keepICC bool
+		want    []byte
+	}{
+		{
+			name:    "keep ICC",
+			data:    all,
+			keepICC: true,
+			want:    fixture.JPEG(8, 8, jfifSegment, iccSegment, adobeSegment),
+		},
+		{
+			name: "drop ICC",
+			data: all,
+			want: stripped,
+		},
+		{
+			name: "data after EOI",
+			data: append(fixture.JPEG(8, 8, exifSegment), fixture.JPEG(4, 4, exifSegment)...),
+			want: fixture.JPEG(8, 8),
+		},
+		{
+			name: "no metadata",
+			data: fixture.JPEG(8, 8),
+			want: fixture.JPEG(8, 8),
+		},
+		{
+			name: "image data cut short",
+			data: all[:len(all)-3],
+			want: stripped[:len(stripped)-3],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Strip(bytes.NewReader(tt.data), int64(len(tt.data)), &out, tt.keepICC); err != nil {
+				t.Fatalf("Strip() error = %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), tt.want) {
+				t.Errorf("Strip() =\n% x\nwant\n% x", out.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestStripBroken(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not a JPEG", []byte("\x89PNG\r\n\x1a\n")},
+		{"segment past the end", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x10, 0x00, 'E', 'x'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Strip(bytes.NewReader(tt.data), int64(len(tt.data)), &bytes.Buffer{}, true); err == nil {
+				t.Error("Strip() succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package jpg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/tiff"
+)
+
+// The Multi-Picture Format (CIPA DC-007) APP2 segment starts with "MPF\0"
+// followed by a TIFF header and the MP Index IFD. The MP Entry tag of that IFD
+// lists every image of the file with an offset relative to the TIFF header.
+const (
+	mpfIdentifier = "MPF\x00"
+	mpEntrySize   = 16
+)
+
+// MP Index IFD tags
+const (
+	mpfVersionTag = 0xB000
+	mpEntryTag    = 0xB002
+)
+
+func isMPF(r io.ReaderAt, offset int64, length int64) bool {
+	if length < int64(len(mpfIdentifier))+8 {
+		return false
+	}
+	identifier := make([]byte, len(mpfIdentifier))
+	if _, err := r.ReadAt(identifier, offset); err != nil {
+		return false
+	}
+	return string(identifier) == mpfIdentifier
+}
+
+// ParseMPF reads the MPF APP2 payload that starts at offset in r and is length
+// bytes long. soi is the offset of the SOI marker of the JPEG stream, where the
+// first image starts, and end the offset after the last byte of the file. MP
+// Entries of secondary images that do not hold a JPEG stream within the file
+// are dropped and recorded in imgData.Warnings.
+func ParseMPF(r io.ReaderAt, soi int64, end int64, offset int64, length int64, imgData *metadata.ImageData) error {
+	if imgData.MPF != nil {
+		// the secondary images carry their own MPF segment, keep the index of the first
+		return nil
+	}
+	headerStart := offset + int64(len(mpfIdentifier))
+	mpfReader := io.NewSectionReader(r, 0, offset+length)
+	if err := tiff.ParseAs(imgData, mpfReader, headerStart, metadata.IFDMPF); err != nil {
+		return err
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	byteOrder := make([]byte, 2)
+	if _, err := mpfReader.ReadAt(byteOrder, headerStart); err != nil {
+		return err
+	}
+	if string(byteOrder) == "II" {
+		order = binary.LittleEndian
+	}
+
+	mpf := &metadata.MPF{Offset: headerStart}
+	for _, tag := range imgData.MetaData.MPFTags {
+		data, ok := tag.Data.([]byte)
+		if !ok {
+			continue
+		}
+		switch tag.ID {
+		case mpfVersionTag:
+			mpf.Version = string(data)
+		case mpEntryTag:
+			if rest := len(data) % mpEntrySize; rest != 0 {
+				imgData.AddWarning(fmt.Errorf("MP Entry of %d bytes is not a multiple of %d, ignored the last %d", len(data), mpEntrySize, rest))
+			}
+			for i := 0; i+mpEntrySize <= len(data); i += mpEntrySize {
+				image := mpEntry(data[i:i+mpEntrySize], order, soi, headerStart)
+				// the first image is the file itself
+				if i > 0 {
+					if err := checkMPImage(r, image, end); err != nil {
+						imgData.AddWarning(fmt.Errorf("MP Entry %d: %w, skipped", i/mpEntrySize+1, err))
+						continue
+					}
+				}
+				mpf.Images = append(mpf.Images, image)
+			}
+		}
+	}
+	imgData.MPF = mpf
+	return nil
+}
+
+// checkMPImage verifies that a secondary image lies within the file and, for
+// JPEG images, starts with an SOI marker.
+func checkMPImage(r io.ReaderAt, image metadata.MPFImage, end int64) error {
+	if image.Length == 0 || image.Offset >= end || image.Length > end-image.Offset {
+		return fmt.Errorf("%d bytes at %d lie outside the file", image.Length, image.Offset)
+	}
+	if image.Format != 0 {
+		return nil
+	}
+	marker := make([]byte, 2)
+	if _, err := r.ReadAt(marker, image.Offset); err != nil {
+		return err
+	}
+	if marker[0] != 0xFF || marker[1] != markerSOI {
+		return fmt.Errorf("no SOI marker at %d", image.Offset)
+	}
+	return nil
+}
+
+// mpEntry decodes one 16 byte MP Entry: the attributes, the image size, the
+// offset and two dependent image entry numbers.
+func mpEntry(entry []byte, order binary.ByteOrder, soi int64, headerStart int64) metadata.MPFImage {
+	attributes := order.Uint32(entry[0:4])
+	image := metadata.MPFImage{
+		Type:            attributes & 0x00FFFFFF,
+		Format:          uint8(attributes>>24) & 0x07,
+		Representative:  attributes&(1<<29) != 0,
+		DependentChild:  attributes&(1<<30) != 0,
+		DependentParent: attributes&(1<<31) != 0,
+		Length:          int64(order.Uint32(entry[4:8])),
+		Dependent:       [2]uint16{order.Uint16(entry[12:14]), order.Uint16(entry[14:16])},
+	}
+	// the first image is the file itself and is stored with offset 0
+	if offset := order.Uint32(entry[8:12]); offset == 0 {
+		image.Offset = soi
+	} else {
+		image.Offset = headerStart + int64(offset)
+	}
+	return image
+}
@@ -0,0 +1,158 @@
+package jpg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+// testEntry describes one MP Entry of mpfFile. A zero offset is the primary
+// image; otherwise offset is added to the end of the primary image, which is
+// where the secondary images are stored.
+type testEntry struct {
+	attributes uint32
+	length     uint32
+	offset     int64
+	absolute   bool // offset is relative to the MPF header as is
+}
+
+// mpfFile returns a JPEG whose APP2 MPF segment lists entries, followed by
+// trailer. The MP Entry value is cut or padded to entrySize bytes when it is
+// not 0.
+func mpfFile(order binary.ByteOrder, trailer []byte, entrySize int, entries ...testEntry) []byte {
+	// the TIFF header follows SOI, the APP2 marker, its length and "MPF\0"
+	const headerStart = 10
+	build := func(primary int64) []byte {
+		value := make([]byte, 16*len(entries))
+		for i, e := range entries {
+			b := value[16*i:]
+			order.PutUint32(b, e.attributes)
+			order.PutUint32(b[4:], e.length)
+			switch {
+			case e.absolute:
+				order.PutUint32(b[8:], uint32(e.offset))
+			case i > 0:
+				order.PutUint32(b[8:], uint32(primary-headerStart+e.offset))
+			}
+		}
+		if entrySize > 0 {
+			value = append(value, make([]byte, entrySize)...)[:entrySize]
+		}
+		index := fixture.TIFF(order, &fixture.IFD{Entries: []fixture.Entry{
+			fixture.Undefined(0xB000, []byte("0100")),
+			fixture.Long(0xB001, uint32(len(entries))),
+			fixture.Undefined(0xB002, value),
+		}})
+		return fixture.JPEG(640, 480, fixture.Segment(0xE2, fixture.Concat([]byte(mpfIdentifier), index)))
+	}
+	// the offsets do not change the size of the primary image
+	primary := build(int64(len(build(0))))
+	return fixture.Concat(primary, trailer)
+}
+
+func TestParseMPF(t *testing.T) {
+	secondary := fixture.JPEG(160, 120)
+	vga := uint32(1<<31) | metadata.MPTypeLargeThumbVGA
+	n := uint32(len(secondary))
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantImages   int
+		wantWarnings int
+	}{
+		{
+			name:       "primary and preview",
+			data:       mpfFile(binary.BigEndian, secondary, 0, testEntry{attributes: 1<<29 | metadata.MPTypeBaseline, length: 1}, testEntry{attributes: vga, length: n}),
+			wantImages: 2,
+		},
+		{
+			name:       "little-endian",
+			data:       mpfFile(binary.LittleEndian, secondary, 0, testEntry{attributes: metadata.MPTypeBaseline, length: 1}, testEntry{attributes: vga, length: n}),
+			wantImages: 2,
+		},
+		{
+			name:         "entry past the end",
+			data:         mpfFile(binary.BigEndian, secondary, 0, testEntry{length: 1}, testEntry{attributes: vga, length: n + 1}),
+			wantImages:   1,
+			wantWarnings: 1,
+		},
+		{
+			name:         "offset past the end",
+			data:         mpfFile(binary.BigEndian, secondary, 0, testEntry{length: 1}, testEntry{attributes: vga, length: 1, offset: 1 << 30, absolute: true}),
+			wantImages:   1,
+			wantWarnings: 1,
+		},
+		{
+			name:         "no SOI",
+			data:         mpfFile(binary.BigEndian, secondary, 0, testEntry{length: 1}, testEntry{attributes: vga, length: 4, offset: 2}),
+			wantImages:   1,
+			wantWarnings: 1,
+		},
+		{
+			name:         "empty entry",
+			data:         mpfFile(binary.BigEndian, secondary, 0, testEntry{length: 1}, testEntry{attributes: vga}),
+			wantImages:   1,
+			wantWarnings: 1,
+		},
+		{
+			// a non-JPEG image only has to lie within the file
+			name:       "non-JPEG entry",
+			data:       mpfFile(binary.BigEndian, secondary, 0, testEntry{length: 1}, testEntry{attributes: 1 << 24, length: 4, offset: 2}),
+			wantImages: 2,
+		},
+		{
+			name:         "partial entry",
+			data:         mpfFile(binary.BigEndian, secondary, 40, testEntry{length: 1}, testEntry{attributes: vga, length: n}),
+			wantImages:   2,
+			wantWarnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := decode(t, tt.data)
+			mpf := imgData.MPF
+			if mpf == nil {
+				t.Fatal("MPF is nil")
+			}
+			if mpf.Version != "0100" || mpf.Offset != 10 {
+				t.Errorf("Version, Offset = %q, %d, want 0100, 10", mpf.Version, mpf.Offset)
+			}
+			if len(mpf.Images) != tt.wantImages {
+				t.Fatalf("Images = %+v, want %d", mpf.Images, tt.wantImages)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+			// the primary image is the file itself
+			if primary := mpf.Images[0]; primary.Offset != 0 {
+				t.Errorf("primary Offset = %d, want 0", primary.Offset)
+			}
+			if tt.wantImages < 2 || mpf.Images[1].Format != 0 {
+				return
+			}
+			preview := mpf.Images[1]
+			primaryLength := int64(len(tt.data) - len(secondary))
+			if preview.Offset != primaryLength || preview.Type != metadata.MPTypeLargeThumbVGA || !preview.DependentParent {
+				t.Errorf("Images[1] = %+v, want a VGA thumbnail at %d", preview, primaryLength)
+			}
+			if got, err := preview.Read(bytes.NewReader(tt.data)); err != nil || !bytes.Equal(got, secondary) {
+				t.Errorf("Images[1].Read() = % x, %v, want % x", got, err, secondary)
+			}
+		})
+	}
+}
+
+func TestParseMPFFirstIndex(t *testing.T) {
+	// the secondary image carries an MPF segment of its own, only the index
+	// of the primary image counts
+	secondary := mpfFile(binary.BigEndian, nil, 0, testEntry{length: 1})
+	data := mpfFile(binary.BigEndian, secondary, 0, testEntry{length: 1}, testEntry{length: uint32(len(secondary))})
+	imgData := decode(t, data)
+	if len(imgData.MPF.Images) != 2 {
+		t.Errorf("Images = %+v, want the 2 entries of the first index", imgData.MPF.Images)
+	}
+}
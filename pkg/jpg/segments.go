@@ -0,0 +1,254 @@
+package jpg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// markers with a meaning of their own to the scanner
+const (
+	markerTEM = 0x01
+	markerSOI = 0xD8
+	markerEOI = 0xD9
+	markerSOS = 0xDA
+)
+
+// Segment is one marker of a JPEG stream and the segment it introduces.
+type Segment struct {
+	Marker byte  // second byte of the marker, e.g. 0xE1 for APP1
+	Offset int64 // absolute offset of the marker
+	Length int64 // value of the length field, which counts itself; 0 for standalone markers
+	// ScanLength is the size of the entropy-coded data following an SOS
+	// segment, RSTn markers included. ScanSegments sets it, 0 otherwise.
+	ScanLength int64
+}
+
+// Standalone reports whether the marker stands alone without a length field:
+// SOI, EOI, RSTn and TEM.
+func (s Segment) Standalone() bool {
+	return standalone(s.Marker)
+}
+
+func standalone(marker byte) bool {
+	return marker == markerSOI || marker == markerEOI || marker == markerTEM || marker >= 0xD0 && marker <= 0xD7
+}
+
+// PayloadOffset returns the absolute offset of the data after the length field.
+func (s Segment) PayloadOffset() int64 {
+	if s.Standalone() {
+		return s.Offset + 2
+	}
+	return s.Offset + 4
+}
+
+// PayloadLength returns the size of the data after the length field.
+func (s Segment) PayloadLength() int64 {
+	return max(s.Length-2, 0)
+}
+
+// End returns the offset of the byte after the segment and its entropy-coded data.
+func (s Segment) End() int64 {
+	return s.Offset + 2 + s.Length + s.ScanLength
+}
+
+// Name returns the mnemonic of the marker, e.g. "APP1", "DQT" or "SOF2".
+func (s Segment) Name() string {
+	m := s.Marker
+	switch {
+	case m == 0xC4:
+		return "DHT"
+	case m == 0xC8:
+		return "JPG"
+	case m == 0xCC:
+		return "DAC"
+	case m >= 0xC0 && m <= 0xCF:
+		return fmt.Sprintf("SOF%d", m-0xC0)
+	case m >= 0xD0 && m <= 0xD7:
+		return fmt.Sprintf("RST%d", m-0xD0)
+	case m >= 0xE0 && m <= 0xEF:
+		return fmt.Sprintf("APP%d", m-0xE0)
+	case m >= 0xF0 && m <= 0xFD:
+		return fmt.Sprintf("JPG%d", m-0xF0)
+	}
+	switch m {
+	case markerTEM:
+		return "TEM"
+	case markerSOI:
+		return "SOI"
+	case markerEOI:
+		return "EOI"
+	case markerSOS:
+		return "SOS"
+	case 0xDB:
+		return "DQT"
+	case 0xDC:
+		return "DNL"
+	case 0xDD:
+		return "DRI"
+	case 0xDE:
+		return "DHP"
+	case 0xDF:
+		return "EXP"
+	case 0xFE:
+		return "COM"
+	}
+	return fmt.Sprintf("0x%02X", m)
+}
+
+// ScanSegments lists every marker of the JPEG stream of length bytes at
+// offset in r, from SOI to EOI. The entropy-coded data after each SOS is
+// stepped over, so progressive images with several scans are listed in full.
+// The segments found so far are returned with the error when the stream is
+// cut short.
+func ScanSegments(r io.ReaderAt, offset int64, length int64) ([]Segment, error) {
+	s, soi, err := newScanner(r, offset, offset+length)
+	if err != nil {
+		return nil, err
+	}
+	segments := []Segment{soi}
+	for {
+		segment, err := s.next()
+		if err == io.EOF {
+			return segments, errors.New("missing EOI marker")
+		}
+		if err != nil {
+			return segments, err
+		}
+		if segment.Marker == markerSOS {
+			if err := s.skipScan(&segment); err != nil {
+				return append(segments, segment), err
+			}
+		}
+		segments = append(segments, segment)
+		if segment.Marker == markerEOI {
+			return segments, nil
+		}
+	}
+}
+
+// readHeader lists the markers from SOI up to and including the first SOS or
+// EOI, the part of the stream that holds the metadata. On a broken marker it
+// returns the segments read so far along with the error.
+func readHeader(r io.ReaderAt, start int64, end int64) ([]Segment, error) {
+	s, soi, err := newScanner(r, start, end)
+	if err != nil {
+		return nil, err
+	}
+	segments := []Segment{soi}
+	for {
+		segment, err := s.next()
+		if err == io.EOF {
+			return segments, nil
+		}
+		if err != nil {
+			return segments, err
+		}
+		segments = append(segments, segment)
+		if segment.Marker == markerSOS || segment.Marker == markerEOI {
+			return segments, nil
+		}
+	}
+}
+
+// scanner walks the markers of a JPEG stream.
+type scanner struct {
+	r   io.ReaderAt
+	pos int64
+	end int64
+}
+
+// newScanner checks for the SOI marker at start and returns it.
+func newScanner(r io.ReaderAt, start int64, end int64) (*scanner, Segment, error) {
+	marker := make([]byte, 2)
+	if _, err := r.ReadAt(marker, start); err != nil {
+		return nil, Segment{}, fmt.Errorf("failed to read SOI: %w", err)
+	}
+	if marker[0] != 0xFF || marker[1] != markerSOI {
+		return nil, Segment{}, errors.New("missing SOI marker")
+	}
+	return &scanner{r: r, pos: start + 2, end: end}, Segment{Marker: markerSOI, Offset: start}, nil
+}
+
+// next reads the marker at the current position and steps over its segment.
+// It returns io.EOF at the end of the stream.
+func (s *scanner) next() (Segment, error) {
+	if s.pos >= s.end {
+		return Segment{}, io.EOF
+	}
+	b := make([]byte, 2)
+	if _, err := s.r.ReadAt(b, s.pos); err != nil {
+		return Segment{}, fmt.Errorf("failed to read marker at %d: %w", s.pos, err)
+	}
+	if b[0] != 0xFF {
+		return Segment{}, fmt.Errorf("expected a marker at %d, found 0x%02X", s.pos, b[0])
+	}
+	// any number of 0xFF fill bytes may precede the marker
+	for b[1] == 0xFF {
+		s.pos++
+		if s.pos+2 > s.end {
+			return Segment{}, fmt.Errorf("fill bytes at %d run past the end of the stream", s.pos)
+		}
+		if _, err := s.r.ReadAt(b, s.pos); err != nil {
+			return Segment{}, fmt.Errorf("failed to read marker at %d: %w", s.pos, err)
+		}
+	}
+	segment := Segment{Marker: b[1], Offset: s.pos}
+	s.pos += 2
+	if segment.Standalone() {
+		return segment, nil
+	}
+
+	// segment length includes the 2 length bytes
+	if _, err := s.r.ReadAt(b, s.pos); err != nil {
+		return Segment{}, fmt.Errorf("failed to read %s length at %d: %w", segment.Name(), s.pos, err)
+	}
+	segment.Length = int64(binary.BigEndian.Uint16(b))
+	if segment.Length < 2 {
+		return Segment{}, fmt.Errorf("invalid %s length %d at %d", segment.Name(), segment.Length, s.pos)
+	}
+	if s.pos+segment.Length > s.end {
+		return Segment{}, fmt.Errorf("%s at %d runs past the end of the stream", segment.Name(), segment.Offset)
+	}
+	s.pos += segment.Length
+	return segment, nil
+}
+
+// skipScan steps over the entropy-coded data that follows an SOS segment and
+// records its size. The data ends at the first marker that is neither a
+// stuffed 0xFF00 byte nor RSTn.
+func (s *scanner) skipScan(sos *Segment) error {
+	start := s.pos
+	br := bufio.NewReaderSize(io.NewSectionReader(s.r, start, s.end-start), 64<<10)
+	pos := start
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			sos.ScanLength = pos - start
+			s.pos = pos
+			return fmt.Errorf("entropy-coded data at %d has no end marker", start)
+		}
+		pos++
+		if c != 0xFF {
+			continue
+		}
+		marker, err := br.ReadByte()
+		for err == nil && marker == 0xFF {
+			pos++
+			marker, err = br.ReadByte()
+		}
+		if err != nil {
+			continue
+		}
+		pos++
+		if marker == 0x00 || marker >= 0xD0 && marker <= 0xD7 {
+			continue
+		}
+		// leave the marker, with one fill byte before it, to next
+		sos.ScanLength = pos - 2 - start
+		s.pos = pos - 2
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package jpg
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+// progressive is a stream with a fill byte, TEM, two scans, an RST marker in
+// the first scan and a fill byte before EOI.
+var progressive = fixture.Concat(
+	[]byte{0xFF, 0xD8},
+	[]byte{0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00},       // APP0 at 2
+	[]byte{0xFF, 0xFF, 0xDB, 0x00, 0x03, 0x00},       // fill byte, DQT at 9
+	[]byte{0xFF, 0x01},                               // TEM at 14
+	[]byte{0xFF, 0xC2, 0x00, 0x03, 0x00},             // SOF2 at 16
+	[]byte{0xFF, 0xDA, 0x00, 0x03, 0x00},             // SOS at 21
+	[]byte{0x12, 0xFF, 0x00, 0x34, 0xFF, 0xD0, 0x56}, // stuffed byte and RST0
+	[]byte{0xFF, 0xC4, 0x00, 0x02},                   // DHT at 33
+	[]byte{0xFF, 0xDA, 0x00, 0x03, 0x00},             // SOS at 37
+	[]byte{0x78, 0xFF, 0xFF, 0xD9},                   // fill byte, EOI at 44
+)
+
+func TestScanSegments(t *testing.T) {
+	want := []Segment{
+		{Marker: 0xD8, Offset: 0},
+		{Marker: 0xE0, Offset: 2, Length: 4},
+		{Marker: 0xDB, Offset: 9, Length: 3},
+		{Marker: 0x01, Offset: 14},
+		{Marker: 0xC2, Offset: 16, Length: 3},
+		{Marker: 0xDA, Offset: 21, Length: 3, ScanLength: 7},
+		{Marker: 0xC4, Offset: 33, Length: 2},
+		{Marker: 0xDA, Offset: 37, Length: 3, ScanLength: 2},
+		{Marker: 0xD9, Offset: 44},
+	}
+	// shift returns want moved by offset
+	shift := func(offset int64) []Segment {
+		var segments []Segment
+		for _, s := range want {
+			s.Offset += offset
+			segments = append(segments, s)
+		}
+		return segments
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		offset  int64
+		want    []Segment
+		wantErr bool
+	}{
+		{name: "progressive", data: progressive, want: want},
+		{name: "data after EOI", data: fixture.Concat(progressive, []byte("trailer")), want: want},
+		{name: "embedded stream", data: fixture.Concat([]byte("junk"), progressive), offset: 4, want: shift(4)},
+		{
+			name:    "scan without end marker",
+			data:    progressive[:44],
+			want:    append(want[:7:7], Segment{Marker: 0xDA, Offset: 37, Length: 3, ScanLength: 2}),
+			wantErr: true,
+		},
+		{name: "no EOI after the header", data: progressive[:8], want: want[:2], wantErr: true},
+		{name: "segment past the end", data: progressive[:12], want: want[:2], wantErr: true},
+		{name: "length below 2", data: []byte{0xFF, 0xD8, 0xFF, 0xFE, 0x00, 0x01}, want: want[:1], wantErr: true},
+		{name: "no marker", data: []byte{0xFF, 0xD8, 0x00, 0x00}, want: want[:1], wantErr: true},
+		{name: "fill bytes to the end", data: []byte{0xFF, 0xD8, 0xFF, 0xFF, 0xFF}, want: want[:1], wantErr: true},
+		{name: "no SOI", data: progressive[2:], wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScanSegments(bytes.NewReader(tt.data), tt.offset, int64(len(tt.data))-tt.offset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ScanSegments() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScanSegments() = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		segment    Segment
+		name       string
+		payload    int64
+		end        int64
+		standalone bool
+	}{
+		{Segment{Marker: 0xE1, Offset: 2, Length: 100}, "APP1", 98, 104, false},
+		{Segment{Marker: 0xC0, Offset: 10, Length: 17}, "SOF0", 15, 29, false},
+		{Segment{Marker: 0xDA, Offset: 10, Length: 12, ScanLength: 50}, "SOS", 10, 74, false},
+		{Segment{Marker: 0xD3, Offset: 10}, "RST3", 0, 12, true},
+		{Segment{Marker: 0xFE, Offset: 10, Length: 2}, "COM", 0, 14, false},
+		{Segment{Marker: 0xC4}, "DHT", 0, 2, false},
+		{Segment{Marker: 0xF7}, "JPG7", 0, 2, false},
+		{Segment{Marker: 0x02}, "0x02", 0, 2, false},
+	}
+	for _, tt := range tests {
+		s := tt.segment
+		if s.Name() != tt.name || s.PayloadLength() != tt.payload || s.End() != tt.end || s.Standalone() != tt.standalone {
+			t.Errorf("%+v: Name, PayloadLength, End, Standalone = %q, %d, %d, %v, want %q, %d, %d, %v",
+				s, s.Name(), s.PayloadLength(), s.End(), s.Standalone(), tt.name, tt.payload, tt.end, tt.standalone)
+		}
+	}
+}
+
+func TestWalkSegments(t *testing.T) {
+	brokenExif := fixture.Segment(0xE1, []byte("Exif\x00\x00XX*\x00"))
+	soi := []byte{0xFF, 0xD8}
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantErr      bool
+		wantExif     bool
+		wantXMP      bool
+		wantWarnings int
+	}{
+		// the XMP packet after the broken Exif is still read
+		{name: "broken Exif", data: fixture.JPEG(8, 8, brokenExif, xmpSegmentOf(xmpPacket(`xmp:Rating="5"`))), wantXMP: true, wantWarnings: 1},
+		{name: "unknown segments", data: fixture.JPEG(8, 8, fixture.Segment(0xEB, []byte("JP")), comSegment, fixture.Segment(0xDD, []byte{0, 4}))},
+		{name: "metadata after the scan is ignored", data: fixture.Concat(fixture.JPEG(8, 8)[:len(fixture.JPEG(8, 8))-2], brokenExif, []byte{0xFF, 0xD9})},
+		// the segments before a broken marker are kept
+		{name: "valid APP1 followed by garbage", data: fixture.Concat(soi, exifSegment, []byte("garbage")), wantExif: true, wantWarnings: 1},
+		{name: "valid APP1 followed by a truncated DQT", data: fixture.Concat(soi, exifSegment, []byte{0xFF, 0xDB, 0x00, 0x43, 0x00}), wantExif: true, wantWarnings: 1},
+		{name: "broken marker structure", data: fixture.Concat(soi, []byte{0xFF, 0xE1, 0x00, 0x40}), wantWarnings: 1},
+		{name: "missing SOI", data: fixture.Concat(exifSegment, soi), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := &metadata.ImageData{}
+			err := Decode(bytes.NewReader(tt.data), int64(len(tt.data)), imgData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, want error %v", err, tt.wantErr)
+			}
+			if (len(imgData.MetaData.MainTags) != 0) != tt.wantExif {
+				t.Errorf("IFD0 = %v, want tags %v", imgData.MetaData.MainTags, tt.wantExif)
+			}
+			if (imgData.XMP != nil) != tt.wantXMP {
+				t.Errorf("XMP = %v, want a packet %v", imgData.XMP, tt.wantXMP)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package jpg
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// Extended XMP is split across APP1 segments that each start with
+//
+//	"http://ns.adobe.com/xmp/extension/\x00"
+//	32 byte GUID, the upper case hex MD5 of the full extended packet
+//	4 byte full length of the extended packet
+//	4 byte offset of this chunk in the extended packet
+//	chunk data
+//
+// The main XMP packet names the GUID in xmpNote:HasExtendedXMP.
+const (
+	xmpExtensionIdentifier = "http://ns.adobe.com/xmp/extension/\x00"
+	xmpExtensionHeaderSize = len(xmpExtensionIdentifier) + 32 + 4 + 4
+	// maxExtendedXMP limits the memory all extended packets of a file may take
+	maxExtendedXMP = 64 << 20
+)
+
+func isExtendedXMP(r io.ReaderAt, offset int64, length int64) bool {
+	if length < int64(xmpExtensionHeaderSize) {
+		return false
+	}
+	identifier := make([]byte, len(xmpExtensionIdentifier))
+	if _, err := r.ReadAt(identifier, offset); err != nil {
+		return false
+	}
+	return string(identifier) == xmpExtensionIdentifier
+}
+
+// extendedXMP collects the chunks of every extended packet by GUID.
+type extendedXMP struct {
+	packets map[string]*xmpChunks
+	// budget is what the packets may still allocate. The full lengths come
+	// from the file, so they are bounded by the size of the JPEG stream,
+	// which holds every chunk, and by maxExtendedXMP.
+	budget int64
+}
+
+type xmpChunks struct {
+	data     []byte
+	received map[uint32]int // chunk offset -> chunk size
+}
+
+// newExtendedXMP returns an empty collection for a JPEG stream of size bytes.
+func newExtendedXMP(size int64) *extendedXMP {
+	return &extendedXMP{packets: map[string]*xmpChunks{}, budget: min(size, maxExtendedXMP)}
+}
+
+// add stores the chunk held in the APP1 payload at offset.
+func (e *extendedXMP) add(r io.ReaderAt, offset int64, length int64) error {
+	segment := make([]byte, length)
+	if _, err := r.ReadAt(segment, offset); err != nil {
+		return fmt.Errorf("failed to read extended XMP: %w", err)
+	}
+	header := segment[len(xmpExtensionIdentifier):xmpExtensionHeaderSize]
+	guid := string(header[:32])
+	fullLength := binary.BigEndian.Uint32(header[32:36])
+	chunkOffset := binary.BigEndian.Uint32(header[36:40])
+	chunk := segment[xmpExtensionHeaderSize:]
+
+	packet, ok := e.packets[guid]
+	if !ok {
+		if int64(fullLength) > e.budget {
+			return fmt.Errorf("extended XMP %s: full length %d is larger than the file allows", guid, fullLength)
+		}
+		e.budget -= int64(fullLength)
+		packet = &xmpChunks{data: make([]byte, fullLength), received: map[uint32]int{}}
+		e.packets[guid] = packet
+	}
+	if int(fullLength) != len(packet.data) {
+		return fmt.Errorf("extended XMP %s: full length changed from %d to %d", guid, len(packet.data), fullLength)
+	}
+	if uint64(chunkOffset)+uint64(len(chunk)) > uint64(fullLength) {
+		return fmt.Errorf("extended XMP %s: chunk at %d overruns length %d", guid, chunkOffset, fullLength)
+	}
+	copy(packet.data[chunkOffset:], chunk)
+	packet.received[chunkOffset] = len(chunk)
+	return nil
+}
+
+// merge reassembles the extended packet announced by the main packet,
+// verifies its MD5 and merges its properties into imgData.XMP. On error the
+// extension is dropped and imgData.XMP keeps the main packet alone.
+func (e *extendedXMP) merge(imgData *metadata.ImageData) error {
+	if len(e.packets) == 0 {
+		return nil
+	}
+	if imgData.XMP == nil {
+		return fmt.Errorf("extended XMP found without a main XMP packet")
+	}
+	note, ok := imgData.XMP.Get(xmp.NSXMPNote, "HasExtendedXMP")
+	if !ok {
+		return fmt.Errorf("extended XMP found but xmpNote:HasExtendedXMP is missing")
+	}
+	guid := strings.ToUpper(strings.TrimSpace(note.Value))
+
+	// chunks with any other GUID belong to stale packets and are ignored
+	packet, ok := e.packets[guid]
+	if !ok {
+		return fmt.Errorf("extended XMP %s announced but not found", guid)
+	}
+	received := 0
+	for _, size := range packet.received {
+		received += size
+	}
+	if received != len(packet.data) {
+		return fmt.Errorf("extended XMP %s: got %d of %d bytes", guid, received, len(packet.data))
+	}
+	sum := md5.Sum(packet.data)
+	if strings.ToUpper(hex.EncodeToString(sum[:])) != guid {
+		return fmt.Errorf("extended XMP %s: MD5 mismatch", guid)
+	}
+
+	extended, err := xmp.Parse(packet.data)
+	if err != nil {
+		return fmt.Errorf("extended XMP %s: %w", guid, err)
+	}
+	imgData.XMP.Merge(extended)
+	return nil
+}
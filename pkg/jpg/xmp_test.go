@@ -0,0 +1,130 @@
+package jpg
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// xmpPacket returns a packet with the given rdf:Description attributes.
+func xmpPacket(attrs string) string {
+	return `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:xmpNote="http://ns.adobe.com/xmp/note/"` +
+		` xmlns:photoshop="http://ns.adobe.com/photoshop/1.0/" ` + attrs + `/></rdf:RDF></x:xmpmeta>`
+}
+
+// xmpSegmentOf returns the APP1 segment holding packet.
+func xmpSegmentOf(packet string) []byte {
+	return fixture.Segment(0xE1, []byte(xmpIdentifier+packet))
+}
+
+func TestParseAPP1XMP(t *testing.T) {
+	tests := []struct {
+		name         string
+		segments     [][]byte
+		wantRating   string
+		wantWarnings int
+	}{
+		{name: "packet", segments: [][]byte{xmpSegmentOf(xmpPacket(`xmp:Rating="5"`))}, wantRating: "5"},
+		{name: "NUL padded packet", segments: [][]byte{xmpSegmentOf(xmpPacket(`xmp:Rating="3"`) + "\x00\x00\x00")}, wantRating: "3"},
+		{name: "next to Exif", segments: [][]byte{exifSegment, xmpSegmentOf(xmpPacket(`xmp:Rating="1"`))}, wantRating: "1"},
+		{name: "no rdf:RDF", segments: [][]byte{xmpSegmentOf("<x:xmpmeta/>")}, wantWarnings: 1},
+		{name: "identifier only", segments: [][]byte{xmpSegmentOf("")}, wantWarnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := decode(t, fixture.JPEG(8, 8, tt.segments...))
+			var rating string
+			if imgData.XMP != nil {
+				prop, _ := imgData.XMP.Get(xmp.NSXMP, "Rating")
+				rating = prop.Value
+			}
+			if rating != tt.wantRating {
+				t.Errorf("xmp:Rating = %q, want %q", rating, tt.wantRating)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+// extendedSegment returns an APP1 extended XMP segment holding chunk.
+func extendedSegment(guid string, fullLength, offset uint32, chunk string) []byte {
+	return fixture.Segment(0xE1, fixture.Concat([]byte(xmpExtensionIdentifier), []byte(guid),
+		fixture.BE32(fullLength), fixture.BE32(offset), []byte(chunk)))
+}
+
+func TestExtendedXMP(t *testing.T) {
+	extended := xmpPacket(`photoshop:City="Berlin" xmp:Rating="1"`)
+	sum := md5.Sum([]byte(extended))
+	guid := strings.ToUpper(hex.EncodeToString(sum[:]))
+	main := xmpSegmentOf(xmpPacket(`xmp:Rating="5" xmpNote:HasExtendedXMP="` + guid + `"`))
+	size := uint32(len(extended))
+	half := size / 2
+	first := extendedSegment(guid, size, 0, extended[:half])
+	second := extendedSegment(guid, size, half, extended[half:])
+
+	tests := []struct {
+		name         string
+		segments     [][]byte
+		wantCity     string
+		wantWarnings int
+	}{
+		{name: "two chunks", segments: [][]byte{main, first, second}, wantCity: "Berlin"},
+		{name: "chunks out of order", segments: [][]byte{main, second, first}, wantCity: "Berlin"},
+		{name: "chunk repeated", segments: [][]byte{main, first, first, second}, wantCity: "Berlin"},
+		{name: "missing chunk", segments: [][]byte{main, first}, wantWarnings: 1},
+		{
+			name:         "full length past the file size",
+			segments:     [][]byte{main, extendedSegment(guid, 0xFFFFFFFF, 0, extended)},
+			wantWarnings: 1,
+		},
+		{
+			name:         "chunk overruns the full length",
+			segments:     [][]byte{main, first, extendedSegment(guid, size, size-1, "xx")},
+			wantWarnings: 2,
+		},
+		{
+			name:         "full length changes",
+			segments:     [][]byte{main, first, extendedSegment(guid, size+1, half, extended[half:])},
+			wantWarnings: 2,
+		},
+		{
+			name: "MD5 mismatch",
+			segments: [][]byte{
+				xmpSegmentOf(xmpPacket(`xmp:Rating="5" xmpNote:HasExtendedXMP="00000000000000000000000000000000"`)),
+				extendedSegment("00000000000000000000000000000000", size, 0, extended),
+			},
+			wantWarnings: 1,
+		},
+		{name: "not announced", segments: [][]byte{xmpSegmentOf(xmpPacket(`xmp:Rating="5"`)), first, second}, wantWarnings: 1},
+		{name: "without main packet", segments: [][]byte{first, second}, wantWarnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := decode(t, fixture.JPEG(8, 8, tt.segments...))
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+			if imgData.XMP == nil {
+				if tt.wantCity != "" {
+					t.Fatal("XMP = nil")
+				}
+				return
+			}
+			// the main packet wins over the extension and survives a broken one
+			if rating, _ := imgData.XMP.Get(xmp.NSXMP, "Rating"); rating.Value != "5" {
+				t.Errorf("xmp:Rating = %q, want 5", rating.Value)
+			}
+			city, _ := imgData.XMP.Get(xmp.NSPhotoshop, "City")
+			if city.Value != tt.wantCity {
+				t.Errorf("photoshop:City = %q, want %q", city.Value, tt.wantCity)
+			}
+		})
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"strings"
 )
 
-
 func DecodeTagData(dataBytes []byte, dt DataType, count uint32, order binary.ByteOrder) (any, error) {
 	br := NewBinaryReader(bytes.NewReader(dataBytes), order)
 
@@ -16,54 +15,92 @@ func DecodeTagData(dataBytes []byte, dt DataType, count uint32, order binary.Byt
 	case TypeByte:
 		if count == 1 {
 			val, err := br.ReadUint8()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			return val, nil
 		}
-		vals, err := br.ReadUint8()
-		if err != nil { return nil, err }
+		vals := make([]uint8, count)
+		for i := range vals {
+			v, err := br.ReadUint8()
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+		}
 		return vals, nil
 
 	case TypeAscii:
 		vals, err := br.ReadBytes(int(count))
 		if err != nil {
-	        return nil, fmt.Errorf("failed to read %d bytes for ASCII tag: %w", count, err)
+			return nil, fmt.Errorf("failed to read %d bytes for ASCII tag: %w", count, err)
 		}
 		s := string(vals)
-		s = strings.TrimRight(s,"\x00 \t\r\n")
+		s = strings.TrimRight(s, "\x00 \t\r\n")
 		return s, nil
 
 	case TypeShort:
 		vals := make([]uint16, count)
 		for i := range vals {
 			v, err := br.ReadUint16()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			vals[i] = v
 		}
 		return vals, nil
 
-	case TypeLong:
+	case TypeLong, TypeIFD:
 		vals := make([]uint32, count)
 		for i := range vals {
 			v, err := br.ReadUint32()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			vals[i] = v
 		}
-	return vals, nil
+		return vals, nil
+
+	case TypeLong8, TypeIFD8:
+		vals := make([]uint64, count)
+		for i := range vals {
+			v, err := br.ReadUint64()
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+		}
+		return vals, nil
+
+	case TypeSLong8:
+		vals := make([]int64, count)
+		for i := range vals {
+			v, err := br.ReadInt64()
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+		}
+		return vals, nil
 
 	case TypeRational:
-		 vals := make([]Rational, count)
-	for i := range vals {
+		vals := make([]Rational, count)
+		for i := range vals {
 			v, err := br.ReadRational()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			vals[i] = v
 		}
-	return vals, nil
+		return vals, nil
 
 	case TypeSByte:
 		vals := make([]int8, count)
 		for i := range vals {
 			v, err := br.ReadInt8()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			vals[i] = v
 		}
 		return vals, nil
@@ -71,53 +108,65 @@ func DecodeTagData(dataBytes []byte, dt DataType, count uint32, order binary.Byt
 	case TypeUndefined:
 		// just raw bytes
 		vals, err := br.ReadBytes(int(count))
-		if err != nil { return nil, err }
+		if err != nil {
+			return nil, err
+		}
 		return vals, nil
 
 	case TypeSShort:
 		vals := make([]int16, count)
-		for i:= range vals {
+		for i := range vals {
 			v, err := br.ReadInt16()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			vals[i] = v
 		}
 		return vals, nil
 
 	case TypeSLong:
 		vals := make([]int32, count)
-	for i := range vals {
+		for i := range vals {
 			v, err := br.ReadInt32()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			vals[i] = v
 		}
-	return vals, nil
+		return vals, nil
 
 	case TypeSRational:
 		vals := make([]Srational, count)
 		for i := range vals {
 			v, err := br.ReadSRational()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			vals[i] = v
 		}
-	return vals, nil
+		return vals, nil
 
 	case TypeFloat:
 		vals := make([]float32, count)
 		for i := range vals {
 			v, err := br.ReadFloat32()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			vals[i] = v
 		}
-	return vals, nil
+		return vals, nil
 
 	case TypeDouble:
 		vals := make([]float64, count)
 		for i := range vals {
 			v, err := br.ReadFloat64()
-			if err != nil { return nil, err }
+			if err != nil {
+				return nil, err
+			}
 			vals[i] = v
 		}
-	return vals, nil
+		return vals, nil
 
 	default:
 		return nil, fmt.Errorf("Unsupported datat type: %d", dt)
@@ -125,12 +174,12 @@ func DecodeTagData(dataBytes []byte, dt DataType, count uint32, order binary.Byt
 }
 
 type BinaryReader struct {
-	r 			io.ReadSeeker
-	byteOrder 	binary.ByteOrder
+	r         io.ReadSeeker
+	byteOrder binary.ByteOrder
 }
 
-func NewBinaryReader(r io.ReadSeeker, order binary.ByteOrder) *BinaryReader{
-	return &BinaryReader{ r: r, byteOrder: order }
+func NewBinaryReader(r io.ReadSeeker, order binary.ByteOrder) *BinaryReader {
+	return &BinaryReader{r: r, byteOrder: order}
 }
 
 func (br *BinaryReader) ChangeByteOrder(order binary.ByteOrder) {
@@ -185,6 +234,12 @@ func (br *BinaryReader) ReadUint32() (uint32, error) {
 	return val, err
 }
 
+func (br *BinaryReader) ReadUint64() (uint64, error) {
+	var val uint64
+	err := binary.Read(br.r, br.byteOrder, &val)
+	return val, err
+}
+
 // Int
 func (br *BinaryReader) ReadInt8() (int8, error) {
 	var val int8
@@ -204,6 +259,12 @@ func (br *BinaryReader) ReadInt32() (int32, error) {
 	return val, err
 }
 
+func (br *BinaryReader) ReadInt64() (int64, error) {
+	var val int64
+	err := binary.Read(br.r, br.byteOrder, &val)
+	return val, err
+}
+
 // Float
 func (br *BinaryReader) ReadFloat32() (float32, error) {
 	var val float32
@@ -219,24 +280,31 @@ func (br *BinaryReader) ReadFloat64() (float64, error) {
 
 // Rational
 func (br *BinaryReader) ReadRational() (Rational, error) {
-	var numerator 	uint32
+	var numerator uint32
 	var denominator uint32
 
 	err := binary.Read(br.r, br.byteOrder, &numerator)
-	if err != nil { return Rational{}, err }
+	if err != nil {
+		return Rational{}, err
+	}
 	err = binary.Read(br.r, br.byteOrder, &denominator)
-	if err != nil { return Rational{}, err }
+	if err != nil {
+		return Rational{}, err
+	}
 	return Rational{Numerator: numerator, Denominator: denominator}, err
 }
 
 func (br *BinaryReader) ReadSRational() (Srational, error) {
-	var numerator 	int32
+	var numerator int32
 	var denominator int32
 
 	err := binary.Read(br.r, br.byteOrder, &numerator)
-	if err != nil { return Srational{}, err }
+	if err != nil {
+		return Srational{}, err
+	}
 	err = binary.Read(br.r, br.byteOrder, &denominator)
-	if err != nil { return Srational{}, err }
-	return  Srational{Numerator: numerator, Denominator: denominator}, err
+	if err != nil {
+		return Srational{}, err
+	}
+	return Srational{Numerator: numerator, Denominator: denominator}, err
 }
-
@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// signatures identify the formats that have no decoder. They are tried after
+// the registered formats.
+var signatures = []format{
+	{name: FormatGIF, magic: "GIF87a"},
+	{name: FormatGIF, magic: "GIF89a"},
+	{name: FormatJXL, magic: "\xff\x0a"},                       // bare codestream
+	{name: FormatJXL, magic: "\x00\x00\x00\x0cJXL \r\n\x87\n"}, // ISO-BMFF container
+	{name: FormatJP2, magic: "\x00\x00\x00\x0cjP  \r\n\x87\n"}, // JPEG 2000 signature box
+	{name: FormatJP2, magic: "\xff\x4f\xff\x51"},               // bare J2K codestream
+}
+
+// extensionFormats maps file extensions to the formats they may hold. The
+// TIFF based RAW formats are only told apart by their tags, so those
+// extensions also accept a plain TIFF.
+var extensionFormats = map[string][]Format{
+	".jpg":  {FormatJPEG},
+	".jpeg": {FormatJPEG},
+	".tif":  {FormatTIFF, FormatBigTIFF},
+	".tiff": {FormatTIFF, FormatBigTIFF},
+	".png":  {FormatPNG},
+	".webp": {FormatWebP},
+	".heic": {FormatHEIF},
+	".heif": {FormatHEIF, FormatAVIF},
+	".avif": {FormatAVIF},
+	".dng":  {FormatDNG, FormatTIFF},
+	".cr2":  {FormatCR2, FormatTIFF},
+	".nef":  {FormatNEF, FormatTIFF},
+	".arw":  {FormatARW, FormatTIFF},
+	".orf":  {FormatORF},
+	".rw2":  {FormatRW2},
+	".pef":  {FormatPEF, FormatTIFF},
+	".cr3":  {FormatCR3},
+	".raf":  {FormatRAF},
+	".gif":  {FormatGIF},
+	".jxl":  {FormatJXL},
+	".jp2":  {FormatJP2},
+	".j2k":  {FormatJP2},
+}
+
+// Detect identifies the container held in r from its leading bytes. TIFF
+// based RAW files are reported as FormatTIFF, Decode tells them apart.
+func Detect(r io.ReaderAt, size int64) (Format, error) {
+	f, err := sniff(r, size)
+	if err != nil {
+		return "", err
+	}
+	return f.name, nil
+}
+
+// DetectFile opens the file at path and identifies its container.
+func DetectFile(path string) (Format, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return Detect(file, info.Size())
+}
+
+// CanDecode reports whether a decoder is registered for name.
+func CanDecode(name Format) bool {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	return slices.ContainsFunc(formats, func(f format) bool { return f.name == name })
+}
+
+// CheckExtension returns an error when the extension of path names another
+// format than the content, e.g. a PNG saved as photo.jpg. Unknown extensions
+// and files without one always pass.
+func CheckExtension(path string, format Format) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	allowed, ok := extensionFormats[ext]
+	if !ok || slices.Contains(allowed, format) {
+		return nil
+	}
+	return fmt.Errorf("extension %s does not match the %s content", ext, format)
+}
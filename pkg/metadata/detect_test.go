@@ -0,0 +1,115 @@
+package metadata_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+
+	_ "github.com/justikun/metadata-viewer/pkg/cr3"
+	_ "github.com/justikun/metadata-viewer/pkg/heif"
+	_ "github.com/justikun/metadata-viewer/pkg/jpg"
+	_ "github.com/justikun/metadata-viewer/pkg/png"
+	_ "github.com/justikun/metadata-viewer/pkg/raf"
+	_ "github.com/justikun/metadata-viewer/pkg/tiff"
+	_ "github.com/justikun/metadata-viewer/pkg/webp"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    metadata.Format
+		wantErr error
+	}{
+		{name: "JPEG", data: "\xff\xd8\xff\xe0", want: metadata.FormatJPEG},
+		{name: "TIFF little-endian", data: "II*\x00\x08\x00\x00\x00", want: metadata.FormatTIFF},
+		{name: "TIFF big-endian", data: "MM\x00*\x00\x00\x00\x08", want: metadata.FormatTIFF},
+		{name: "BigTIFF", data: "II+\x00\x08\x00\x00\x00", want: metadata.FormatBigTIFF},
+		{name: "ORF", data: "IIRO\x08\x00\x00\x00", want: metadata.FormatORF},
+		{name: "RW2", data: "IIU\x00\x08\x00\x00\x00", want: metadata.FormatRW2},
+		{name: "PNG", data: "\x89PNG\r\n\x1a\n", want: metadata.FormatPNG},
+		{name: "WebP", data: "RIFF\x24\x00\x00\x00WEBPVP8 ", want: metadata.FormatWebP},
+		{name: "HEIC", data: "\x00\x00\x00\x18ftypheic", want: metadata.FormatHEIF},
+		{name: "AVIF", data: "\x00\x00\x00\x1cftypavif", want: metadata.FormatAVIF},
+		{name: "CR3", data: "\x00\x00\x00\x18ftypcrx ", want: metadata.FormatCR3},
+		{name: "RAF", data: "FUJIFILMCCD-RAW 0201", want: metadata.FormatRAF},
+		{name: "GIF", data: "GIF89a\x01\x00", want: metadata.FormatGIF},
+		{name: "JPEG XL codestream", data: "\xff\x0a\xfa", want: metadata.FormatJXL},
+		{name: "JPEG XL container", data: "\x00\x00\x00\x0cJXL \r\n\x87\n", want: metadata.FormatJXL},
+		{name: "JPEG 2000", data: "\x00\x00\x00\x0cjP  \r\n\x87\n", want: metadata.FormatJP2},
+		{name: "J2K codestream", data: "\xff\x4f\xff\x51", want: metadata.FormatJP2},
+		{name: "MP4", data: "\x00\x00\x00\x18ftypisom", wantErr: metadata.ErrFormat},
+		{name: "RIFF WAVE", data: "RIFF\x24\x00\x00\x00WAVE", wantErr: metadata.ErrFormat},
+		{name: "text", data: "hello, world", wantErr: metadata.ErrFormat},
+		{name: "empty", wantErr: metadata.ErrFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := metadata.Detect(bytes.NewReader([]byte(tt.data)), int64(len(tt.data)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Detect() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	// the content decides, not the extension
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := metadata.DetectFile(path); err != nil || got != metadata.FormatPNG {
+		t.Errorf("DetectFile() = %q, %v, want %q", got, err, metadata.FormatPNG)
+	}
+	if _, err := metadata.DetectFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("DetectFile() of a missing file succeeded")
+	}
+}
+
+func TestCheckExtension(t *testing.T) {
+	tests := []struct {
+		path    string
+		format  metadata.Format
+		wantErr bool
+	}{
+		{"photo.jpg", metadata.FormatJPEG, false},
+		{"PHOTO.JPEG", metadata.FormatJPEG, false},
+		{"scan.tif", metadata.FormatBigTIFF, false},
+		{"IMG_0001.CR2", metadata.FormatCR2, false},
+		{"IMG_0001.CR2", metadata.FormatTIFF, false}, // a CR2 whose header is damaged
+		{"image.heif", metadata.FormatAVIF, false},
+		{"photo", metadata.FormatPNG, false},      // no extension
+		{"photo.bin", metadata.FormatJPEG, false}, // unknown extension
+		{"photo.jpg", metadata.FormatPNG, true},
+		{"IMG_0001.CR3", metadata.FormatCR2, true},
+		{"photo.heic", metadata.FormatAVIF, true},
+	}
+	for _, tt := range tests {
+		if err := metadata.CheckExtension(tt.path, tt.format); (err != nil) != tt.wantErr {
+			t.Errorf("CheckExtension(%q, %q) error = %v, want error %v", tt.path, tt.format, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCanDecode(t *testing.T) {
+	for _, format := range []metadata.Format{metadata.FormatJPEG, metadata.FormatTIFF, metadata.FormatBigTIFF, metadata.FormatPNG,
+		metadata.FormatWebP, metadata.FormatHEIF, metadata.FormatAVIF, metadata.FormatORF, metadata.FormatRW2, metadata.FormatCR3, metadata.FormatRAF} {
+		if !metadata.CanDecode(format) {
+			t.Errorf("CanDecode(%q) = false, want true", format)
+		}
+	}
+	// detected, but there is no decoder
+	for _, format := range []metadata.Format{metadata.FormatGIF, metadata.FormatJXL, metadata.FormatJP2} {
+		if metadata.CanDecode(format) {
+			t.Errorf("CanDecode(%q) = true, want false", format)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// ErrFormat is returned when the container of an image is not recognised
+// by any registered decoder.
+var ErrFormat = errors.New("metadata: unknown format")
+
+// ErrNoDecoder is returned when the container is recognised, e.g. GIF, but no
+// decoder reads its metadata.
+var ErrNoDecoder = errors.New("metadata: no decoder for format")
+
+type Format string
+
+const (
+	FormatJPEG    Format = "jpeg"
+	FormatTIFF    Format = "tiff"
+	FormatBigTIFF Format = "bigtiff"
+	FormatPNG     Format = "png"
+	FormatWebP    Format = "webp"
+	FormatHEIF    Format = "heif"
+	FormatAVIF    Format = "avif"
+
+	// TIFF based camera RAW formats
+	FormatDNG Format = "dng"
+	FormatCR2 Format = "cr2"
+	FormatNEF Format = "nef"
+	FormatARW Format = "arw"
+	FormatORF Format = "orf"
+	FormatRW2 Format = "rw2"
+	FormatPEF Format = "pef"
+
+	// camera RAW formats with their own container
+	FormatCR3 Format = "cr3"
+	FormatRAF Format = "raf"
+
+	// formats Detect recognises without a decoder
+	FormatGIF Format = "gif"
+	FormatJXL Format = "jxl"
+	FormatJP2 Format = "jp2"
+)
+
+// DecodeFunc fills imgData with the metadata found in the first size bytes of r.
+type DecodeFunc func(r io.ReaderAt, size int64, imgData *ImageData) error
+
+type format struct {
+	name   Format
+	magic  string
+	decode DecodeFunc
+}
+
+var (
+	formatsMu sync.Mutex
+	formats   []format
+)
+
+// RegisterFormat registers a container decoder for use by Decode.
+// Magic is the leading bytes that identify the container. A "?" matches any byte.
+// Decoder packages call this from init, the same way image.RegisterFormat works.
+func RegisterFormat(name Format, magic string, decode DecodeFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats = append(formats, format{name: name, magic: magic, decode: decode})
+}
+
+func match(magic string, b []byte) bool {
+	if len(magic) > len(b) {
+		return false
+	}
+	for i, c := range []byte(magic) {
+		if c != b[i] && c != '?' {
+			return false
+		}
+	}
+	return true
+}
+
+// sniff returns the registered format matching the leading bytes of r,
+// falling back to the signatures of the formats without a decoder.
+func sniff(r io.ReaderAt, size int64) (format, error) {
+	formatsMu.Lock()
+	fs := slices.Concat(formats, signatures)
+	formatsMu.Unlock()
+
+	longest := 0
+	for _, f := range fs {
+		longest = max(longest, len(f.magic))
+	}
+	header := make([]byte, min(int64(longest), size))
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return format{}, fmt.Errorf("failed to read header: %w", err)
+	}
+	header = header[:n]
+
+	for _, f := range fs {
+		if match(f.magic, header) {
+			return f, nil
+		}
+	}
+	return format{}, ErrFormat
+}
+
+// Decode sniffs the container held in r and walks it with the matching
+// registered decoder. The packages providing decoders (pkg/jpg, ...) must be
+// imported, possibly with a blank import, for their format to be recognised.
+func Decode(r io.ReaderAt, size int64) (*ImageData, error) {
+	f, err := sniff(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if f.decode == nil {
+		return nil, fmt.Errorf("%s: %w", f.name, ErrNoDecoder)
+	}
+	imgData := &ImageData{Format: f.name}
+	if err := f.decode(r, size, imgData); err != nil {
+		return imgData, fmt.Errorf("%s: %w", f.name, err)
+	}
+	return imgData, nil
+}
+
+// DecodeFile opens the file at path and decodes its metadata.
+func DecodeFile(path string) (*ImageData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	imgData, err := Decode(file, info.Size())
+	if imgData != nil {
+		imgData.ImagePath = path
+	}
+	return imgData, err
+}
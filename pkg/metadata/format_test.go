@@ -0,0 +1,99 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testFormat is registered once for the tests below, its magic is unlikely to
+// clash with a real container.
+const testFormat Format = "test"
+
+var errTestDecode = errors.New("broken test container")
+
+func init() {
+	RegisterFormat(testFormat, "TST?", func(r io.ReaderAt, size int64, imgData *ImageData) error {
+		b := make([]byte, size)
+		if _, err := r.ReadAt(b, 0); err != nil {
+			return err
+		}
+		if bytes.HasSuffix(b, []byte("bad")) {
+			return errTestDecode
+		}
+		imgData.MetaData.MainTags = []IFDtag{{ID: 0x010F, Name: "Make", Data: string(b[4:])}}
+		return nil
+	})
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		magic string
+		data  string
+		want  bool
+	}{
+		{"II*\x00", "II*\x00\x08", true},
+		{"II*\x00", "MM\x00*", false},
+		{"RIFF????WEBP", "RIFF\x10\x00\x00\x00WEBP", true},
+		{"RIFF????WEBP", "RIFF\x10\x00\x00\x00WAVE", false},
+		{"\xff\xd8", "\xff", false}, // shorter than the magic
+	}
+	for _, tt := range tests {
+		if got := match(tt.magic, []byte(tt.data)); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.magic, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string // Make
+		wantErr error
+	}{
+		{name: "registered", data: "TST1Canon", want: "Canon"},
+		{name: "decoder error", data: "TST1bad", wantErr: errTestDecode},
+		{name: "unknown", data: "\x00\x01\x02\x03", wantErr: ErrFormat},
+		{name: "empty", data: "", wantErr: ErrFormat},
+		{name: "no decoder", data: "GIF89a\x01\x00", wantErr: ErrNoDecoder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData, err := Decode(bytes.NewReader([]byte(tt.data)), int64(len(tt.data)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if imgData.Format != testFormat {
+				t.Errorf("Format = %q, want %q", imgData.Format, testFormat)
+			}
+			if got := imgData.MetaData.MainTags[0].Data; got != tt.want {
+				t.Errorf("Make = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.tst")
+	if err := os.WriteFile(path, []byte("TST1Nikon"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	imgData, err := DecodeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imgData.ImagePath != path {
+		t.Errorf("ImagePath = %q, want %q", imgData.ImagePath, path)
+	}
+
+	if _, err := DecodeFile(filepath.Join(t.TempDir(), "missing.jpg")); err == nil {
+		t.Error("DecodeFile() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,21 @@
+package metadata
+
+// HEIF describes the items of a HEIF or AVIF file.
+type HEIF struct {
+	MajorBrand       string     `json:"majorBrand"`
+	CompatibleBrands []string   `json:"compatibleBrands"`
+	PrimaryItem      uint32     `json:"primaryItem"`
+	Width            uint32     `json:"width"` // ispe of the primary item
+	Height           uint32     `json:"height"`
+	Items            []HEIFItem `json:"items"`
+}
+
+// HEIFItem is one item of the meta box.
+type HEIFItem struct {
+	ID          uint32 `json:"id"`
+	Type        string `json:"type"` // e.g. "hvc1", "av01", "grid", "Exif" or "mime"
+	Name        string `json:"name,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Width       uint32 `json:"width,omitempty"` // ispe, 0 when the item has none
+	Height      uint32 `json:"height,omitempty"`
+}
@@ -0,0 +1,50 @@
+package metadata
+
+import "time"
+
+// ICCProfile is the header and the descriptive tags of an ICC color profile.
+type ICCProfile struct {
+	Size            uint32      `json:"size"`
+	CMM             string      `json:"cmm,omitempty"`     // preferred color management module
+	Version         string      `json:"version"`           // e.g. "4.3.0"
+	Class           string      `json:"class"`             // e.g. "mntr" for display devices
+	ColorSpace      string      `json:"colorSpace"`        // data color space, e.g. "RGB" or "CMYK"
+	PCS             string      `json:"pcs"`               // profile connection space, "XYZ" or "Lab"
+	Created         *time.Time  `json:"created,omitempty"` // UTC, nil when the header leaves it zero
+	Platform        string      `json:"platform,omitempty"`
+	RenderingIntent uint32      `json:"renderingIntent"` // 0 perceptual to 3 absolute colorimetric
+	Creator         string      `json:"creator,omitempty"`
+	Description     string      `json:"description,omitempty"` // desc tag
+	Copyright       string      `json:"copyright,omitempty"`   // cprt tag
+	WhitePoint      *[3]float64 `json:"whitePoint,omitempty"`  // wtpt tag, CIE XYZ
+	Tags            []ICCTag    `json:"tags"`
+	Data            []byte      `json:"-"` // the whole profile
+}
+
+// ICCTag is one entry of the tag table of an ICC profile.
+type ICCTag struct {
+	Signature string `json:"signature"` // e.g. "desc", "rXYZ" or "rTRC"
+	Offset    uint32 `json:"offset"`    // from the start of the profile
+	Size      uint32 `json:"size"`
+}
+
+// ClassName describes the profile class.
+func (p ICCProfile) ClassName() string {
+	switch p.Class {
+	case "scnr":
+		return "Input Device"
+	case "mntr":
+		return "Display Device"
+	case "prtr":
+		return "Output Device"
+	case "link":
+		return "Device Link"
+	case "spac":
+		return "Color Space"
+	case "abst":
+		return "Abstract"
+	case "nmcl":
+		return "Named Color"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,27 @@
+package metadata
+
+// IPTC holds the IPTC-IIM datasets of an image in the order they were found.
+type IPTC struct {
+	CharacterSet string // from dataset 1:90, "UTF-8" or "" for the ISO 8859-1 default
+	Datasets     []IPTCDataset
+}
+
+// IPTCDataset is one IIM dataset. Repeatable datasets such as 2:25 Keywords
+// collect every occurrence in Values.
+type IPTCDataset struct {
+	Record     uint8
+	ID         uint8
+	Name       string
+	Repeatable bool
+	Values     []string
+}
+
+// Get returns the dataset record:id.
+func (p IPTC) Get(record, id uint8) (IPTCDataset, bool) {
+	for _, dataset := range p.Datasets {
+		if dataset.Record == record && dataset.ID == id {
+			return dataset, true
+		}
+	}
+	return IPTCDataset{}, false
+}
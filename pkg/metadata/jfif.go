@@ -0,0 +1,31 @@
+package metadata
+
+// JFIF is the JPEG File Interchange Format header stored in APP0.
+type JFIF struct {
+	VersionMajor uint8  `json:"versionMajor"`
+	VersionMinor uint8  `json:"versionMinor"`
+	DensityUnits uint8  `json:"densityUnits"` // 0 no units (aspect ratio), 1 dots per inch, 2 dots per cm
+	XDensity     uint16 `json:"xDensity"`
+	YDensity     uint16 `json:"yDensity"`
+	ThumbWidth   uint8  `json:"thumbWidth"`
+	ThumbHeight  uint8  `json:"thumbHeight"`
+	// Thumbnail holds ThumbWidth * ThumbHeight 24-bit RGB pixels.
+	Thumbnail []byte `json:"-"`
+}
+
+// JFXX thumbnail formats
+const (
+	JFXXJPEG    uint8 = 0x10 // JPEG stream
+	JFXXPalette uint8 = 0x11 // 1 byte per pixel with a 256 entry RGB palette
+	JFXXRGB     uint8 = 0x13 // 3 bytes per pixel RGB
+)
+
+// JFXX is the JFIF extension APP0 segment that carries a thumbnail.
+type JFXX struct {
+	Format  uint8  `json:"format"` // JFXXJPEG, JFXXPalette, JFXXRGB or an unknown extension code
+	Width   uint8  `json:"width"`  // 0 for JPEG thumbnails, read the SOF of Data instead
+	Height  uint8  `json:"height"`
+	Palette []byte `json:"-"`      // 768 bytes for JFXXPalette
+	Data    []byte `json:"-"`      // JPEG stream, palette indexes or RGB pixels
+	Offset  int64  `json:"offset"` // absolute offset of Data in the file
+}
@@ -0,0 +1,299 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// JSON schema
+//
+// Grouped (default):
+//
+//	{
+//	  "path": "photo.jpg",
+//	  "format": "jpeg",
+//	  "ifds": {
+//	    "IFD0": [{"id": "0x010F", "name": "Make", "type": "ASCII", "count": 6, "value": "Canon"}, ...],
+//	    "IFD1": [...],
+//	    "SubIFD0": [...],
+//	    "Exif": [{"id": "0x829A", "name": "Exposure Time", "type": "RATIONAL", "count": 1,
+//	              "value": {"num": 1, "den": 250, "float": 0.004}}, ...],
+//	    "GPS": [...],
+//	    "Interop": [...],
+//	    "MakerNote": [...]
+//	  },
+//	  "jfif": {"versionMajor": 1, "versionMinor": 2, "densityUnits": 1, "xDensity": 72, "yDensity": 72,
+//	           "thumbWidth": 0, "thumbHeight": 0},
+//	  "jfxx": {"format": 16, "width": 0, "height": 0, "offset": 30},
+//	  "xmp": {"dc:creator": ["Jane Doe"], "dc:title": {"x-default": "Sunset"}, "xmp:Rating": "5"},
+//	  "png": {"width": 640, "height": 480, "bitDepth": 8, "colorType": 6,
+//	          "text": [{"chunk": "tEXt", "keyword": "Software", "text": "GIMP"}],
+//	          "time": "2024-05-01T10:11:12Z", "phys": {"pixelsPerUnitX": 2835, "pixelsPerUnitY": 2835, "unit": 1},
+//	          "gamma": 0.45455, "srgbIntent": 0, "iccProfile": {"name": "ICC profile"}},
+//	  "webp": {"extended": true, "width": 400, "height": 300,
+//	           "features": {"icc": false, "alpha": true, "exif": true, "xmp": false, "animation": true},
+//	           "frames": 12, "loopCount": 0},
+//	  "heif": {"majorBrand": "heic", "compatibleBrands": ["mif1", "heic"], "primaryItem": 1,
+//	           "width": 4032, "height": 3024,
+//	           "items": [{"id": 1, "type": "hvc1", "width": 4032, "height": 3024}, {"id": 2, "type": "Exif"}]},
+//	  "raf": {"formatVersion": "0201", "cameraId": "FF129502", "model": "X-T5", "directoryVersion": "0100",
+//	          "jpegOffset": 148, "jpegLength": 1524512, ..., "rawWidth": 7872, "rawHeight": 5196,
+//	          "records": [{"tag": 256, "name": "RawImageFullSize"}, ...]},
+//	  "mpf": {"version": "0100", "offset": 1870, "images": [{"type": 196608, "format": 0, "representative": true,
+//	          "dependentParent": false, "dependentChild": false, "offset": 0, "length": 2451012, "dependent": [0, 0]}, ...]},
+//	  "icc": {"size": 3144, "cmm": "Lino", "version": "2.1.0", "class": "mntr", "colorSpace": "RGB", "pcs": "XYZ",
+//	          "created": "1998-02-09T06:49:00Z", "renderingIntent": 0, "description": "sRGB IEC61966-2.1",
+//	          "copyright": "Copyright (c) 1998 Hewlett-Packard Company", "whitePoint": [0.95045, 1, 1.08905],
+//	          "tags": [{"signature": "cprt", "offset": 336, "size": 51}, ...]},
+//	  "iptc": {"Coded Character Set": "UTF-8", "Keywords": ["sunset", "beach"], "By-line": ["Jane Doe"],
+//	           "Caption-Abstract": "Sunset over the beach", "City": "Berlin"},
+//	  "photoshop": {"resources": [{"id": 1005, "name": "Resolution Info", "size": 16}, ...],
+//	                "resolution": {"xResolution": 300, "xUnit": 1, "widthUnit": 1, ...},
+//	                "jpegQuality": {"quality": 10, "format": "Standard"},
+//	                "thumbnail": {"format": 1, "width": 160, "height": 120, "offset": 1024},
+//	                "copyrighted": true, "url": "https://example.com", "iptcDigest": "5e0b3f..."},
+//	  "previews": [{"source": "CR3 PRVW", "width": 1620, "height": 1080, "offset": 54272, "length": 412345}],
+//	  "warnings": ["APP2 at 1282: ICC profile chunk 3 of 2 is out of range"]
+//	}
+//
+// Flat:
+//
+//	{"path": "photo.jpg", "format": "jpeg", "tags": {"IFD0:Make": "Canon", "Exif:ExposureTime": {...}}}
+//
+// Sections such as "jfif" are written in both modes and left out when the image has none.
+// Tags are ordered by ID inside each group and object keys are sorted, so the
+// output for a given file is always byte for byte the same.
+// Numeric values are written as a scalar when the specification fixes the
+// count of the tag to 1 and the tag holds one value, otherwise as an array,
+// even one of a single element. So the JSON type of a tag never depends on the
+// file: unknown tags and tags of variable count are always arrays.
+// ASCII is a string, UNDEFINED is base64 and rationals carry both the raw
+// numerator/denominator and their float value (null when the denominator is 0).
+
+// JSONOptions controls EncodeJSON.
+type JSONOptions struct {
+	Flat   bool   // "Group:TagName" keys instead of tags grouped by IFD
+	Indent string // indent each level with this string, "" for compact output
+}
+
+type jsonImage struct {
+	Path      string               `json:"path"`
+	Format    Format               `json:"format"`
+	IFDs      map[string][]jsonTag `json:"ifds,omitempty"`
+	Tags      map[string]any       `json:"tags,omitempty"`
+	JFIF      *JFIF                `json:"jfif,omitempty"`
+	JFXX      *JFXX                `json:"jfxx,omitempty"`
+	XMP       *xmp.Packet          `json:"xmp,omitempty"`
+	PNG       *PNG                 `json:"png,omitempty"`
+	WebP      *WebP                `json:"webp,omitempty"`
+	HEIF      *HEIF                `json:"heif,omitempty"`
+	RAF       *RAF                 `json:"raf,omitempty"`
+	MPF       *MPF                 `json:"mpf,omitempty"`
+	ICC       *ICCProfile          `json:"icc,omitempty"`
+	IPTC      *IPTC                `json:"iptc,omitempty"`
+	Photoshop *Photoshop           `json:"photoshop,omitempty"`
+	Previews  []Preview            `json:"previews,omitempty"`
+	Warnings  []string             `json:"warnings,omitempty"`
+}
+
+// MarshalJSON writes the datasets as an object keyed by dataset name.
+// Repeatable datasets are arrays, the others a single string.
+func (p IPTC) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]any, len(p.Datasets))
+	for _, dataset := range p.Datasets {
+		if dataset.Repeatable {
+			doc[dataset.Name] = dataset.Values
+		} else if len(dataset.Values) > 0 {
+			doc[dataset.Name] = dataset.Values[0]
+		}
+	}
+	return json.Marshal(doc)
+}
+
+type jsonTag struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Count uint32 `json:"count"`
+	Value any    `json:"value"`
+}
+
+type jsonRational struct {
+	Num   int64    `json:"num"`
+	Den   int64    `json:"den"`
+	Float *float64 `json:"float"`
+}
+
+func newJSONRational(num, den int64) jsonRational {
+	r := jsonRational{Num: num, Den: den}
+	if den != 0 {
+		f := float64(num) / float64(den)
+		r.Float = &f
+	}
+	return r
+}
+
+// MarshalJSON encodes imgData with the grouped schema.
+func (d ImageData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.jsonImage(false))
+}
+
+// EncodeJSON writes imgData to w as a single JSON object.
+func EncodeJSON(w io.Writer, imgData *ImageData, opts JSONOptions) error {
+	return encodeJSON(w, imgData.jsonImage(opts.Flat), opts)
+}
+
+// EncodeJSONList writes the images to w as a JSON array, even when there is only one.
+func EncodeJSONList(w io.Writer, images []*ImageData, opts JSONOptions) error {
+	docs := make([]jsonImage, len(images))
+	for i, imgData := range images {
+		docs[i] = imgData.jsonImage(opts.Flat)
+	}
+	return encodeJSON(w, docs, opts)
+}
+
+func encodeJSON(w io.Writer, v any, opts JSONOptions) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", opts.Indent)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode json: %w", err)
+	}
+	return nil
+}
+
+func (d ImageData) jsonImage(flat bool) jsonImage {
+	doc := jsonImage{
+		Path: d.ImagePath, Format: d.Format, JFIF: d.JFIF, JFXX: d.JFXX, XMP: d.XMP, PNG: d.PNG, WebP: d.WebP,
+		HEIF: d.HEIF, RAF: d.RAF, MPF: d.MPF, ICC: d.ICC, IPTC: d.IPTC, Photoshop: d.Photoshop,
+		Previews: d.Previews,
+	}
+	for _, err := range d.Warnings {
+		doc.Warnings = append(doc.Warnings, err.Error())
+	}
+	if flat {
+		doc.Tags = map[string]any{}
+	} else {
+		doc.IFDs = map[string][]jsonTag{}
+	}
+
+	for _, group := range d.TagGroups() {
+		if len(group.Tags) == 0 {
+			continue
+		}
+		tags := slices.Clone(group.Tags)
+		slices.SortStableFunc(tags, func(a, b IFDtag) int { return int(a.ID) - int(b.ID) })
+
+		for _, tag := range tags {
+			value := jsonValue(tag, d.singleValue(group, tag.ID))
+			if flat {
+				key := group.Name + ":" + strings.ReplaceAll(tag.Name, " ", "")
+				doc.Tags[key] = value
+				continue
+			}
+			doc.IFDs[group.Name] = append(doc.IFDs[group.Name], jsonTag{
+				ID:    fmt.Sprintf("0x%04X", tag.ID),
+				Name:  tag.Name,
+				Type:  tag.DataType.String(),
+				Count: tag.DataCount,
+				Value: value,
+			})
+		}
+	}
+	return doc
+}
+
+// singleValue reports whether the specification fixes the count of tag id in
+// group to 1. Unknown tags may hold any number of values.
+func (d ImageData) singleValue(group TagGroup, id uint16) bool {
+	def, ok := LookupTag(group.Type, id)
+	if d.Format == FormatRW2 && group.Name == "IFD0" {
+		// Panasonic redefines the low tag IDs of IFD0
+		if panasonicDef, found := LookupTag(IFDPANASONIC, id); found {
+			def, ok = panasonicDef, true
+		}
+	}
+	return ok && def.Count == 1
+}
+
+// jsonValue converts the decoded tag data into something encoding/json renders
+// well. Numbers are written as a scalar when single is set and the tag holds
+// exactly one value, otherwise as an array.
+func jsonValue(tag IFDtag, single bool) any {
+	switch v := tag.Data.(type) {
+	case []uint8:
+		if tag.DataType == TypeUndefined {
+			// encoding/json writes []byte as base64
+			return v
+		}
+		ints := make([]int, len(v))
+		for i, b := range v {
+			ints[i] = int(b)
+		}
+		return scalarOrSlice(ints, single)
+	case []uint16:
+		return scalarOrSlice(v, single)
+	case []uint32:
+		return scalarOrSlice(v, single)
+	case []uint64:
+		return scalarOrSlice(v, single)
+	case []int8:
+		return scalarOrSlice(v, single)
+	case []int16:
+		return scalarOrSlice(v, single)
+	case []int32:
+		return scalarOrSlice(v, single)
+	case []int64:
+		return scalarOrSlice(v, single)
+	case []float32:
+		floats := make([]*float64, len(v))
+		for i, f := range v {
+			floats[i] = finite(float64(f))
+		}
+		return scalarOrSlice(floats, single)
+	case []float64:
+		floats := make([]*float64, len(v))
+		for i, f := range v {
+			floats[i] = finite(f)
+		}
+		return scalarOrSlice(floats, single)
+	case Rational:
+		return scalarOrSlice([]jsonRational{newJSONRational(int64(v.Numerator), int64(v.Denominator))}, single)
+	case Srational:
+		return scalarOrSlice([]jsonRational{newJSONRational(int64(v.Numerator), int64(v.Denominator))}, single)
+	case []Rational:
+		rs := make([]jsonRational, len(v))
+		for i, r := range v {
+			rs[i] = newJSONRational(int64(r.Numerator), int64(r.Denominator))
+		}
+		return scalarOrSlice(rs, single)
+	case []Srational:
+		rs := make([]jsonRational, len(v))
+		for i, r := range v {
+			rs[i] = newJSONRational(int64(r.Numerator), int64(r.Denominator))
+		}
+		return scalarOrSlice(rs, single)
+	default:
+		return v
+	}
+}
+
+func scalarOrSlice[T any](v []T, single bool) any {
+	if single && len(v) == 1 {
+		return v[0]
+	}
+	return v
+}
+
+// finite returns nil for NaN and infinities, which JSON cannot represent.
+func finite(f float64) *float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,103 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	imgData := &ImageData{
+		ImagePath: "photo.jpg",
+		Format:    FormatJPEG,
+		MetaData: MetaData{
+			// out of ID order on purpose
+			MainTags: []IFDtag{
+				{ID: 0x0112, Name: "Orientation", DataType: TypeShort, DataCount: 1, Data: []uint16{1}},
+				{ID: 0x010F, Name: "Make", DataType: TypeAscii, DataCount: 6, Data: "Canon"},
+				// unknown tags are arrays even with a single value
+				{ID: 0x9999, Name: "Unknown_0x9999", DataType: TypeLong, DataCount: 1, Data: []uint32{7}},
+			},
+			ExifTags: []IFDtag{
+				{ID: 0x829A, Name: "Exposure Time", DataType: TypeRational, DataCount: 1, Data: []Rational{{1, 250}}},
+				{ID: 0x9204, Name: "Exposure Bias Value", DataType: TypeSRational, DataCount: 1, Data: []Srational{{-1, 0}}},
+				{ID: 0x9000, Name: "Exif Version", DataType: TypeUndefined, DataCount: 4, Data: []uint8("0232")},
+				{ID: 0xA500, Name: "Gamma", DataType: TypeDouble, DataCount: 2, Data: []float64{2.2, math.NaN()}},
+				// so are tags of variable count
+				{ID: 0x8827, Name: "ISO Speed Ratings", DataType: TypeShort, DataCount: 1, Data: []uint16{100}},
+			},
+		},
+		Warnings: []error{errors.New("APP2 at 20: bad chunk")},
+	}
+
+	tests := []struct {
+		name string
+		opts JSONOptions
+		want string
+	}{
+		{
+			name: "grouped",
+			want: `{"path":"photo.jpg","format":"jpeg","ifds":{` +
+				`"Exif":[{"id":"0x829A","name":"Exposure Time","type":"RATIONAL","count":1,"value":{"num":1,"den":250,"float":0.004}},` +
+				`{"id":"0x8827","name":"ISO Speed Ratings","type":"SHORT","count":1,"value":[100]},` +
+				`{"id":"0x9000","name":"Exif Version","type":"UNDEFINED","count":4,"value":"MDIzMg=="},` +
+				`{"id":"0x9204","name":"Exposure Bias Value","type":"SRATIONAL","count":1,"value":{"num":-1,"den":0,"float":null}},` +
+				`{"id":"0xA500","name":"Gamma","type":"DOUBLE","count":2,"value":[2.2,null]}],` +
+				`"IFD0":[{"id":"0x010F","name":"Make","type":"ASCII","count":6,"value":"Canon"},` +
+				`{"id":"0x0112","name":"Orientation","type":"SHORT","count":1,"value":1},` +
+				`{"id":"0x9999","name":"Unknown_0x9999","type":"LONG","count":1,"value":[7]}]},` +
+				`"warnings":["APP2 at 20: bad chunk"]}` + "\n",
+		},
+		{
+			name: "flat",
+			opts: JSONOptions{Flat: true},
+			want: `{"path":"photo.jpg","format":"jpeg","tags":{` +
+				`"Exif:ExifVersion":"MDIzMg==","Exif:ExposureBiasValue":{"num":-1,"den":0,"float":null},` +
+				`"Exif:ExposureTime":{"num":1,"den":250,"float":0.004},"Exif:Gamma":[2.2,null],"Exif:ISOSpeedRatings":[100],` +
+				`"IFD0:Make":"Canon","IFD0:Orientation":1,"IFD0:Unknown_0x9999":[7]},` +
+				`"warnings":["APP2 at 20: bad chunk"]}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var first, second bytes.Buffer
+			if err := EncodeJSON(&first, imgData, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if got := first.String(); got != tt.want {
+				t.Errorf("EncodeJSON() =\n%s\nwant\n%s", got, tt.want)
+			}
+			// the output must not depend on map iteration order
+			for range 10 {
+				second.Reset()
+				if err := EncodeJSON(&second, imgData, tt.opts); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(first.Bytes(), second.Bytes()) {
+					t.Fatalf("EncodeJSON() is not deterministic:\n%s\n%s", first.String(), second.String())
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeJSONList(t *testing.T) {
+	tests := []struct {
+		name   string
+		images []*ImageData
+		want   string
+	}{
+		{"none", nil, "[]\n"},
+		{"one", []*ImageData{{ImagePath: "a.png", Format: FormatPNG}}, `[{"path":"a.png","format":"png"}]` + "\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := EncodeJSONList(&b, tt.images, JSONOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: EncodeJSONList() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package metadata
+
+import (
+	"fmt"
+	"io"
+)
+
+// MP types of the MP Entry attributes
+const (
+	MPTypeBaseline      uint32 = 0x030000 // baseline MP primary image
+	MPTypeLargeThumbVGA uint32 = 0x010001 // large thumbnail, VGA equivalent
+	MPTypeLargeThumbFHD uint32 = 0x010002 // large thumbnail, full HD equivalent
+	MPTypePanorama      uint32 = 0x020001 // multi-frame panorama
+	MPTypeDisparity     uint32 = 0x020002 // multi-frame disparity (stereo)
+	MPTypeMultiAngle    uint32 = 0x020003 // multi-frame multi-angle
+	MPTypeUndefined     uint32 = 0x000000
+)
+
+// MPF is the MP Index IFD of a JPEG file that holds several images.
+type MPF struct {
+	Version string     `json:"version"`
+	Offset  int64      `json:"offset"` // absolute offset of the MPF header the entry offsets are relative to
+	Images  []MPFImage `json:"images"`
+}
+
+// MPFImage is one MP Entry.
+type MPFImage struct {
+	Type            uint32    `json:"type"`   // MP type code, e.g. MPTypeLargeThumbVGA
+	Format          uint8     `json:"format"` // image data format, 0 is JPEG
+	Representative  bool      `json:"representative"`
+	DependentParent bool      `json:"dependentParent"`
+	DependentChild  bool      `json:"dependentChild"`
+	Offset          int64     `json:"offset"` // absolute offset of the SOI, the first image is the file itself
+	Length          int64     `json:"length"`
+	Dependent       [2]uint16 `json:"dependent"` // entry numbers of dependent images, 0 for none
+}
+
+// TypeName describes the MP type of the image.
+func (m MPFImage) TypeName() string {
+	switch m.Type {
+	case MPTypeBaseline:
+		return "Baseline MP Primary Image"
+	case MPTypeLargeThumbVGA:
+		return "Large Thumbnail (VGA)"
+	case MPTypeLargeThumbFHD:
+		return "Large Thumbnail (Full HD)"
+	case MPTypePanorama:
+		return "Multi-Frame Panorama"
+	case MPTypeDisparity:
+		return "Multi-Frame Disparity"
+	case MPTypeMultiAngle:
+		return "Multi-Frame Multi-Angle"
+	case MPTypeUndefined:
+		return "Undefined"
+	}
+	return fmt.Sprintf("Unknown 0x%06X", m.Type)
+}
+
+// Read returns the JPEG stream of the image.
+func (m MPFImage) Read(r io.ReaderAt) ([]byte, error) {
+	return Preview{Source: "MPF", Offset: m.Offset, Length: m.Length}.Read(r)
+}
@@ -0,0 +1,50 @@
+package metadata
+
+// Photoshop holds the image resource blocks Photoshop stores in APP13 or TIFF
+// tag 0x8649 and the values of the well-known ones.
+type Photoshop struct {
+	Resources   []PhotoshopResource   `json:"resources"`
+	Resolution  *PhotoshopResolution  `json:"resolution,omitempty"`  // 0x03ED
+	JPEGQuality *PhotoshopJPEGQuality `json:"jpegQuality,omitempty"` // 0x0406
+	Thumbnail   *PhotoshopThumbnail   `json:"thumbnail,omitempty"`   // 0x040C
+	Copyrighted *bool                 `json:"copyrighted,omitempty"` // 0x040A
+	URL         string                `json:"url,omitempty"`         // 0x040B
+	IPTCDigest  string                `json:"iptcDigest,omitempty"`  // 0x0425, hex MD5 of the IPTC resource
+}
+
+// PhotoshopResource is one image resource block.
+type PhotoshopResource struct {
+	ID        uint16 `json:"id"`
+	Name      string `json:"name,omitempty"`      // well-known name, empty for unknown IDs
+	BlockName string `json:"blockName,omitempty"` // Pascal string stored in the block, usually empty
+	Size      int    `json:"size"`
+	Data      []byte `json:"-"`
+}
+
+// PhotoshopResolution is the ResolutionInfo resource. Resolutions are in
+// pixels per inch when the unit is 1 and pixels per centimetre when it is 2.
+type PhotoshopResolution struct {
+	XResolution float64 `json:"xResolution"`
+	XUnit       uint16  `json:"xUnit"`
+	WidthUnit   uint16  `json:"widthUnit"` // display unit: 1 inches, 2 cm, 3 points, 4 picas, 5 columns
+	YResolution float64 `json:"yResolution"`
+	YUnit       uint16  `json:"yUnit"`
+	HeightUnit  uint16  `json:"heightUnit"`
+}
+
+// PhotoshopJPEGQuality holds the Save As JPEG options.
+type PhotoshopJPEGQuality struct {
+	Quality int    `json:"quality"`         // 0 to 12
+	Format  string `json:"format"`          // "Standard", "Optimized" or "Progressive"
+	Scans   int    `json:"scans,omitempty"` // progressive scans, 3 to 5
+}
+
+// PhotoshopThumbnail is the header of the thumbnail resource and the JFIF
+// stream that follows it.
+type PhotoshopThumbnail struct {
+	Format uint32 `json:"format"` // 1 JFIF, 0 raw RGB
+	Width  uint32 `json:"width"`
+	Height uint32 `json:"height"`
+	Offset int64  `json:"offset"` // absolute offset of the stream, 0 when unknown
+	Data   []byte `json:"-"`
+}
@@ -0,0 +1,52 @@
+package metadata
+
+import "time"
+
+// PNG holds the header and the ancillary chunks of a PNG file.
+type PNG struct {
+	Width          uint32             `json:"width"`
+	Height         uint32             `json:"height"`
+	BitDepth       uint8              `json:"bitDepth"`
+	ColorType      uint8              `json:"colorType"`
+	Text           []PNGText          `json:"text,omitempty"`           // tEXt, zTXt and iTXt in file order
+	Time           *time.Time         `json:"time,omitempty"`           // tIME, last modification in UTC
+	Phys           *PNGPhys           `json:"phys,omitempty"`           // pHYs
+	Gamma          *float64           `json:"gamma,omitempty"`          // gAMA
+	Chromaticities *PNGChromaticities `json:"chromaticities,omitempty"` // cHRM
+	SRGBIntent     *uint8             `json:"srgbIntent,omitempty"`     // sRGB rendering intent, 0 perceptual to 3 absolute
+	ICCProfile     *PNGICCProfile     `json:"iccProfile,omitempty"`     // iCCP
+}
+
+// PNGText is a tEXt, zTXt or iTXt chunk with its text inflated and decoded to UTF-8.
+type PNGText struct {
+	Chunk             string `json:"chunk"` // "tEXt", "zTXt" or "iTXt"
+	Keyword           string `json:"keyword"`
+	Language          string `json:"language,omitempty"`          // iTXt only
+	TranslatedKeyword string `json:"translatedKeyword,omitempty"` // iTXt only
+	Text              string `json:"text"`
+}
+
+// PNGPhys is the pHYs chunk.
+type PNGPhys struct {
+	PixelsPerUnitX uint32 `json:"pixelsPerUnitX"`
+	PixelsPerUnitY uint32 `json:"pixelsPerUnitY"`
+	Unit           uint8  `json:"unit"` // 0 unknown (aspect ratio only), 1 metre
+}
+
+// PNGChromaticities is the cHRM chunk, already divided by 100000.
+type PNGChromaticities struct {
+	WhiteX float64 `json:"whiteX"`
+	WhiteY float64 `json:"whiteY"`
+	RedX   float64 `json:"redX"`
+	RedY   float64 `json:"redY"`
+	GreenX float64 `json:"greenX"`
+	GreenY float64 `json:"greenY"`
+	BlueX  float64 `json:"blueX"`
+	BlueY  float64 `json:"blueY"`
+}
+
+// PNGICCProfile is the iCCP chunk.
+type PNGICCProfile struct {
+	Name    string `json:"name"`
+	Profile []byte `json:"-"` // inflated ICC profile
+}
@@ -0,0 +1,30 @@
+package metadata
+
+import (
+	"fmt"
+	"io"
+)
+
+// Preview is a JPEG preview embedded in a RAW file.
+type Preview struct {
+	Source string `json:"source"` // where the preview was found, e.g. "CR3 PRVW"
+	Width  uint32 `json:"width"`  // 0 when the container does not record the size
+	Height uint32 `json:"height"`
+	Offset int64  `json:"offset"` // absolute offset of the JPEG stream
+	Length int64  `json:"length"`
+}
+
+// maxPreviewSize limits how much Preview.Read loads into memory.
+const maxPreviewSize = 128 << 20
+
+// Read returns the JPEG stream of the preview from the file it was found in.
+func (p Preview) Read(r io.ReaderAt) ([]byte, error) {
+	if p.Length < 0 || p.Length > maxPreviewSize {
+		return nil, fmt.Errorf("%s preview length %d is out of range", p.Source, p.Length)
+	}
+	data := make([]byte, p.Length)
+	if _, err := r.ReadAt(data, p.Offset); err != nil {
+		return nil, fmt.Errorf("failed to read %s preview: %w", p.Source, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,31 @@
+package metadata
+
+// RAF is the header of a Fujifilm RAF file and the records of its CFA header.
+type RAF struct {
+	FormatVersion    string `json:"formatVersion"` // e.g. "0201"
+	CameraID         string `json:"cameraId"`
+	Model            string `json:"model"`
+	DirectoryVersion string `json:"directoryVersion"`
+	JPEGOffset       uint32 `json:"jpegOffset"`
+	JPEGLength       uint32 `json:"jpegLength"`
+	CFAHeaderOffset  uint32 `json:"cfaHeaderOffset"`
+	CFAHeaderLength  uint32 `json:"cfaHeaderLength"`
+	CFAOffset        uint32 `json:"cfaOffset"`
+	CFALength        uint32 `json:"cfaLength"`
+	// values decoded from the records, 0 when the record is missing
+	RawWidth      uint16      `json:"rawWidth,omitempty"` // 0x0100 RawImageFullSize
+	RawHeight     uint16      `json:"rawHeight,omitempty"`
+	CropTop       uint16      `json:"cropTop,omitempty"` // 0x0110 RawImageCropTopLeft
+	CropLeft      uint16      `json:"cropLeft,omitempty"`
+	CroppedWidth  uint16      `json:"croppedWidth,omitempty"` // 0x0111 RawImageCroppedSize
+	CroppedHeight uint16      `json:"croppedHeight,omitempty"`
+	WBLevels      []uint16    `json:"wbLevels,omitempty"` // 0x2FF0 white balance levels in G R G B order
+	Records       []RAFRecord `json:"records"`
+}
+
+// RAFRecord is one tag record of the RAF CFA header.
+type RAFRecord struct {
+	Tag  uint16 `json:"tag"`
+	Name string `json:"name,omitempty"` // empty for unknown tags
+	Data []byte `json:"-"`
+}
@@ -2,199 +2,427 @@ package metadata
 
 import (
 	"fmt"
+	"slices"
 )
 
-func GetNameFromID(id uint16) (string, error) {
-	if name, exists := ifdMainTagList[id]; exists {
-		return name, nil
+// CountAny marks a tag whose number of values is not fixed by the specification.
+const CountAny = -1
+
+// TagDef describes a tag as defined by TIFF 6.0, TIFF/EP and EXIF 3.0.
+type TagDef struct {
+	ID          uint16
+	Name        string
+	IFD         IFDtype
+	Types       []DataType // allowed data types, empty means any type
+	Count       int        // expected number of values or CountAny
+	Description string
+}
+
+// Validate reports whether tag carries one of the expected data types and the expected count.
+func (d TagDef) Validate(tag IFDtag) error {
+	if len(d.Types) > 0 && !slices.Contains(d.Types, tag.DataType) && !slices.Contains(d.Types, narrow(tag.DataType)) {
+		return fmt.Errorf("%s: unexpected data type %s, expected %v", d.Name, tag.DataType, d.Types)
+	}
+	if d.Count != CountAny && tag.DataCount != uint32(d.Count) {
+		return fmt.Errorf("%s: unexpected count %d, expected %d", d.Name, tag.DataCount, d.Count)
+	}
+	return nil
+}
+
+// narrow maps the 64 bit BigTIFF types to the 32 bit type they stand in for.
+// BigTIFF writers may use LONG8 wherever TIFF allows LONG.
+func narrow(dt DataType) DataType {
+	switch dt {
+	case TypeLong8:
+		return TypeLong
+	case TypeSLong8:
+		return TypeSLong
+	case TypeIFD8:
+		return TypeIFD
+	}
+	return dt
+}
+
+// tagLists holds the tag dictionary of every IFD type. Tag IDs are only unique
+// within one IFD, e.g. 0x0001 is "GPS Latitude Ref" in the GPS IFD but
+// "Interop Index" in the Interoperability IFD.
+var tagLists = map[IFDtype][]TagDef{
+	IFDMAIN:      ifdMainTagList,
+	IFDEXIF:      ifdExifTagList,
+	IFDINTROP:    ifdIntropTagList,
+	IFDGPS:       ifdGPSTagList,
+	IFDPANASONIC: ifdPanasonicTagList,
+	IFDCANON:     ifdCanonTagList,
+	IFDMPF:       ifdMPFTagList,
+}
+
+// tagIndex is tagLists keyed by tag ID.
+var tagIndex = map[IFDtype]map[uint16]TagDef{}
+
+func init() {
+	for ifdType, list := range tagLists {
+		index := make(map[uint16]TagDef, len(list))
+		for _, def := range list {
+			index[def.ID] = def
+		}
+		tagIndex[ifdType] = index
+	}
+}
+
+// LookupTag returns the definition of tag id in an ifdType directory.
+func LookupTag(ifdType IFDtype, id uint16) (TagDef, bool) {
+	def, ok := tagIndex[ifdType][id]
+	return def, ok
+}
+
+// TagDefs returns every known tag of an ifdType directory ordered by ID.
+func TagDefs(ifdType IFDtype) []TagDef {
+	defs := slices.Clone(tagLists[ifdType])
+	slices.SortFunc(defs, func(a, b TagDef) int { return int(a.ID) - int(b.ID) })
+	return defs
+}
+
+// GetNameFromID returns the name of tag id in an ifdType directory.
+// Unknown tags get a stable synthetic name such as "Unknown_0xA431".
+func GetNameFromID(ifdType IFDtype, id uint16) string {
+	if def, exists := LookupTag(ifdType, id); exists {
+		return def.Name
 	}
-	return "", fmt.Errorf("No Tag ID found")
+	return UnknownTagName(id)
 }
 
-var ifdMainTagList = map[uint16]string{
+// UnknownTagName is the name given to tags missing from the dictionaries.
+func UnknownTagName(id uint16) string {
+	return fmt.Sprintf("Unknown_0x%04X", id)
+}
+
+// expected data types
+var (
+	typesByte      = []DataType{TypeByte}
+	typesAscii     = []DataType{TypeAscii}
+	typesShort     = []DataType{TypeShort}
+	typesLong      = []DataType{TypeLong}
+	typesShortLong = []DataType{TypeShort, TypeLong}
+	typesLongIFD   = []DataType{TypeLong, TypeIFD}
+	typesRational  = []DataType{TypeRational}
+	typesSRational = []DataType{TypeSRational}
+	typesUndefined = []DataType{TypeUndefined}
+	typesSShort    = []DataType{TypeSShort}
+	typesByteUndef = []DataType{TypeByte, TypeUndefined}
+	typesAnything  = []DataType{}
+)
+
+var ifdMainTagList = []TagDef{
 	// TIFF Baseline MainTags (TIFF 6.0)
-	0x00FE: "New Subfile Type",
-	0x00FF: "Subfile Type",
-	0x0100: "Image Width",
-	0x0101: "Image Length",
-	0x0102: "Bits Per Sample",
-	0x0103: "Compression",
-	0x0106: "Photometric Interpretation",
-	0x0107: "Thresholding",
-	0x0108: "Cell Width",
-	0x0109: "Cell Length",
-	0x010A: "Fill Order",
-	0x010D: "Document Name",
-	0x010E: "Image Description",
-	0x010F: "Make",
-	0x0110: "Model",
-	0x0111: "Strip Offsets",
-	0x0112: "Orientation",
-	0x0115: "Samples Per Pixel",
-	0x0116: "Rows Per Strip",
-	0x0117: "Strip Byte Counts",
-	0x0118: "Min Sample Value",
-	0x0119: "Max Sample Value",
-	0x011A: "X Resolution",
-	0x011B: "Y Resolution",
-	0x011C: "Planar Configuration",
-	0x0128: "Resolution Unit",
-	0x012D: "Transfer Function",
-	0x0131: "Software",
-	0x0132: "Date Time",
-	0x013B: "Artist",
-	0x013E: "White Point",
-	0x013F: "Primary Chromaticities",
+	{0x00FE, "New Subfile Type", IFDMAIN, typesLong, 1, "Kind of data in this subfile (reduced resolution, page, mask)"},
+	{0x00FF, "Subfile Type", IFDMAIN, typesShort, 1, "Deprecated kind of data in this subfile"},
+	{0x0100, "Image Width", IFDMAIN, typesShortLong, 1, "Number of columns in the image"},
+	{0x0101, "Image Length", IFDMAIN, typesShortLong, 1, "Number of rows in the image"},
+	{0x0102, "Bits Per Sample", IFDMAIN, typesShort, CountAny, "Number of bits per component"},
+	{0x0103, "Compression", IFDMAIN, typesShort, 1, "Compression scheme used on the image data"},
+	{0x0106, "Photometric Interpretation", IFDMAIN, typesShort, 1, "Color space of the image data"},
+	{0x0107, "Thresholding", IFDMAIN, typesShort, 1, "Technique used to convert gray to black and white"},
+	{0x0108, "Cell Width", IFDMAIN, typesShort, 1, "Width of the dithering matrix"},
+	{0x0109, "Cell Length", IFDMAIN, typesShort, 1, "Length of the dithering matrix"},
+	{0x010A, "Fill Order", IFDMAIN, typesShort, 1, "Logical order of bits within a byte"},
+	{0x010D, "Document Name", IFDMAIN, typesAscii, CountAny, "Name of the scanned document"},
+	{0x010E, "Image Description", IFDMAIN, typesAscii, CountAny, "Title of the image"},
+	{0x010F, "Make", IFDMAIN, typesAscii, CountAny, "Manufacturer of the recording equipment"},
+	{0x0110, "Model", IFDMAIN, typesAscii, CountAny, "Model of the recording equipment"},
+	{0x0111, "Strip Offsets", IFDMAIN, typesShortLong, CountAny, "Offset of each strip of image data"},
+	{0x0112, "Orientation", IFDMAIN, typesShort, 1, "Orientation of the image relative to rows and columns"},
+	{0x0115, "Samples Per Pixel", IFDMAIN, typesShort, 1, "Number of components per pixel"},
+	{0x0116, "Rows Per Strip", IFDMAIN, typesShortLong, 1, "Number of rows in each strip"},
+	{0x0117, "Strip Byte Counts", IFDMAIN, typesShortLong, CountAny, "Bytes in each strip after compression"},
+	{0x0118, "Min Sample Value", IFDMAIN, typesShort, CountAny, "Minimum component value used"},
+	{0x0119, "Max Sample Value", IFDMAIN, typesShort, CountAny, "Maximum component value used"},
+	{0x011A, "X Resolution", IFDMAIN, typesRational, 1, "Pixels per resolution unit in the width direction"},
+	{0x011B, "Y Resolution", IFDMAIN, typesRational, 1, "Pixels per resolution unit in the height direction"},
+	{0x011C, "Planar Configuration", IFDMAIN, typesShort, 1, "Chunky or planar storage of the components"},
+	{0x0128, "Resolution Unit", IFDMAIN, typesShort, 1, "Unit of X and Y Resolution"},
+	{0x012D, "Transfer Function", IFDMAIN, typesShort, CountAny, "Transfer function of the image"},
+	{0x0131, "Software", IFDMAIN, typesAscii, CountAny, "Software used to create the image"},
+	{0x0132, "Date Time", IFDMAIN, typesAscii, 20, "Date and time the file was changed"},
+	{0x013B, "Artist", IFDMAIN, typesAscii, CountAny, "Person who created the image"},
+	{0x013E, "White Point", IFDMAIN, typesRational, 2, "Chromaticity of the white point"},
+	{0x013F, "Primary Chromaticities", IFDMAIN, typesRational, 6, "Chromaticities of the primaries"},
 	// TIFF Extended MainTags
-	0x011D: "Page Name",
-	0x011E: "X Position",
-	0x011F: "Y Position",
-	0x0120: "Free Offsets",
-	0x0121: "Free Byte Counts",
-	0x0122: "Gray Response Unit",
-	0x0123: "Gray Response Curve",
-	0x0124: "T4 Options",
-	0x0125: "T6 Options",
-	0x0129: "Page Number",
-	0x013C: "Host Computer",
-	0x013D: "Predictor",
-	0x0140: "Color Map",
-	0x0141: "Halftone Hints",
-	0x0142: "Tile Width",
-	0x0143: "Tile Length",
-	0x0144: "Tile Offsets",
-	0x0145: "Tile Byte Counts",
-	0x014C: "Ink Set",
-	0x014D: "Ink Names",
-	0x014E: "Number Of Inks",
-	0x0150: "Dot Range",
-	0x0151: "Target Printer",
-	0x0152: "Extra Samples",
-	0x0153: "Sample Format",
-	0x0154: "S Min Sample Value",
-	0x0155: "S Max Sample Value",
-	0x0156: "Transfer Range",
-	0x0200: "JPEG Proc",
-	0x0201: "JPEG Interchange Format",
-	0x0202: "JPEG Interchange Format Length",
-	0x0203: "JPEG Restart Interval",
-	0x0205: "JPEG Lossless Predictors",
-	0x0206: "JPEG Point Transforms",
-	0x0207: "JPEG Q Tables",
-	0x0208: "JPEG DC Tables",
-	0x0209: "JPEG AC Tables",
-	0x0211: "Y Cb Cr Coefficients",
-	0x0212: "Y Cb Cr Sub Sampling",
-	0x0213: "Y Cb Cr Positioning",
-	0x0214: "Reference Black White",
+	{0x011D, "Page Name", IFDMAIN, typesAscii, CountAny, "Name of the page the image was scanned from"},
+	{0x011E, "X Position", IFDMAIN, typesRational, 1, "X offset of the image"},
+	{0x011F, "Y Position", IFDMAIN, typesRational, 1, "Y offset of the image"},
+	{0x0120, "Free Offsets", IFDMAIN, typesLong, CountAny, "Offsets of unused byte strings"},
+	{0x0121, "Free Byte Counts", IFDMAIN, typesLong, CountAny, "Sizes of unused byte strings"},
+	{0x0122, "Gray Response Unit", IFDMAIN, typesShort, 1, "Precision of Gray Response Curve"},
+	{0x0123, "Gray Response Curve", IFDMAIN, typesShort, CountAny, "Optical density of each gray level"},
+	{0x0124, "T4 Options", IFDMAIN, typesLong, 1, "CCITT Group 3 options"},
+	{0x0125, "T6 Options", IFDMAIN, typesLong, 1, "CCITT Group 4 options"},
+	{0x0129, "Page Number", IFDMAIN, typesShort, 2, "Page number and total pages"},
+	{0x013C, "Host Computer", IFDMAIN, typesAscii, CountAny, "Computer used to create the image"},
+	{0x013D, "Predictor", IFDMAIN, typesShort, 1, "Prediction scheme used before compression"},
+	{0x0140, "Color Map", IFDMAIN, typesShort, CountAny, "Palette of a palette-color image"},
+	{0x0141, "Halftone Hints", IFDMAIN, typesShort, 2, "Highlight and shadow values"},
+	{0x0142, "Tile Width", IFDMAIN, typesShortLong, 1, "Number of columns in each tile"},
+	{0x0143, "Tile Length", IFDMAIN, typesShortLong, 1, "Number of rows in each tile"},
+	{0x0144, "Tile Offsets", IFDMAIN, typesLong, CountAny, "Offset of each tile"},
+	{0x0145, "Tile Byte Counts", IFDMAIN, typesShortLong, CountAny, "Bytes in each tile after compression"},
+	{0x014A, "Sub IFDs", IFDMAIN, typesLongIFD, CountAny, "Offsets of child IFDs"},
+	{0x014C, "Ink Set", IFDMAIN, typesShort, 1, "Set of inks used in a separated image"},
+	{0x014D, "Ink Names", IFDMAIN, typesAscii, CountAny, "Names of the inks"},
+	{0x014E, "Number Of Inks", IFDMAIN, typesShort, 1, "Number of inks"},
+	{0x0150, "Dot Range", IFDMAIN, []DataType{TypeByte, TypeShort}, CountAny, "Component values for 0% and 100% dots"},
+	{0x0151, "Target Printer", IFDMAIN, typesAscii, CountAny, "Intended printing environment"},
+	{0x0152, "Extra Samples", IFDMAIN, typesShort, CountAny, "Meaning of extra components"},
+	{0x0153, "Sample Format", IFDMAIN, typesShort, CountAny, "Interpretation of each component"},
+	{0x0154, "S Min Sample Value", IFDMAIN, typesAnything, CountAny, "Minimum sample value"},
+	{0x0155, "S Max Sample Value", IFDMAIN, typesAnything, CountAny, "Maximum sample value"},
+	{0x0156, "Transfer Range", IFDMAIN, typesShort, 6, "Expands the range of Transfer Function"},
+	{0x0200, "JPEG Proc", IFDMAIN, typesShort, 1, "Old-style JPEG process"},
+	{0x0201, "JPEG Interchange Format", IFDMAIN, typesLong, 1, "Offset to the JPEG SOI of the thumbnail"},
+	{0x0202, "JPEG Interchange Format Length", IFDMAIN, typesLong, 1, "Bytes of JPEG thumbnail data"},
+	{0x0203, "JPEG Restart Interval", IFDMAIN, typesShort, 1, "Old-style JPEG restart interval"},
+	{0x0205, "JPEG Lossless Predictors", IFDMAIN, typesShort, CountAny, "Old-style JPEG lossless predictors"},
+	{0x0206, "JPEG Point Transforms", IFDMAIN, typesShort, CountAny, "Old-style JPEG point transforms"},
+	{0x0207, "JPEG Q Tables", IFDMAIN, typesLong, CountAny, "Offsets of the quantization tables"},
+	{0x0208, "JPEG DC Tables", IFDMAIN, typesLong, CountAny, "Offsets of the DC Huffman tables"},
+	{0x0209, "JPEG AC Tables", IFDMAIN, typesLong, CountAny, "Offsets of the AC Huffman tables"},
+	{0x0211, "Y Cb Cr Coefficients", IFDMAIN, typesRational, 3, "RGB to YCbCr transformation coefficients"},
+	{0x0212, "Y Cb Cr Sub Sampling", IFDMAIN, typesShort, 2, "Chroma subsampling ratio"},
+	{0x0213, "Y Cb Cr Positioning", IFDMAIN, typesShort, 1, "Position of chroma relative to luma"},
+	{0x0214, "Reference Black White", IFDMAIN, typesRational, 6, "Reference black and white point values"},
+	// TIFF/EP MainTags
+	{0x828D, "CFA Repeat Pattern Dim", IFDMAIN, typesShort, 2, "Dimensions of the CFA repeat pattern"},
+	{0x828E, "CFA Pattern", IFDMAIN, typesByte, CountAny, "Color filter array geometric pattern"},
+	{0x9216, "TIFF EP Standard ID", IFDMAIN, typesByte, 4, "TIFF/EP standard version"},
+	{0x9217, "Sensing Method", IFDMAIN, typesShort, 1, "Image sensor type"},
 	// Other Common MainTags
-	0x8769: "Exif IFD Pointer",
-	0x8825: "GPS Info IFD Pointer",
-	0x02BC: "Application Notes", // XMP metadata
-	0x83BB: "IPTC Data",
-	0x8773: "ICC Profile",
-}
-
-var ifdExifTagList = map[uint16]string{
-	0x8298: "Copyright",
-	0x829A: "Exposure Time",
-	0x829D: "F Number",
-	0x8822: "Exposure Program",
-	0x8824: "Spectral Sensitivity",
-	0x8827: "ISO Speed Ratings",
-	0x8828: "Opto-Electric Conversion Factor",
-	0x882A: "Time Zone Offset",
-	0x882B: "Self Timer Mode",
-	0x9000: "Exif Version",
-	0x9003: "Date Time Original",
-	0x9004: "Create Date",
-	0x9101: "Components Configuration",
-	0x9102: "Compressed Bits Per Pixel",
-	0x9201: "Shutter Speed Value",
-	0x9202: "Aperture Value",
-	0x9203: "Brightness Value",
-	0x9204: "Exposure Compensation",
-	0x9205: "Max Aperture Value",
-	0x9206: "Subject Distance",
-	0x9207: "Metering Mode",
-	0x9208: "Light Source",
-	0x9209: "Flash",
-	0x920A: "Focal Length",
-	0x9214: "Subject Area",
-	0x927C: "Maker Note",
-	0x9286: "User Comment",
-	0x9290: "Sub Sec Time",
-	0x9291: "Sub Sec Time Original",
-	0x9292: "Sub Sec Time Digitized",
-	0xA000: "Flashpix Version",
-	0xA001: "Color Space",
-	0xA002: "Pixel X Dimension",
-	0xA003: "Pixel Y Dimension",
-	0xA004: "Related Sound File",
-	0xA005: "Interop Offset",
-	0xA20B: "Flash Energy",
-	0xA20C: "Spatial Frequency Response",
-	0xA20E: "Focal Plane X Resolution",
-	0xA20F: "Focal Plane Y Resolution",
-	0xA210: "Focal Plane Resolution Unit",
-	0xA214: "Subject Location",
-	0xA215: "Exposure Index",
-	0xA217: "Sensing Method",
-	0xA300: "File Source",
-	0xA301: "Scene Type",
-	0xA302: "CFA Pattern",
-	0xA401: "Custom Rendered",
-	0xA402: "Exposure Mode",
-	0xA403: "White Balance",
-	0xA404: "Digital Zoom Ratio",
-	0xA405: "Focal Length In 35mm Film",
-	0xA406: "Scene Capture Type",
-	0xA407: "Gain Control",
-	0xA408: "Contrast",
-	0xA409: "Saturation",
-	0xA40A: "Sharpness",
-	0xA40C: "Subject Distance Range",
-	0xA420: "Image Unique ID",
-}
-
-var ifdIntropTagList = map[uint16]string{
-	0x0001: "Interop Index",
-	0x0002: "Interop Version",
-	0x1000: "Related Image File Format",
-	0x1001: "Related Image Width",
-	0x1002: "Related Image Length",
-}
-
-var ifdGPSTagList = map[uint16]string{
-	0x0000: "GPS Version ID",
-	0x0001: "GPS Latitude Ref",
-	0x0002: "GPS Latitude",
-	0x0003: "GPS Longitude Ref",
-	0x0004: "GPS Longitude",
-	0x0005: "GPS Altitude Ref",
-	0x0006: "GPS Altitude",
-	0x0007: "GPS Time Stamp",
-	0x0008: "GPS Satellites",
-	0x0009: "GPS Status",
-	0x000A: "GPS Measure Mode",
-	0x000B: "GPS DOP",
-	0x000C: "GPS Speed Ref",
-	0x000D: "GPS Speed",
-	0x000E: "GPS Track Ref",
-	0x000F: "GPS Track",
-	0x0010: "GPS Img Direction Ref",
-	0x0011: "GPS Img Direction",
-	0x0012: "GPS Map Datum",
-	0x0013: "GPS Dest Latitude Ref",
-	0x0014: "GPS Dest Latitude",
-	0x0015: "GPS Dest Longitude Ref",
-	0x0016: "GPS Dest Longitude",
-	0x0017: "GPS Dest Bearing Ref",
-	0x0018: "GPS Dest Bearing",
-	0x0019: "GPS Dest Distance Ref",
-	0x001A: "GPS Dest Distance",
-	0x001B: "GPS Processing Method",
-	0x001C: "GPS Area Information",
-	0x001D: "GPS Date Stamp",
-	0x001E: "GPS Differential",
+	{0x8298, "Copyright", IFDMAIN, typesAscii, CountAny, "Photographer and editor copyright"},
+	{0x8769, "Exif IFD Pointer", IFDMAIN, typesLongIFD, 1, "Offset of the Exif IFD"},
+	{0x8825, "GPS Info IFD Pointer", IFDMAIN, typesLongIFD, 1, "Offset of the GPS IFD"},
+	{0x02BC, "Application Notes", IFDMAIN, typesByteUndef, CountAny, "XMP metadata packet"},
+	{0x83BB, "IPTC Data", IFDMAIN, []DataType{TypeUndefined, TypeLong, TypeByte}, CountAny, "IPTC-IIM records"},
+	{0x8649, "Photoshop Settings", IFDMAIN, typesByteUndef, CountAny, "Photoshop image resource blocks"},
+	{0x8773, "ICC Profile", IFDMAIN, typesUndefined, CountAny, "Embedded ICC color profile"},
+	{0x4746, "Rating", IFDMAIN, typesShort, 1, "Windows star rating (0-5)"},
+	{0x4749, "Rating Percent", IFDMAIN, typesShort, 1, "Windows rating as a percentage"},
+	{0xC4A5, "Print IM", IFDMAIN, typesUndefined, CountAny, "Epson PRINT Image Matching data"},
+	// Windows XP tags, UCS-2 little-endian strings stored as BYTE
+	{0x9C9B, "XP Title", IFDMAIN, typesByte, CountAny, "Windows title"},
+	{0x9C9C, "XP Comment", IFDMAIN, typesByte, CountAny, "Windows comment"},
+	{0x9C9D, "XP Author", IFDMAIN, typesByte, CountAny, "Windows author"},
+	{0x9C9E, "XP Keywords", IFDMAIN, typesByte, CountAny, "Windows keywords separated by semicolons"},
+	{0x9C9F, "XP Subject", IFDMAIN, typesByte, CountAny, "Windows subject"},
+	// DNG MainTags (DNG 1.6)
+	{0xC612, "DNG Version", IFDMAIN, typesByte, 4, "DNG specification version"},
+	{0xC613, "DNG Backward Version", IFDMAIN, typesByte, 4, "Oldest DNG version a reader needs"},
+	{0xC614, "Unique Camera Model", IFDMAIN, typesAscii, CountAny, "Unique non-localized camera model name"},
+	{0xC615, "Localized Camera Model", IFDMAIN, []DataType{TypeAscii, TypeByte}, CountAny, "Localized camera model name"},
+	{0xC61A, "Black Level", IFDMAIN, []DataType{TypeShort, TypeLong, TypeRational}, CountAny, "Zero light encoding level"},
+	{0xC61D, "White Level", IFDMAIN, typesShortLong, CountAny, "Fully saturated encoding level"},
+	{0xC61E, "Default Scale", IFDMAIN, typesRational, 2, "Scale to square pixels"},
+	{0xC61F, "Default Crop Origin", IFDMAIN, []DataType{TypeShort, TypeLong, TypeRational}, 2, "Origin of the final image area"},
+	{0xC620, "Default Crop Size", IFDMAIN, []DataType{TypeShort, TypeLong, TypeRational}, 2, "Size of the final image area"},
+	{0xC621, "Color Matrix 1", IFDMAIN, typesSRational, CountAny, "XYZ to camera space matrix for illuminant 1"},
+	{0xC622, "Color Matrix 2", IFDMAIN, typesSRational, CountAny, "XYZ to camera space matrix for illuminant 2"},
+	{0xC627, "Analog Balance", IFDMAIN, typesRational, CountAny, "Gain applied to the stored raw values"},
+	{0xC628, "As Shot Neutral", IFDMAIN, []DataType{TypeShort, TypeRational}, CountAny, "Selected white balance as camera neutral"},
+	{0xC62A, "Baseline Exposure", IFDMAIN, typesSRational, 1, "Zero point of the exposure compensation"},
+	{0xC62F, "Camera Serial Number", IFDMAIN, typesAscii, CountAny, "Serial number of the camera"},
+	{0xC630, "DNG Lens Info", IFDMAIN, typesRational, 4, "Focal lengths and f-numbers of the lens"},
+	{0xC634, "DNG Private Data", IFDMAIN, typesByte, CountAny, "Manufacturer private data"},
+	{0xC640, "CR2 Slice", IFDMAIN, typesShort, 3, "Canon CR2 raw slice layout"},
+	{0xC65A, "Calibration Illuminant 1", IFDMAIN, typesShort, 1, "Illuminant of Color Matrix 1"},
+	{0xC65B, "Calibration Illuminant 2", IFDMAIN, typesShort, 1, "Illuminant of Color Matrix 2"},
+	{0xC68D, "Active Area", IFDMAIN, typesShortLong, 4, "Sensor area holding image data"},
+}
+
+// ifdCanonTagList holds the best known tags of the Canon MakerNote.
+var ifdCanonTagList = []TagDef{
+	{0x0001, "Canon Camera Settings", IFDCANON, typesShort, CountAny, "Shooting mode, focus and flash settings"},
+	{0x0002, "Canon Focal Length", IFDCANON, typesShort, 4, "Focal type, focal length and plane size"},
+	{0x0004, "Canon Shot Info", IFDCANON, typesShort, CountAny, "Exposure values measured by the camera"},
+	{0x0006, "Canon Image Type", IFDCANON, typesAscii, CountAny, "Image type, e.g. Canon EOS R5 JPEG"},
+	{0x0007, "Canon Firmware Version", IFDCANON, typesAscii, CountAny, "Camera firmware version"},
+	{0x0009, "Owner Name", IFDCANON, typesAscii, CountAny, "Owner set in the camera"},
+	{0x000C, "Serial Number", IFDCANON, typesLong, 1, "Camera body serial number"},
+	{0x0010, "Canon Model ID", IFDCANON, typesLong, 1, "Numeric camera model"},
+	{0x0095, "Lens Model", IFDCANON, typesAscii, CountAny, "Name of the lens"},
+	{0x0096, "Internal Serial Number", IFDCANON, typesAscii, CountAny, "Internal camera serial number"},
+	{0x0097, "Dust Removal Data", IFDCANON, typesUndefined, CountAny, "Sensor dust positions"},
+	{0x00A0, "Processing Info", IFDCANON, typesShort, CountAny, "In camera processing parameters"},
+	{0x00E0, "Sensor Info", IFDCANON, typesShort, CountAny, "Sensor size and borders"},
+	{0x4001, "Color Data", IFDCANON, typesShort, CountAny, "White balance and color temperature data"},
+}
+
+// ifdMPFTagList holds the tags of the MP Index IFD of CIPA DC-007.
+var ifdMPFTagList = []TagDef{
+	{0xB000, "MPF Version", IFDMPF, typesUndefined, 4, "Multi-Picture Format version, e.g. 0100"},
+	{0xB001, "Number Of Images", IFDMPF, typesLong, 1, "Number of images in the file"},
+	{0xB002, "MP Entry", IFDMPF, typesUndefined, CountAny, "16 bytes per image: attributes, size, offset and dependent images"},
+	{0xB003, "Image UID List", IFDMPF, typesUndefined, CountAny, "33 bytes per image: unique ID of each image"},
+	{0xB004, "Total Frames", IFDMPF, typesLong, 1, "Number of frames captured"},
+}
+
+// ifdPanasonicTagList holds the tags Panasonic stores in IFD0 of RW2 files
+// in place of the TIFF baseline tags with the same IDs.
+var ifdPanasonicTagList = []TagDef{
+	{0x0001, "Panasonic Raw Version", IFDPANASONIC, typesUndefined, 4, "RW2 format version"},
+	{0x0002, "Sensor Width", IFDPANASONIC, typesShort, 1, "Width of the sensor in pixels"},
+	{0x0003, "Sensor Height", IFDPANASONIC, typesShort, 1, "Height of the sensor in pixels"},
+	{0x0004, "Sensor Top Border", IFDPANASONIC, typesShort, 1, "First row of image data"},
+	{0x0005, "Sensor Left Border", IFDPANASONIC, typesShort, 1, "First column of image data"},
+	{0x0006, "Sensor Bottom Border", IFDPANASONIC, typesShort, 1, "Last row of image data"},
+	{0x0007, "Sensor Right Border", IFDPANASONIC, typesShort, 1, "Last column of image data"},
+	{0x0009, "CFA Pattern", IFDPANASONIC, typesShort, 1, "Color filter array layout"},
+	{0x000A, "Bits Per Sample", IFDPANASONIC, typesShort, 1, "Number of bits per raw sample"},
+	{0x000B, "Compression", IFDPANASONIC, typesShort, 1, "Raw compression scheme"},
+	{0x0017, "ISO", IFDPANASONIC, typesShort, 1, "ISO speed"},
+	{0x0024, "WB Red Level", IFDPANASONIC, typesShort, 1, "Red white balance level"},
+	{0x0025, "WB Green Level", IFDPANASONIC, typesShort, 1, "Green white balance level"},
+	{0x0026, "WB Blue Level", IFDPANASONIC, typesShort, 1, "Blue white balance level"},
+	{0x002D, "Raw Format", IFDPANASONIC, typesShort, 1, "Raw encoding"},
+	{0x002E, "Jpg From Raw", IFDPANASONIC, typesUndefined, CountAny, "Embedded JPEG preview with Exif"},
+	{0x002F, "Crop Top", IFDPANASONIC, typesShort, 1, "Top of the default crop"},
+	{0x0030, "Crop Left", IFDPANASONIC, typesShort, 1, "Left of the default crop"},
+	{0x0031, "Crop Bottom", IFDPANASONIC, typesShort, 1, "Bottom of the default crop"},
+	{0x0032, "Crop Right", IFDPANASONIC, typesShort, 1, "Right of the default crop"},
+	{0x0118, "Raw Data Offset", IFDPANASONIC, typesLong, 1, "Offset of the raw image data"},
+	{0x0119, "Distortion Info", IFDPANASONIC, typesUndefined, CountAny, "Lens distortion correction"},
+	{0x011C, "Gamma", IFDPANASONIC, typesShort, 1, "Gamma applied to the raw data"},
+}
+
+var ifdExifTagList = []TagDef{
+	{0x829A, "Exposure Time", IFDEXIF, typesRational, 1, "Exposure time in seconds"},
+	{0x829D, "F Number", IFDEXIF, typesRational, 1, "F number"},
+	{0x8822, "Exposure Program", IFDEXIF, typesShort, 1, "Class of program used to set exposure"},
+	{0x8824, "Spectral Sensitivity", IFDEXIF, typesAscii, CountAny, "Spectral sensitivity of each channel"},
+	{0x8827, "ISO Speed Ratings", IFDEXIF, typesShort, CountAny, "Photographic sensitivity (ISO)"},
+	{0x8828, "Opto-Electric Conversion Factor", IFDEXIF, typesUndefined, CountAny, "OECF as specified in ISO 14524"},
+	{0x882A, "Time Zone Offset", IFDEXIF, typesSShort, CountAny, "TIFF/EP time zone offset in hours"},
+	{0x882B, "Self Timer Mode", IFDEXIF, typesShort, 1, "Self timer delay in seconds"},
+	{0x8830, "Sensitivity Type", IFDEXIF, typesShort, 1, "Which sensitivity parameter ISO Speed Ratings holds"},
+	{0x8831, "Standard Output Sensitivity", IFDEXIF, typesLong, 1, "Standard output sensitivity (ISO 12232)"},
+	{0x8832, "Recommended Exposure Index", IFDEXIF, typesLong, 1, "Recommended exposure index (ISO 12232)"},
+	{0x8833, "ISO Speed", IFDEXIF, typesLong, 1, "ISO speed (ISO 12232)"},
+	{0x8834, "ISO Speed Latitude yyy", IFDEXIF, typesLong, 1, "ISO speed latitude yyy"},
+	{0x8835, "ISO Speed Latitude zzz", IFDEXIF, typesLong, 1, "ISO speed latitude zzz"},
+	{0x9000, "Exif Version", IFDEXIF, typesUndefined, 4, "Exif version, e.g. 0232"},
+	{0x9003, "Date Time Original", IFDEXIF, typesAscii, 20, "Date and time the original image was generated"},
+	{0x9004, "Create Date", IFDEXIF, typesAscii, 20, "Date and time the image was stored as digital data"},
+	{0x9010, "Offset Time", IFDEXIF, typesAscii, 7, "UTC offset of Date Time"},
+	{0x9011, "Offset Time Original", IFDEXIF, typesAscii, 7, "UTC offset of Date Time Original"},
+	{0x9012, "Offset Time Digitized", IFDEXIF, typesAscii, 7, "UTC offset of Create Date"},
+	{0x9101, "Components Configuration", IFDEXIF, typesUndefined, 4, "Order of the components"},
+	{0x9102, "Compressed Bits Per Pixel", IFDEXIF, typesRational, 1, "Image compression mode"},
+	{0x9201, "Shutter Speed Value", IFDEXIF, typesSRational, 1, "APEX shutter speed"},
+	{0x9202, "Aperture Value", IFDEXIF, typesRational, 1, "APEX lens aperture"},
+	{0x9203, "Brightness Value", IFDEXIF, typesSRational, 1, "APEX brightness"},
+	{0x9204, "Exposure Compensation", IFDEXIF, typesSRational, 1, "APEX exposure bias"},
+	{0x9205, "Max Aperture Value", IFDEXIF, typesRational, 1, "APEX smallest F number of the lens"},
+	{0x9206, "Subject Distance", IFDEXIF, typesRational, 1, "Distance to the subject in meters"},
+	{0x9207, "Metering Mode", IFDEXIF, typesShort, 1, "Metering mode"},
+	{0x9208, "Light Source", IFDEXIF, typesShort, 1, "Kind of light source"},
+	{0x9209, "Flash", IFDEXIF, typesShort, 1, "Flash status"},
+	{0x920A, "Focal Length", IFDEXIF, typesRational, 1, "Actual focal length of the lens in mm"},
+	{0x9214, "Subject Area", IFDEXIF, typesShort, CountAny, "Location and area of the main subject"},
+	{0x927C, "Maker Note", IFDEXIF, typesUndefined, CountAny, "Manufacturer specific information"},
+	{0x9286, "User Comment", IFDEXIF, typesUndefined, CountAny, "User comment with character code prefix"},
+	{0x9290, "Sub Sec Time", IFDEXIF, typesAscii, CountAny, "Fractions of a second for Date Time"},
+	{0x9291, "Sub Sec Time Original", IFDEXIF, typesAscii, CountAny, "Fractions of a second for Date Time Original"},
+	{0x9292, "Sub Sec Time Digitized", IFDEXIF, typesAscii, CountAny, "Fractions of a second for Create Date"},
+	{0x9400, "Ambient Temperature", IFDEXIF, typesSRational, 1, "Ambient temperature in degrees Celsius"},
+	{0x9401, "Humidity", IFDEXIF, typesRational, 1, "Ambient relative humidity in percent"},
+	{0x9402, "Pressure", IFDEXIF, typesRational, 1, "Air pressure in hPa"},
+	{0x9403, "Water Depth", IFDEXIF, typesSRational, 1, "Water depth in meters"},
+	{0x9404, "Acceleration", IFDEXIF, typesRational, 1, "Acceleration in mGal"},
+	{0x9405, "Camera Elevation Angle", IFDEXIF, typesSRational, 1, "Elevation angle of the camera in degrees"},
+	{0xA000, "Flashpix Version", IFDEXIF, typesUndefined, 4, "Supported Flashpix version"},
+	{0xA001, "Color Space", IFDEXIF, typesShort, 1, "Color space, 1 is sRGB"},
+	{0xA002, "Pixel X Dimension", IFDEXIF, typesShortLong, 1, "Valid image width"},
+	{0xA003, "Pixel Y Dimension", IFDEXIF, typesShortLong, 1, "Valid image height"},
+	{0xA004, "Related Sound File", IFDEXIF, typesAscii, 13, "Name of a related audio file"},
+	{0xA005, "Interop Offset", IFDEXIF, typesLongIFD, 1, "Offset of the Interoperability IFD"},
+	{0xA20B, "Flash Energy", IFDEXIF, typesRational, 1, "Strobe energy in BCPS"},
+	{0xA20C, "Spatial Frequency Response", IFDEXIF, typesUndefined, CountAny, "SFR table as specified in ISO 12233"},
+	{0xA20E, "Focal Plane X Resolution", IFDEXIF, typesRational, 1, "Pixels per focal plane resolution unit (width)"},
+	{0xA20F, "Focal Plane Y Resolution", IFDEXIF, typesRational, 1, "Pixels per focal plane resolution unit (height)"},
+	{0xA210, "Focal Plane Resolution Unit", IFDEXIF, typesShort, 1, "Unit of the focal plane resolution"},
+	{0xA214, "Subject Location", IFDEXIF, typesShort, 2, "Location of the main subject"},
+	{0xA215, "Exposure Index", IFDEXIF, typesRational, 1, "Exposure index selected on the camera"},
+	{0xA217, "Sensing Method", IFDEXIF, typesShort, 1, "Image sensor type"},
+	{0xA300, "File Source", IFDEXIF, typesUndefined, 1, "Image source, 3 is a digital camera"},
+	{0xA301, "Scene Type", IFDEXIF, typesUndefined, 1, "Type of scene, 1 is directly photographed"},
+	{0xA302, "CFA Pattern", IFDEXIF, typesUndefined, CountAny, "Color filter array geometric pattern"},
+	{0xA401, "Custom Rendered", IFDEXIF, typesShort, 1, "Use of special processing"},
+	{0xA402, "Exposure Mode", IFDEXIF, typesShort, 1, "Exposure mode"},
+	{0xA403, "White Balance", IFDEXIF, typesShort, 1, "White balance mode"},
+	{0xA404, "Digital Zoom Ratio", IFDEXIF, typesRational, 1, "Digital zoom ratio"},
+	{0xA405, "Focal Length In 35mm Film", IFDEXIF, typesShort, 1, "Equivalent focal length for 35mm film"},
+	{0xA406, "Scene Capture Type", IFDEXIF, typesShort, 1, "Type of scene that was shot"},
+	{0xA407, "Gain Control", IFDEXIF, typesShort, 1, "Degree of overall image gain adjustment"},
+	{0xA408, "Contrast", IFDEXIF, typesShort, 1, "Contrast processing applied by the camera"},
+	{0xA409, "Saturation", IFDEXIF, typesShort, 1, "Saturation processing applied by the camera"},
+	{0xA40A, "Sharpness", IFDEXIF, typesShort, 1, "Sharpness processing applied by the camera"},
+	{0xA40B, "Device Setting Description", IFDEXIF, typesUndefined, CountAny, "Picture-taking conditions of a camera model"},
+	{0xA40C, "Subject Distance Range", IFDEXIF, typesShort, 1, "Distance range to the subject"},
+	{0xA420, "Image Unique ID", IFDEXIF, typesAscii, 33, "Unique identifier of the image"},
+	{0xA430, "Camera Owner Name", IFDEXIF, typesAscii, CountAny, "Owner of the camera"},
+	{0xA431, "Body Serial Number", IFDEXIF, typesAscii, CountAny, "Serial number of the camera body"},
+	{0xA432, "Lens Specification", IFDEXIF, typesRational, 4, "Min/max focal length and min F number at each"},
+	{0xA433, "Lens Make", IFDEXIF, typesAscii, CountAny, "Manufacturer of the lens"},
+	{0xA434, "Lens Model", IFDEXIF, typesAscii, CountAny, "Model name of the lens"},
+	{0xA435, "Lens Serial Number", IFDEXIF, typesAscii, CountAny, "Serial number of the lens"},
+	{0xA436, "Image Title", IFDEXIF, typesAscii, CountAny, "Title of the image"},
+	{0xA437, "Photographer", IFDEXIF, typesAscii, CountAny, "Name of the photographer"},
+	{0xA438, "Image Editor", IFDEXIF, typesAscii, CountAny, "Name of the person who edited the image"},
+	{0xA439, "Camera Firmware", IFDEXIF, typesAscii, CountAny, "Firmware of the camera"},
+	{0xA43A, "RAW Developing Software", IFDEXIF, typesAscii, CountAny, "Software used to develop the RAW image"},
+	{0xA43B, "Image Editing Software", IFDEXIF, typesAscii, CountAny, "Software used to edit the image"},
+	{0xA43C, "Metadata Editing Software", IFDEXIF, typesAscii, CountAny, "Software used to edit the metadata"},
+	{0xA460, "Composite Image", IFDEXIF, typesShort, 1, "Whether the image is a composite"},
+	{0xA461, "Composite Image Count", IFDEXIF, typesShort, 2, "Number of source images of a composite"},
+	{0xA462, "Composite Image Exposure Times", IFDEXIF, typesUndefined, CountAny, "Exposure times of the source images"},
+	{0xA500, "Gamma", IFDEXIF, typesRational, 1, "Gamma coefficient"},
+}
+
+var ifdIntropTagList = []TagDef{
+	{0x0001, "Interop Index", IFDINTROP, typesAscii, 4, "Interoperability rule, e.g. R98"},
+	{0x0002, "Interop Version", IFDINTROP, typesUndefined, 4, "Interoperability version"},
+	{0x1000, "Related Image File Format", IFDINTROP, typesAscii, CountAny, "File format of a related image"},
+	{0x1001, "Related Image Width", IFDINTROP, typesShortLong, 1, "Width of a related image"},
+	{0x1002, "Related Image Length", IFDINTROP, typesShortLong, 1, "Height of a related image"},
+}
+
+var ifdGPSTagList = []TagDef{
+	{0x0000, "GPS Version ID", IFDGPS, typesByte, 4, "GPS IFD version"},
+	{0x0001, "GPS Latitude Ref", IFDGPS, typesAscii, 2, "N or S latitude"},
+	{0x0002, "GPS Latitude", IFDGPS, typesRational, 3, "Latitude as degrees, minutes, seconds"},
+	{0x0003, "GPS Longitude Ref", IFDGPS, typesAscii, 2, "E or W longitude"},
+	{0x0004, "GPS Longitude", IFDGPS, typesRational, 3, "Longitude as degrees, minutes, seconds"},
+	{0x0005, "GPS Altitude Ref", IFDGPS, typesByte, 1, "0 above sea level, 1 below sea level"},
+	{0x0006, "GPS Altitude", IFDGPS, typesRational, 1, "Altitude in meters"},
+	{0x0007, "GPS Time Stamp", IFDGPS, typesRational, 3, "UTC time as hours, minutes, seconds"},
+	{0x0008, "GPS Satellites", IFDGPS, typesAscii, CountAny, "Satellites used for the measurement"},
+	{0x0009, "GPS Status", IFDGPS, typesAscii, 2, "Receiver status, A or V"},
+	{0x000A, "GPS Measure Mode", IFDGPS, typesAscii, 2, "2 or 3 dimensional measurement"},
+	{0x000B, "GPS DOP", IFDGPS, typesRational, 1, "Measurement precision"},
+	{0x000C, "GPS Speed Ref", IFDGPS, typesAscii, 2, "Unit of GPS Speed, K, M or N"},
+	{0x000D, "GPS Speed", IFDGPS, typesRational, 1, "Speed of the receiver"},
+	{0x000E, "GPS Track Ref", IFDGPS, typesAscii, 2, "Reference of GPS Track, T or M"},
+	{0x000F, "GPS Track", IFDGPS, typesRational, 1, "Direction of movement in degrees"},
+	{0x0010, "GPS Img Direction Ref", IFDGPS, typesAscii, 2, "Reference of GPS Img Direction, T or M"},
+	{0x0011, "GPS Img Direction", IFDGPS, typesRational, 1, "Direction of the image in degrees"},
+	{0x0012, "GPS Map Datum", IFDGPS, typesAscii, CountAny, "Geodetic survey data used"},
+	{0x0013, "GPS Dest Latitude Ref", IFDGPS, typesAscii, 2, "N or S destination latitude"},
+	{0x0014, "GPS Dest Latitude", IFDGPS, typesRational, 3, "Destination latitude"},
+	{0x0015, "GPS Dest Longitude Ref", IFDGPS, typesAscii, 2, "E or W destination longitude"},
+	{0x0016, "GPS Dest Longitude", IFDGPS, typesRational, 3, "Destination longitude"},
+	{0x0017, "GPS Dest Bearing Ref", IFDGPS, typesAscii, 2, "Reference of GPS Dest Bearing, T or M"},
+	{0x0018, "GPS Dest Bearing", IFDGPS, typesRational, 1, "Bearing to the destination in degrees"},
+	{0x0019, "GPS Dest Distance Ref", IFDGPS, typesAscii, 2, "Unit of GPS Dest Distance, K, M or N"},
+	{0x001A, "GPS Dest Distance", IFDGPS, typesRational, 1, "Distance to the destination"},
+	{0x001B, "GPS Processing Method", IFDGPS, typesUndefined, CountAny, "Name of the positioning method"},
+	{0x001C, "GPS Area Information", IFDGPS, typesUndefined, CountAny, "Name of the GPS area"},
+	{0x001D, "GPS Date Stamp", IFDGPS, typesAscii, 11, "UTC date as YYYY:MM:DD"},
+	{0x001E, "GPS Differential", IFDGPS, typesShort, 1, "Whether differential correction was applied"},
+	{0x001F, "GPS H Positioning Error", IFDGPS, typesRational, 1, "Horizontal positioning error in meters"},
 }
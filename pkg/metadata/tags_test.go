@@ -0,0 +1,76 @@
+package metadata
+
+import "testing"
+
+func TestGetNameFromID(t *testing.T) {
+	tests := []struct {
+		ifdType IFDtype
+		id      uint16
+		want    string
+	}{
+		{IFDMAIN, 0x010F, "Make"},
+		{IFDEXIF, 0x829A, "Exposure Time"},
+		// the same ID means something else in every IFD
+		{IFDGPS, 0x0001, "GPS Latitude Ref"},
+		{IFDINTROP, 0x0001, "Interop Index"},
+		{IFDPANASONIC, 0x0002, "Sensor Width"},
+		{IFDMPF, 0xB000, "MPF Version"},
+		{IFDMAIN, 0x829A, "Unknown_0x829A"},
+		{IFDEXIF, 0xFFFF, "Unknown_0xFFFF"},
+		{IFDtype("nope"), 0x010F, "Unknown_0x010F"},
+	}
+	for _, tt := range tests {
+		if got := GetNameFromID(tt.ifdType, tt.id); got != tt.want {
+			t.Errorf("GetNameFromID(%s, 0x%04X) = %q, want %q", tt.ifdType, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestTagDictionaries(t *testing.T) {
+	for ifdType, list := range tagLists {
+		seen := map[uint16]bool{}
+		for _, def := range list {
+			if seen[def.ID] {
+				t.Errorf("%s: tag 0x%04X defined twice", ifdType, def.ID)
+			}
+			seen[def.ID] = true
+			if def.IFD != ifdType {
+				t.Errorf("%s: tag 0x%04X %s is filed under %s", ifdType, def.ID, def.Name, def.IFD)
+			}
+			if def.Name == "" {
+				t.Errorf("%s: tag 0x%04X has no name", ifdType, def.ID)
+			}
+		}
+		defs := TagDefs(ifdType)
+		for i := 1; i < len(defs); i++ {
+			if defs[i-1].ID >= defs[i].ID {
+				t.Errorf("%s: TagDefs not ordered at 0x%04X", ifdType, defs[i].ID)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	exposureTime, _ := LookupTag(IFDEXIF, 0x829A)
+	maker, _ := LookupTag(IFDMAIN, 0x010F)
+	width, _ := LookupTag(IFDMAIN, 0x0100)
+
+	tests := []struct {
+		name    string
+		def     TagDef
+		tag     IFDtag
+		wantErr bool
+	}{
+		{"expected type and count", exposureTime, IFDtag{DataType: TypeRational, DataCount: 1}, false},
+		{"wrong type", exposureTime, IFDtag{DataType: TypeShort, DataCount: 1}, true},
+		{"wrong count", exposureTime, IFDtag{DataType: TypeRational, DataCount: 2}, true},
+		{"any count", maker, IFDtag{DataType: TypeAscii, DataCount: 42}, false},
+		{"SHORT or LONG", width, IFDtag{DataType: TypeLong, DataCount: 1}, false},
+		{"BigTIFF LONG8 for LONG", width, IFDtag{DataType: TypeLong8, DataCount: 1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.def.Validate(tt.tag); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
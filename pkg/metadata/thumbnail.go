@@ -0,0 +1,138 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// tags locating a JPEG stream in an IFD
+const (
+	tagImageWidth  = 0x0100
+	tagImageLength = 0x0101
+	tagJPEGOffset  = 0x0201 // JPEG Interchange Format
+	tagJPEGLength  = 0x0202 // JPEG Interchange Format Length
+)
+
+// Thumbnails returns every embedded JPEG preview: the JPEG streams referenced
+// by the IFDs and SubIFDs (the IFD1 thumbnail of Exif), the JFXX thumbnail,
+// the Photoshop thumbnail, the previews of RAW containers and the MPF images
+// after the primary image. Width and Height are 0 when the container does not
+// record them, JPEGSize reads them from the stream.
+func (d ImageData) Thumbnails() []Preview {
+	var thumbs []Preview
+	for _, group := range []struct {
+		name string
+		ifds []IFD
+	}{{"IFD", d.MetaData.IFDs}, {"SubIFD", d.MetaData.SubIFDs}} {
+		for _, ifd := range group.ifds {
+			if thumb, ok := ifdThumbnail(ifd); ok {
+				thumb.Source = fmt.Sprintf("%s%d", group.name, ifd.Index)
+				thumbs = append(thumbs, thumb)
+			}
+		}
+	}
+	if jfxx := d.JFXX; jfxx != nil && jfxx.Format == JFXXJPEG && len(jfxx.Data) > 0 {
+		thumb := Preview{Source: "JFXX", Offset: jfxx.Offset, Length: int64(len(jfxx.Data))}
+		thumb.Width, thumb.Height, _ = JPEGSize(jfxx.Data)
+		thumbs = append(thumbs, thumb)
+	}
+	if ps := d.Photoshop; ps != nil && ps.Thumbnail != nil && ps.Thumbnail.Format == 1 && ps.Thumbnail.Offset > 0 {
+		thumb := ps.Thumbnail
+		thumbs = append(thumbs, Preview{
+			Source: "Photoshop", Width: thumb.Width, Height: thumb.Height,
+			Offset: thumb.Offset, Length: int64(len(thumb.Data)),
+		})
+	}
+	thumbs = append(thumbs, d.Previews...)
+	if d.MPF != nil {
+		for i, image := range d.MPF.Images {
+			// the first entry is the primary image, i.e. the file itself
+			if i == 0 || image.Format != 0 {
+				continue
+			}
+			thumbs = append(thumbs, Preview{Source: fmt.Sprintf("MPF %d", i+1), Offset: image.Offset, Length: image.Length})
+		}
+	}
+	return thumbs
+}
+
+// ifdThumbnail returns the JPEG stream an IFD points to with tags 0x0201 and 0x0202.
+func ifdThumbnail(ifd IFD) (Preview, bool) {
+	var thumb Preview
+	var offset, length uint64
+	for _, tag := range ifd.Tags {
+		switch tag.ID {
+		case tagJPEGOffset:
+			offset = firstUint(tag)
+		case tagJPEGLength:
+			length = firstUint(tag)
+		case tagImageWidth:
+			thumb.Width = uint32(firstUint(tag))
+		case tagImageLength:
+			thumb.Height = uint32(firstUint(tag))
+		}
+	}
+	if offset == 0 || length == 0 {
+		return Preview{}, false
+	}
+	thumb.Offset = ifd.Base + int64(offset)
+	thumb.Length = int64(length)
+	return thumb, true
+}
+
+// firstUint returns the first value of a SHORT, LONG or LONG8 tag.
+func firstUint(tag IFDtag) uint64 {
+	switch v := tag.Data.(type) {
+	case []uint16:
+		if len(v) > 0 {
+			return uint64(v[0])
+		}
+	case []uint32:
+		if len(v) > 0 {
+			return uint64(v[0])
+		}
+	case []uint64:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return 0
+}
+
+// JPEGSize returns the image size recorded in the SOFn segment of a JPEG stream.
+func JPEGSize(data []byte) (width, height uint32, ok bool) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, 0, false
+	}
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 0, 0, false
+		}
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF:
+			// fill byte
+			pos++
+			continue
+		case marker >= 0xD0 && marker <= 0xD7, marker == 0x01:
+			// RSTn and TEM carry no length
+			pos += 2
+			continue
+		case marker == 0xD9, marker == 0xDA:
+			// EOI or SOS before any SOF
+			return 0, 0, false
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		// SOF0-SOF15 except DHT (C4), JPG (C8) and DAC (CC)
+		if marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC {
+			if pos+9 > len(data) {
+				return 0, 0, false
+			}
+			height := binary.BigEndian.Uint16(data[pos+5:])
+			width := binary.BigEndian.Uint16(data[pos+7:])
+			return uint32(width), uint32(height), true
+		}
+		pos += 2 + length
+	}
+	return 0, 0, false
+}
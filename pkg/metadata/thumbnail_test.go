@@ -0,0 +1,109 @@
+package metadata
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// sof0 is a JPEG stream of a 160x120 frame.
+var sof0 = []byte{
+	0xFF, 0xD8,
+	0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00, // APP0 with an empty payload
+	0xFF, 0xC0, 0x00, 0x0B, 0x08, 0x00, 0x78, 0x00, 0xA0, 0x01, 0x01, 0x11, 0x00,
+	0xFF, 0xD9,
+}
+
+func TestThumbnails(t *testing.T) {
+	imgData := ImageData{
+		MetaData: MetaData{
+			IFDs: []IFD{
+				{Index: 0, Base: 12, Tags: []IFDtag{{ID: tagImageWidth, Data: []uint32{6000}}}},
+				{Index: 1, Base: 12, Tags: []IFDtag{
+					{ID: tagJPEGOffset, Data: []uint32{500}},
+					{ID: tagJPEGLength, Data: []uint32{2000}},
+				}},
+			},
+			SubIFDs: []IFD{
+				// a DNG preview records its size, a BigTIFF one uses LONG8
+				{Index: 0, Base: 0, Tags: []IFDtag{
+					{ID: tagImageWidth, Data: []uint16{1024}},
+					{ID: tagImageLength, Data: []uint16{768}},
+					{ID: tagJPEGOffset, Data: []uint64{10000}},
+					{ID: tagJPEGLength, Data: []uint64{30000}},
+				}},
+				// an offset without a length is no thumbnail
+				{Index: 1, Tags: []IFDtag{{ID: tagJPEGOffset, Data: []uint32{100}}}},
+			},
+		},
+		JFXX:      &JFXX{Format: JFXXJPEG, Data: sof0, Offset: 40},
+		Photoshop: &Photoshop{Thumbnail: &PhotoshopThumbnail{Format: 1, Width: 256, Height: 192, Offset: 3000, Data: make([]byte, 50)}},
+		Previews:  []Preview{{Source: "CR3 PRVW", Width: 1620, Height: 1080, Offset: 70000, Length: 9000}},
+		MPF: &MPF{Images: []MPFImage{
+			{Offset: 0, Length: 80000},
+			{Format: 0, Offset: 80000, Length: 5000},
+			{Format: 3, Offset: 85000, Length: 100}, // not a JPEG stream
+		}},
+	}
+	want := []Preview{
+		{Source: "IFD1", Offset: 512, Length: 2000},
+		{Source: "SubIFD0", Width: 1024, Height: 768, Offset: 10000, Length: 30000},
+		{Source: "JFXX", Width: 160, Height: 120, Offset: 40, Length: int64(len(sof0))},
+		{Source: "Photoshop", Width: 256, Height: 192, Offset: 3000, Length: 50},
+		{Source: "CR3 PRVW", Width: 1620, Height: 1080, Offset: 70000, Length: 9000},
+		{Source: "MPF 2", Offset: 80000, Length: 5000},
+	}
+	if got := imgData.Thumbnails(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Thumbnails() = %+v\nwant %+v", got, want)
+	}
+	if got := (ImageData{}).Thumbnails(); got != nil {
+		t.Errorf("Thumbnails() of an empty image = %+v, want nil", got)
+	}
+}
+
+func TestJPEGSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		width, height uint32
+		ok            bool
+	}{
+		{name: "SOF0", data: sof0, width: 160, height: 120, ok: true},
+		{name: "fill bytes", data: append([]byte{0xFF, 0xD8, 0xFF, 0xFF}, sof0[8:]...), width: 160, height: 120, ok: true},
+		{name: "progressive", data: append([]byte{0xFF, 0xD8, 0xFF, 0xC2}, sof0[10:]...), width: 160, height: 120, ok: true},
+		{name: "DHT is no frame", data: append([]byte{0xFF, 0xD8, 0xFF, 0xC4, 0x00, 0x02}, sof0[8:]...), width: 160, height: 120, ok: true},
+		{name: "SOS before SOF", data: []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}},
+		{name: "no SOI", data: sof0[2:]},
+		{name: "SOF cut short", data: sof0[:14]},
+		{name: "garbage between segments", data: []byte{0xFF, 0xD8, 0x00, 0x00, 0x00, 0x00}},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, ok := JPEGSize(tt.data)
+			if width != tt.width || height != tt.height || ok != tt.ok {
+				t.Errorf("JPEGSize() = %d, %d, %v, want %d, %d, %v", width, height, ok, tt.width, tt.height, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPreviewRead(t *testing.T) {
+	r := bytes.NewReader([]byte("0123456789"))
+	tests := []struct {
+		preview Preview
+		want    string
+		wantErr bool
+	}{
+		{preview: Preview{Offset: 2, Length: 3}, want: "234"},
+		{preview: Preview{Offset: 8, Length: 4}, wantErr: true},
+		{preview: Preview{Offset: 0, Length: -1}, wantErr: true},
+		{preview: Preview{Offset: 0, Length: maxPreviewSize + 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.preview.Read(r)
+		if (err != nil) != tt.wantErr || string(got) != tt.want {
+			t.Errorf("%+v: Read() = %q, %v, want %q, error %v", tt.preview, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -4,11 +4,34 @@ import (
 	"encoding/binary"
 	"fmt"
 	"strings"
+
+	"github.com/justikun/metadata-viewer/pkg/xmp"
 )
 
 type ImageData struct {
 	ImagePath string
+	Format    Format
 	MetaData  MetaData
+	JFIF      *JFIF // APP0 JFIF header, nil when absent
+	JFXX      *JFXX // APP0 JFIF extension thumbnail, nil when absent
+	XMP       *xmp.Packet
+	PNG       *PNG        // PNG header and ancillary chunks, nil for other formats
+	WebP      *WebP       // WebP canvas and features, nil for other formats
+	HEIF      *HEIF       // HEIF/AVIF brands and items, nil for other formats
+	RAF       *RAF        // Fujifilm RAF header and CFA records, nil for other formats
+	MPF       *MPF        // APP2 Multi-Picture Format index, nil when absent
+	ICC       *ICCProfile // decoded ICC profile of JPEG APP2, PNG iCCP or WebP ICCP, nil when absent
+	IPTC      *IPTC       // IPTC-IIM datasets of APP13 or TIFF tag 0x83BB, nil when absent
+	Photoshop *Photoshop  // image resource blocks of APP13 or TIFF tag 0x8649, nil when absent
+	Previews  []Preview   // JPEG previews embedded in RAW files
+	// Warnings holds the problems that did not stop the decode, such as a
+	// corrupt ICC profile in a JPEG whose Exif was read.
+	Warnings []error
+}
+
+// AddWarning records a problem that the decoder stepped over.
+func (d *ImageData) AddWarning(err error) {
+	d.Warnings = append(d.Warnings, err)
 }
 
 type MetaData struct {
@@ -16,6 +39,54 @@ type MetaData struct {
 	ExifTags   []IFDtag
 	IntropTags []IFDtag
 	GPStags    []IFDtag
+	// MakerNoteTags holds the Canon MakerNote IFD of CR3 files.
+	MakerNoteTags []IFDtag
+	// MPFTags holds the MP Index IFD of a JPEG with APP2 MPF.
+	MPFTags []IFDtag
+	// IFDs holds IFD0, IFD1 (thumbnail) and any further pages in chain order.
+	IFDs []IFD
+	// SubIFDs holds the child IFDs listed by tag 0x014A, e.g. the full size
+	// image of a DNG, in the order they were found.
+	SubIFDs []IFD
+}
+
+// IFD is one directory of the TIFF IFD chain.
+type IFD struct {
+	Index  int     // position in the chain, 0 is IFD0
+	Type   IFDtype // dictionary the tags belong to
+	Offset int64   // absolute offset of the directory
+	Base   int64   // absolute offset of the TIFF header, the offsets held in tags are relative to it
+	Tags   []IFDtag
+}
+
+// TagGroup is one IFD worth of tags with its display name
+// ("IFD0", "IFD1", ..., "SubIFD0", ..., "Exif", "GPS", "Interop", "MakerNote", "MPF").
+type TagGroup struct {
+	Name string
+	Type IFDtype // dictionary the tags are defined in
+	Tags []IFDtag
+}
+
+// TagGroups returns the IFD chain followed by the sub-IFDs.
+func (d ImageData) TagGroups() []TagGroup {
+	var groups []TagGroup
+	if len(d.MetaData.IFDs) == 0 && len(d.MetaData.MainTags) > 0 {
+		groups = append(groups, TagGroup{"IFD0", IFDMAIN, d.MetaData.MainTags})
+	}
+	for _, ifd := range d.MetaData.IFDs {
+		groups = append(groups, TagGroup{fmt.Sprintf("IFD%d", ifd.Index), IFDMAIN, ifd.Tags})
+	}
+	for _, ifd := range d.MetaData.SubIFDs {
+		groups = append(groups, TagGroup{fmt.Sprintf("SubIFD%d", ifd.Index), IFDMAIN, ifd.Tags})
+	}
+	groups = append(groups,
+		TagGroup{"Exif", IFDEXIF, d.MetaData.ExifTags},
+		TagGroup{"GPS", IFDGPS, d.MetaData.GPStags},
+		TagGroup{"Interop", IFDINTROP, d.MetaData.IntropTags},
+		TagGroup{"MakerNote", IFDCANON, d.MetaData.MakerNoteTags},
+		TagGroup{"MPF", IFDMPF, d.MetaData.MPFTags},
+	)
+	return groups
 }
 
 type IFDtag struct {
@@ -24,6 +95,7 @@ type IFDtag struct {
 	DataType  DataType
 	DataCount uint32
 	Data      any
+	Offset    int64 // absolute offset of the data, 0 when it is stored in the entry
 }
 
 type Rational struct {
@@ -43,6 +115,13 @@ const (
 	IFDEXIF   IFDtype = "exif"
 	IFDINTROP IFDtype = "introp"
 	IFDGPS    IFDtype = "gps"
+	// IFDPANASONIC is IFD0 of a Panasonic RW2 file, which redefines tag IDs
+	// below 0x0120.
+	IFDPANASONIC IFDtype = "panasonic"
+	// IFDCANON is the Canon MakerNote IFD.
+	IFDCANON IFDtype = "canon"
+	// IFDMPF is the MP Index IFD of the APP2 Multi-Picture Format segment.
+	IFDMPF IFDtype = "mpf"
 )
 
 type DataType uint16
@@ -146,6 +225,10 @@ const (
 	TypeSRational DataType = 10 // Two SLONGs (signed numerator, denominator)
 	TypeFloat     DataType = 11 // 32-bit IEEE floating point
 	TypeDouble    DataType = 12 // 64-bit IEEE floating point
+	TypeIFD       DataType = 13 // Unsigned 32-bit IFD offset
+	TypeLong8     DataType = 16 // Unsigned 64-bit integer (BigTIFF)
+	TypeSLong8    DataType = 17 // Signed 64-bit integer (BigTIFF)
+	TypeIFD8      DataType = 18 // Unsigned 64-bit IFD offset (BigTIFF)
 )
 
 func GetDataTypeString(b []byte, byteOrder binary.ByteOrder) (string, error) {
@@ -176,6 +259,14 @@ func GetDataTypeString(b []byte, byteOrder binary.ByteOrder) (string, error) {
 		return "Float", nil
 	case TypeDouble:
 		return "Double", nil
+	case TypeIFD:
+		return "IFD", nil
+	case TypeLong8:
+		return "Long8", nil
+	case TypeSLong8:
+		return "SLong8", nil
+	case TypeIFD8:
+		return "IFD8", nil
 	default:
 		return "", fmt.Errorf("Failed to get data type string. Unknown data type value: %d", dataValue)
 	}
@@ -209,6 +300,14 @@ func GetDataType(b []byte, byteOrder binary.ByteOrder) (DataType, error) {
 		return TypeFloat, nil
 	case TypeDouble:
 		return TypeDouble, nil
+	case TypeIFD:
+		return TypeIFD, nil
+	case TypeLong8:
+		return TypeLong8, nil
+	case TypeSLong8:
+		return TypeSLong8, nil
+	case TypeIFD8:
+		return TypeIFD8, nil
 	default:
 		return 0, fmt.Errorf("Failed to GetDataTypeBytes. Unknown data type value: %d", dataValue)
 	}
@@ -227,6 +326,10 @@ var typeSize = map[DataType]int{
 	TypeSRational: 8, // Two SLong type. numerator(4 bytes), denominator(4 bytes)
 	TypeFloat:     4, // 32 bit IEEE floating point (4 bytes)
 	TypeDouble:    8, // 64 bit IEEE floating point (8 bytes)
+	TypeIFD:       4, // 32 bit IFD offset (4 bytes)
+	TypeLong8:     8, // Unsigned 64-bit int (8 bytes)
+	TypeSLong8:    8, // 64 bit signed int (8 bytes)
+	TypeIFD8:      8, // 64 bit IFD offset (8 bytes)
 }
 
 func (dt DataType) String() string {
@@ -255,6 +358,14 @@ func (dt DataType) String() string {
 		return "FLOAT"
 	case TypeDouble:
 		return "DOUBLE"
+	case TypeIFD:
+		return "IFD"
+	case TypeLong8:
+		return "LONG8"
+	case TypeSLong8:
+		return "SLONG8"
+	case TypeIFD8:
+		return "IFD8"
 	default:
 		return ""
 	}
@@ -266,9 +377,9 @@ func (dt DataType) ByteSize() (int, error) {
 		return 1, nil
 	case TypeShort, TypeSShort:
 		return 2, nil
-	case TypeLong, TypeSLong, TypeFloat:
+	case TypeLong, TypeSLong, TypeFloat, TypeIFD:
 		return 4, nil
-	case TypeRational, TypeSRational, TypeDouble:
+	case TypeRational, TypeSRational, TypeDouble, TypeLong8, TypeSLong8, TypeIFD8:
 		return 8, nil
 	default:
 		return 0, fmt.Errorf("Unkown or invalid datatype for size calculation: %d", dt)
@@ -0,0 +1,21 @@
+package metadata
+
+// WebP describes the image held in a WebP RIFF container.
+type WebP struct {
+	Extended   bool         `json:"extended"` // a VP8X chunk is present
+	Width      uint32       `json:"width"`    // canvas size, or the bitstream size of a simple file
+	Height     uint32       `json:"height"`
+	Features   WebPFeatures `json:"features"`            // VP8X feature flags
+	Frames     int          `json:"frames,omitempty"`    // ANMF chunks of an animation
+	LoopCount  *uint16      `json:"loopCount,omitempty"` // ANIM loop count, 0 loops forever
+	ICCProfile []byte       `json:"-"`                   // ICCP chunk
+}
+
+// WebPFeatures are the feature flags of the VP8X chunk.
+type WebPFeatures struct {
+	ICC       bool `json:"icc"`
+	Alpha     bool `json:"alpha"`
+	EXIF      bool `json:"exif"`
+	XMP       bool `json:"xmp"`
+	Animation bool `json:"animation"`
+}
@@ -0,0 +1,184 @@
+package photoshop
+
+import (
+	"cmp"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/justikun/metadata-viewer/pkg/iptc"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+// thumbnailHeaderSize is the size of the header preceding the thumbnail
+// stream: format, width, height, widthbytes, total size, compressed size,
+// bits per pixel and planes.
+const thumbnailHeaderSize = 28
+
+var resourceNames = map[uint16]string{
+	0x03E9: "Macintosh Print Info",
+	0x03ED: "Resolution Info",
+	0x03EE: "Alpha Channel Names",
+	0x03F0: "Caption",
+	0x03F1: "Border Information",
+	0x03F2: "Background Color",
+	0x03F3: "Print Flags",
+	0x03F5: "Color Halftoning Info",
+	0x03F8: "Color Transfer Functions",
+	0x0400: "Layer State Information",
+	0x0402: "Layers Group Information",
+	0x0404: "IPTC-NAA",
+	0x0406: "JPEG Quality",
+	0x0408: "Grid And Guides Information",
+	0x0409: "Photoshop 4 Thumbnail",
+	0x040A: "Copyright Flag",
+	0x040B: "URL",
+	0x040C: "Thumbnail",
+	0x040D: "Global Angle",
+	0x0411: "ICC Untagged Profile",
+	0x0414: "Document Specific IDs",
+	0x0419: "Global Altitude",
+	0x041A: "Slices",
+	0x041D: "Alpha Identifiers",
+	0x041E: "URL List",
+	0x0421: "Version Info",
+	0x0422: "Exif Data 1",
+	0x0423: "Exif Data 3",
+	0x0424: "XMP Metadata",
+	0x0425: "IPTC Digest",
+	0x0426: "Print Scale",
+	0x0428: "Pixel Aspect Ratio",
+	0x0429: "Layer Comps",
+	0x042D: "Layer Selection IDs",
+	0x0430: "Layer Groups Enabled ID",
+	0x0436: "Onion Skins",
+	0x0438: "Count Information",
+	0x043A: "Print Information",
+	0x043B: "Print Style",
+	0x0BB7: "Clipping Path Name",
+	0x0FA0: "Plug-In Resource",
+	0x2710: "Print Flags Information",
+}
+
+// resourceName returns the well-known name of a resource, "" for unknown IDs.
+func resourceName(id uint16) string {
+	if id >= 0x07D0 && id <= 0x0BB6 {
+		return "Path Information"
+	}
+	return resourceNames[id]
+}
+
+// Decode reads the image resource blocks in data, which starts at the
+// absolute offset in the file, 0 when that is unknown. The IPTC-IIM resource
+// is parsed into imgData.IPTC unless TIFF tag 0x83BB has already set it; a
+// malformed one is recorded as a warning. Resources decoded earlier, e.g. from
+// a previous APP13 segment, are kept and the new ones appended.
+func Decode(data []byte, offset int64, imgData *metadata.ImageData) error {
+	resources, err := ParseResources(data)
+	if err != nil {
+		return err
+	}
+	ps := &metadata.Photoshop{}
+	for _, resource := range resources {
+		ps.Resources = append(ps.Resources, metadata.PhotoshopResource{
+			ID:        resource.ID,
+			Name:      resourceName(resource.ID),
+			BlockName: resource.Name,
+			Size:      len(resource.Data),
+			Data:      resource.Data,
+		})
+		b := resource.Data
+		switch resource.ID {
+		case ResolutionResource:
+			if len(b) >= 16 {
+				ps.Resolution = &metadata.PhotoshopResolution{
+					XResolution: fixed(b[0:4]),
+					XUnit:       binary.BigEndian.Uint16(b[4:]),
+					WidthUnit:   binary.BigEndian.Uint16(b[6:]),
+					YResolution: fixed(b[8:12]),
+					YUnit:       binary.BigEndian.Uint16(b[12:]),
+					HeightUnit:  binary.BigEndian.Uint16(b[14:]),
+				}
+			}
+		case JPEGQualityResource:
+			if len(b) >= 6 {
+				ps.JPEGQuality = jpegQuality(b)
+			}
+		case ThumbnailResource:
+			if len(b) >= thumbnailHeaderSize {
+				thumb := &metadata.PhotoshopThumbnail{
+					Format: binary.BigEndian.Uint32(b[0:]),
+					Width:  binary.BigEndian.Uint32(b[4:]),
+					Height: binary.BigEndian.Uint32(b[8:]),
+					Data:   b[thumbnailHeaderSize:],
+				}
+				if offset > 0 {
+					thumb.Offset = offset + int64(resource.Offset+thumbnailHeaderSize)
+				}
+				ps.Thumbnail = thumb
+			}
+		case CopyrightResource:
+			if len(b) >= 1 {
+				copyrighted := b[0] != 0
+				ps.Copyrighted = &copyrighted
+			}
+		case URLResource:
+			ps.URL = string(b)
+		case IPTCDigestResource:
+			ps.IPTCDigest = hex.EncodeToString(b)
+		case IPTCResource:
+			if imgData.IPTC != nil {
+				continue
+			}
+			iptcData, err := iptc.Parse(b)
+			if err != nil {
+				// the other resources are still good
+				imgData.AddWarning(fmt.Errorf("IPTC resource: %w", err))
+				continue
+			}
+			imgData.IPTC = iptcData
+		}
+	}
+	if imgData.Photoshop == nil {
+		imgData.Photoshop = ps
+	} else {
+		// writers split large resource sections over several APP13 segments
+		merge(imgData.Photoshop, ps)
+	}
+	return nil
+}
+
+// merge appends the resources of next to ps. The decoded fields already set in
+// ps win, like the IPTC datasets.
+func merge(ps, next *metadata.Photoshop) {
+	ps.Resources = append(ps.Resources, next.Resources...)
+	ps.Resolution = cmp.Or(ps.Resolution, next.Resolution)
+	ps.JPEGQuality = cmp.Or(ps.JPEGQuality, next.JPEGQuality)
+	ps.Thumbnail = cmp.Or(ps.Thumbnail, next.Thumbnail)
+	ps.Copyrighted = cmp.Or(ps.Copyrighted, next.Copyrighted)
+	ps.URL = cmp.Or(ps.URL, next.URL)
+	ps.IPTCDigest = cmp.Or(ps.IPTCDigest, next.IPTCDigest)
+}
+
+// fixed decodes an unsigned 16.16 fixed point number.
+func fixed(b []byte) float64 {
+	return float64(binary.BigEndian.Uint32(b)) / 65536
+}
+
+// jpegQuality decodes the three 16 bit values of the JPEG Quality resource:
+// the quality stored as -4 to 8, the format and the progressive scan count.
+func jpegQuality(b []byte) *metadata.PhotoshopJPEGQuality {
+	q := &metadata.PhotoshopJPEGQuality{Quality: int(int16(binary.BigEndian.Uint16(b[0:]))) + 4}
+	switch format := binary.BigEndian.Uint16(b[2:]); format {
+	case 0x0000:
+		q.Format = "Standard"
+	case 0x0001:
+		q.Format = "Optimized"
+	case 0x0101:
+		q.Format = "Progressive"
+		q.Scans = int(binary.BigEndian.Uint16(b[4:])) + 2
+	default:
+		q.Format = fmt.Sprintf("Unknown 0x%04X", format)
+	}
+	return q
+}
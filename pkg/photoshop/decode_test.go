@@ -0,0 +1,145 @@
+package photoshop
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+func TestDecodeIPTC(t *testing.T) {
+	iptc := fixture.Concat(fixture.Dataset(2, 25, "lake"), fixture.Dataset(2, 25, "sunset"))
+	url := fixture.Resource(URLResource, "", []byte("https://example.com"))
+
+	tests := []struct {
+		name         string
+		data         []byte
+		preset       *metadata.IPTC // set by TIFF tag 0x83BB before the resources are read
+		wantKeywords int
+		wantWarnings int
+	}{
+		{name: "IPTC resource", data: fixture.Concat(fixture.Resource(IPTCResource, "", iptc), url), wantKeywords: 2},
+		{name: "broken IPTC resource", data: fixture.Concat(fixture.Resource(IPTCResource, "", []byte{0x1C, 2}), url), wantWarnings: 1},
+		{
+			name:         "IPTC tag wins",
+			data:         fixture.Concat(fixture.Resource(IPTCResource, "", iptc), url),
+			preset:       &metadata.IPTC{Datasets: []metadata.IPTCDataset{{Record: 2, ID: 25, Values: []string{"tag"}}}},
+			wantKeywords: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := &metadata.ImageData{IPTC: tt.preset}
+			if err := Decode(tt.data, 0, imgData); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			keywords := 0
+			if imgData.IPTC != nil {
+				dataset, _ := imgData.IPTC.Get(2, 25)
+				keywords = len(dataset.Values)
+			}
+			if keywords != tt.wantKeywords {
+				t.Errorf("got %d keywords, want %d", keywords, tt.wantKeywords)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+			// the other resources survive a broken IPTC resource
+			if ps := imgData.Photoshop; ps == nil || len(ps.Resources) != 2 || ps.URL != "https://example.com" {
+				t.Errorf("Photoshop = %+v, want both resources", ps)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	jfif := []byte{0xFF, 0xD8, 0xFF, 0xD9}
+	thumbnail := fixture.Concat(fixture.BE32(1), fixture.BE32(160), fixture.BE32(120), make([]byte, 16), jfif)
+	resolution := fixture.Concat(fixture.BE32(300<<16), fixture.BE16(1), fixture.BE16(1), fixture.BE32(150<<16|0x8000), fixture.BE16(2), fixture.BE16(2))
+	data := fixture.Concat(
+		fixture.Resource(ResolutionResource, "", resolution),
+		fixture.Resource(JPEGQualityResource, "", fixture.Concat(fixture.BE16(0xFFFF), fixture.BE16(0x0101), fixture.BE16(1))),
+		fixture.Resource(ThumbnailResource, "", thumbnail),
+		fixture.Resource(CopyrightResource, "", []byte{1}),
+		fixture.Resource(URLResource, "", []byte("https://example.com")),
+		fixture.Resource(IPTCDigestResource, "", []byte{0xDE, 0xAD, 0xBE, 0xEF}),
+		fixture.Resource(0x07D5, "Work Path", []byte{0}),
+		fixture.Resource(0x1234, "", []byte{0}),
+	)
+	// the resources start after a 100 byte prefix in the file
+	imgData := &metadata.ImageData{}
+	if err := Decode(data, 100, imgData); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	ps := imgData.Photoshop
+
+	wantResolution := &metadata.PhotoshopResolution{XResolution: 300, XUnit: 1, WidthUnit: 1, YResolution: 150.5, YUnit: 2, HeightUnit: 2}
+	if !reflect.DeepEqual(ps.Resolution, wantResolution) {
+		t.Errorf("Resolution = %+v, want %+v", ps.Resolution, wantResolution)
+	}
+	if want := (&metadata.PhotoshopJPEGQuality{Quality: 3, Format: "Progressive", Scans: 3}); !reflect.DeepEqual(ps.JPEGQuality, want) {
+		t.Errorf("JPEGQuality = %+v, want %+v", ps.JPEGQuality, want)
+	}
+	// the resolution and JPEG quality blocks take 28 and 18 bytes, the
+	// thumbnail header starts at byte 12 of the third block
+	thumbOffset := int64(100 + 28 + 18 + 12 + thumbnailHeaderSize)
+	if thumb := ps.Thumbnail; thumb == nil || thumb.Width != 160 || thumb.Height != 120 || thumb.Offset != thumbOffset || !bytes.Equal(thumb.Data, jfif) {
+		t.Errorf("Thumbnail = %+v, want 160x120 at %d", thumb, thumbOffset)
+	}
+	if ps.Copyrighted == nil || !*ps.Copyrighted || ps.URL != "https://example.com" || ps.IPTCDigest != "deadbeef" {
+		t.Errorf("Copyrighted, URL, IPTCDigest = %v, %q, %q", ps.Copyrighted, ps.URL, ps.IPTCDigest)
+	}
+
+	var names []string
+	for _, resource := range ps.Resources {
+		names = append(names, resource.Name)
+	}
+	wantNames := []string{"Resolution Info", "JPEG Quality", "Thumbnail", "Copyright Flag", "URL", "IPTC Digest", "Path Information", ""}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("resource names = %q, want %q", names, wantNames)
+	}
+	if path := ps.Resources[6]; path.BlockName != "Work Path" || path.Size != 1 {
+		t.Errorf("path resource = %+v, want block name Work Path", path)
+	}
+}
+
+func TestDecodeShortResources(t *testing.T) {
+	// well-known resources too short to decode are listed but not interpreted
+	data := fixture.Concat(
+		fixture.Resource(ResolutionResource, "", make([]byte, 8)),
+		fixture.Resource(JPEGQualityResource, "", make([]byte, 2)),
+		fixture.Resource(ThumbnailResource, "", make([]byte, 10)),
+		fixture.Resource(CopyrightResource, "", nil),
+	)
+	imgData := &metadata.ImageData{}
+	if err := Decode(data, 0, imgData); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	ps := imgData.Photoshop
+	if len(ps.Resources) != 4 || ps.Resolution != nil || ps.JPEGQuality != nil || ps.Thumbnail != nil || ps.Copyrighted != nil {
+		t.Errorf("Photoshop = %+v, want 4 resources and no decoded fields", ps)
+	}
+
+	if err := Decode([]byte("8BIM\x03"), 0, &metadata.ImageData{}); err == nil {
+		t.Error("Decode() of a broken block succeeded")
+	}
+}
+
+func TestJPEGQuality(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want metadata.PhotoshopJPEGQuality
+	}{
+		{fixture.Concat(fixture.BE16(0xFFFC), fixture.BE16(0x0000), fixture.BE16(0)), metadata.PhotoshopJPEGQuality{Quality: 0, Format: "Standard"}},
+		{fixture.Concat(fixture.BE16(8), fixture.BE16(0x0001), fixture.BE16(0)), metadata.PhotoshopJPEGQuality{Quality: 12, Format: "Optimized"}},
+		{fixture.Concat(fixture.BE16(4), fixture.BE16(0x0101), fixture.BE16(3)), metadata.PhotoshopJPEGQuality{Quality: 8, Format: "Progressive", Scans: 5}},
+		{fixture.Concat(fixture.BE16(0), fixture.BE16(0x0202), fixture.BE16(0)), metadata.PhotoshopJPEGQuality{Quality: 4, Format: "Unknown 0x0202"}},
+	}
+	for _, tt := range tests {
+		if got := jpegQuality(tt.data); *got != tt.want {
+			t.Errorf("jpegQuality(% x) = %+v, want %+v", tt.data, *got, tt.want)
+		}
+	}
+}
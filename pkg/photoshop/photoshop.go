@@ -0,0 +1,77 @@
+package photoshop
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Identifier starts the APP13 payload that holds image resources.
+const Identifier = "Photoshop 3.0\x00"
+
+// resource IDs
+const (
+	ResolutionResource  = 0x03ED // ResolutionInfo structure
+	IPTCResource        = 0x0404 // IPTC-IIM datasets
+	JPEGQualityResource = 0x0406 // Save As JPEG options
+	CopyrightResource   = 0x040A // copyright flag
+	URLResource         = 0x040B // URL of the image
+	ThumbnailResource   = 0x040C // thumbnail header and JFIF stream
+	IPTCDigestResource  = 0x0425 // MD5 of the IPTC-IIM resource
+)
+
+// Resource is one image resource block.
+type Resource struct {
+	ID     uint16
+	Name   string
+	Data   []byte
+	Offset int // offset of Data in the parsed block
+}
+
+// ParseResources splits data into image resource blocks. Each block is the
+// "8BIM" signature, a resource ID, a Pascal string name padded to an even
+// length, a 32 bit data size and the data padded to an even length.
+func ParseResources(data []byte) ([]Resource, error) {
+	var resources []Resource
+	for pos := 0; pos+4 <= len(data); {
+		signature := string(data[pos : pos+4])
+		if signature != "8BIM" {
+			if isPadding(data[pos:]) {
+				break
+			}
+			return resources, fmt.Errorf("unexpected resource signature %q at %d", signature, pos)
+		}
+		if pos+7 > len(data) {
+			return resources, fmt.Errorf("resource header at %d runs past the data", pos)
+		}
+		id := binary.BigEndian.Uint16(data[pos+4:])
+		nameLength := int(data[pos+6])
+		// the length byte and the name together are padded to an even size
+		nameEnd := pos + 7 + nameLength
+		sizeStart := nameEnd + (nameLength+1)%2
+		if sizeStart+4 > len(data) {
+			return resources, fmt.Errorf("resource 0x%04X runs past the data", id)
+		}
+		size := int(binary.BigEndian.Uint32(data[sizeStart:]))
+		dataStart := sizeStart + 4
+		if size < 0 || size > len(data)-dataStart {
+			return resources, fmt.Errorf("resource 0x%04X of %d bytes runs past the data", id, size)
+		}
+		resources = append(resources, Resource{
+			ID:     id,
+			Name:   string(data[pos+7 : nameEnd]),
+			Data:   data[dataStart : dataStart+size],
+			Offset: dataStart,
+		})
+		pos = dataStart + size + size%2
+	}
+	return resources, nil
+}
+
+func isPadding(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package photoshop
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+)
+
+func TestParseResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []Resource
+		wantErr bool
+	}{
+		{
+			name: "names and padding",
+			data: fixture.Concat(
+				fixture.Resource(0x0BB7, "", []byte("odd")),
+				fixture.Resource(URLResource, "a", []byte("ab")),
+				fixture.Resource(0x07D0, "path", nil),
+			),
+			want: []Resource{
+				{ID: 0x0BB7, Data: []byte("odd"), Offset: 12},
+				{ID: URLResource, Name: "a", Data: []byte("ab"), Offset: 28},
+				{ID: 0x07D0, Name: "path", Data: []byte{}, Offset: 46},
+			},
+		},
+		{
+			name: "zero padding after the blocks",
+			data: fixture.Concat(fixture.Resource(CopyrightResource, "", []byte{1}), make([]byte, 6)),
+			want: []Resource{{ID: CopyrightResource, Data: []byte{1}, Offset: 12}},
+		},
+		{
+			name:    "bad signature",
+			data:    fixture.Concat(fixture.Resource(CopyrightResource, "", []byte{1}), []byte("8BPS\x04\x0a")),
+			want:    []Resource{{ID: CopyrightResource, Data: []byte{1}, Offset: 12}},
+			wantErr: true,
+		},
+		{name: "header cut short", data: []byte("8BIM\x04\x0a"), wantErr: true},
+		{name: "name past the data", data: []byte("8BIM\x04\x0a\x20abc"), wantErr: true},
+		{name: "data past the end", data: fixture.Concat([]byte("8BIM\x04\x0a\x00\x00"), fixture.BE32(100), []byte{1}), wantErr: true},
+		{name: "huge size", data: fixture.Concat([]byte("8BIM\x04\x0a\x00\x00"), fixture.BE32(0xFFFFFFFF)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResources(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResources() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseResources() = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
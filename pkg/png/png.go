@@ -0,0 +1,272 @@
+package png
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/justikun/metadata-viewer/pkg/icc"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/tiff"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+const signature = "\x89PNG\r\n\x1a\n"
+
+// xmpKeyword is the iTXt keyword of the XMP packet.
+const xmpKeyword = "XML:com.adobe.xmp"
+
+// maxInflatedSize limits how much a compressed chunk may expand.
+const maxInflatedSize = 64 << 20
+
+func init() {
+	metadata.RegisterFormat(metadata.FormatPNG, signature, Decode)
+}
+
+// Decode walks the chunks of the PNG stream in r, checks their CRCs and
+// fills imgData from the chunks it understands. Ancillary chunks that are
+// corrupt or fail to parse are recorded in imgData.Warnings and skipped.
+func Decode(r io.ReaderAt, size int64, imgData *metadata.ImageData) error {
+	sig := make([]byte, len(signature))
+	if _, err := r.ReadAt(sig, 0); err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+	if string(sig) != signature {
+		return errors.New("missing PNG signature")
+	}
+	imgData.PNG = &metadata.PNG{}
+
+	offset := int64(len(signature))
+	for offset < size {
+		// length and type
+		header := make([]byte, 8)
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return fmt.Errorf("failed to read chunk header at %d: %w", offset, err)
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:8])
+		dataStart := offset + 8
+		if length > size-dataStart-4 {
+			return fmt.Errorf("%q chunk at %d: length %d runs past the end of the file", chunkType, offset, length)
+		}
+
+		err := checkCRC(r, offset+4, length)
+		if err == nil {
+			err = parseChunk(r, chunkType, dataStart, length, imgData)
+		}
+		if err != nil {
+			err = fmt.Errorf("%q chunk at %d: %w", chunkType, offset, err)
+			if !ancillary(chunkType) {
+				return err
+			}
+			// a damaged ancillary chunk loses only its own metadata
+			imgData.AddWarning(err)
+		}
+		if chunkType == "IEND" {
+			return nil
+		}
+		offset = dataStart + length + 4
+	}
+	return errors.New("missing IEND chunk")
+}
+
+// ancillary reports whether a chunk may be ignored by decoders, which the
+// PNG specification marks with a lower case first letter of the type.
+func ancillary(chunkType string) bool {
+	return chunkType[0]&0x20 != 0
+}
+
+// checkCRC compares the CRC that follows the chunk data with the CRC of the
+// chunk type and data. typeStart is the offset of the 4 type bytes.
+func checkCRC(r io.ReaderAt, typeStart int64, length int64) error {
+	crc := crc32.NewIEEE()
+	if _, err := io.Copy(crc, io.NewSectionReader(r, typeStart, 4+length)); err != nil {
+		return fmt.Errorf("failed to read chunk: %w", err)
+	}
+	stored := make([]byte, 4)
+	if _, err := r.ReadAt(stored, typeStart+4+length); err != nil {
+		return fmt.Errorf("failed to read CRC: %w", err)
+	}
+	if want := binary.BigEndian.Uint32(stored); crc.Sum32() != want {
+		return fmt.Errorf("CRC mismatch: computed %08x, stored %08x", crc.Sum32(), want)
+	}
+	return nil
+}
+
+// parseChunk reads the chunks that carry metadata. Image data and every other
+// chunk are skipped.
+func parseChunk(r io.ReaderAt, chunkType string, dataStart int64, length int64, imgData *metadata.ImageData) error {
+	switch chunkType {
+	case "eXIf":
+		return tiff.ParseExif(imgData, r, dataStart, length)
+	case "IHDR", "tEXt", "zTXt", "iTXt", "tIME", "pHYs", "gAMA", "cHRM", "sRGB", "iCCP":
+	default:
+		return nil
+	}
+
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, dataStart); err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+	pngData := imgData.PNG
+	switch chunkType {
+	case "IHDR":
+		if len(data) < 13 {
+			return errors.New("IHDR shorter than 13 bytes")
+		}
+		pngData.Width = binary.BigEndian.Uint32(data[0:4])
+		pngData.Height = binary.BigEndian.Uint32(data[4:8])
+		pngData.BitDepth = data[8]
+		pngData.ColorType = data[9]
+	case "tEXt":
+		keyword, text, ok := bytes.Cut(data, []byte{0})
+		if !ok {
+			return errors.New("missing keyword separator")
+		}
+		pngData.Text = append(pngData.Text, metadata.PNGText{
+			Chunk: chunkType, Keyword: latin1(keyword), Text: latin1(text),
+		})
+	case "zTXt":
+		keyword, rest, ok := bytes.Cut(data, []byte{0})
+		if !ok || len(rest) < 1 {
+			return errors.New("missing keyword separator")
+		}
+		text, err := inflate(rest[0], rest[1:])
+		if err != nil {
+			return err
+		}
+		pngData.Text = append(pngData.Text, metadata.PNGText{
+			Chunk: chunkType, Keyword: latin1(keyword), Text: latin1(text),
+		})
+	case "iTXt":
+		return parseITXt(data, imgData)
+	case "tIME":
+		if len(data) < 7 {
+			return errors.New("tIME shorter than 7 bytes")
+		}
+		t := time.Date(int(binary.BigEndian.Uint16(data[0:2])), time.Month(data[2]), int(data[3]),
+			int(data[4]), int(data[5]), int(data[6]), 0, time.UTC)
+		pngData.Time = &t
+	case "pHYs":
+		if len(data) < 9 {
+			return errors.New("pHYs shorter than 9 bytes")
+		}
+		pngData.Phys = &metadata.PNGPhys{
+			PixelsPerUnitX: binary.BigEndian.Uint32(data[0:4]),
+			PixelsPerUnitY: binary.BigEndian.Uint32(data[4:8]),
+			Unit:           data[8],
+		}
+	case "gAMA":
+		if len(data) < 4 {
+			return errors.New("gAMA shorter than 4 bytes")
+		}
+		gamma := float64(binary.BigEndian.Uint32(data)) / 100000
+		pngData.Gamma = &gamma
+	case "cHRM":
+		if len(data) < 32 {
+			return errors.New("cHRM shorter than 32 bytes")
+		}
+		v := func(i int) float64 { return float64(binary.BigEndian.Uint32(data[4*i:])) / 100000 }
+		pngData.Chromaticities = &metadata.PNGChromaticities{
+			WhiteX: v(0), WhiteY: v(1), RedX: v(2), RedY: v(3),
+			GreenX: v(4), GreenY: v(5), BlueX: v(6), BlueY: v(7),
+		}
+	case "sRGB":
+		if len(data) < 1 {
+			return errors.New("empty sRGB chunk")
+		}
+		intent := data[0]
+		pngData.SRGBIntent = &intent
+	case "iCCP":
+		name, rest, ok := bytes.Cut(data, []byte{0})
+		if !ok || len(rest) < 1 {
+			return errors.New("missing profile name separator")
+		}
+		profile, err := inflate(rest[0], rest[1:])
+		if err != nil {
+			return err
+		}
+		pngData.ICCProfile = &metadata.PNGICCProfile{Name: latin1(name), Profile: profile}
+		if imgData.ICC, err = icc.Parse(profile); err != nil {
+			return fmt.Errorf("ICC profile: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseITXt reads an international text chunk. The XMP packet is parsed into
+// imgData.XMP instead of being listed with the other text.
+func parseITXt(data []byte, imgData *metadata.ImageData) error {
+	// keyword, compression flag and method, language tag, translated keyword, text
+	keyword, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok || len(rest) < 2 {
+		return errors.New("missing keyword separator")
+	}
+	compressed, method := rest[0] == 1, rest[1]
+	language, rest, ok := bytes.Cut(rest[2:], []byte{0})
+	if !ok {
+		return errors.New("missing language tag separator")
+	}
+	translated, text, ok := bytes.Cut(rest, []byte{0})
+	if !ok {
+		return errors.New("missing translated keyword separator")
+	}
+	if compressed {
+		inflated, err := inflate(method, text)
+		if err != nil {
+			return err
+		}
+		text = inflated
+	}
+
+	if string(keyword) == xmpKeyword {
+		xmpPacket, err := xmp.Parse(text)
+		if err != nil {
+			return err
+		}
+		imgData.XMP = xmpPacket
+		return nil
+	}
+	imgData.PNG.Text = append(imgData.PNG.Text, metadata.PNGText{
+		Chunk:             "iTXt",
+		Keyword:           latin1(keyword),
+		Language:          string(language),
+		TranslatedKeyword: string(translated),
+		Text:              string(text),
+	})
+	return nil
+}
+
+// inflate decompresses zlib data. 0 is the only compression method PNG defines.
+func inflate(method byte, data []byte) ([]byte, error) {
+	if method != 0 {
+		return nil, fmt.Errorf("unknown compression method %d", method)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate: %w", err)
+	}
+	defer zr.Close()
+	inflated, err := io.ReadAll(io.LimitReader(zr, maxInflatedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate: %w", err)
+	}
+	if len(inflated) > maxInflatedSize {
+		return nil, fmt.Errorf("inflated data larger than %d bytes", maxInflatedSize)
+	}
+	return inflated, nil
+}
+
+// latin1 converts the ISO 8859-1 text of tEXt and zTXt chunks to UTF-8.
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
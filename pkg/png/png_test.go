@@ -0,0 +1,156 @@
+package png
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// chunk returns a PNG chunk with its CRC.
+func chunk(chunkType string, data []byte) []byte {
+	b := fixture.Concat(fixture.BE32(uint32(len(data))), []byte(chunkType), data)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b[4:]))
+}
+
+// badCRC returns a chunk whose CRC does not match.
+func badCRC(chunkType string, data []byte) []byte {
+	b := chunk(chunkType, data)
+	b[len(b)-1] ^= 0xFF
+	return b
+}
+
+// deflate returns data compressed with zlib.
+func deflate(data string) []byte {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write([]byte(data))
+	zw.Close()
+	return b.Bytes()
+}
+
+var ihdr = chunk("IHDR", fixture.Concat(fixture.BE32(640), fixture.BE32(480), []byte{8, 6, 0, 0, 0}))
+
+// image returns a PNG file with chunks between IHDR and IEND.
+func image(chunks ...[]byte) []byte {
+	return fixture.Concat([]byte(signature), ihdr, fixture.Concat(chunks...), chunk("IDAT", deflate("\x00")), chunk("IEND", nil))
+}
+
+// decode runs Decode on data.
+func decode(t *testing.T, data []byte) (*metadata.ImageData, error) {
+	t.Helper()
+	imgData := &metadata.ImageData{}
+	return imgData, Decode(bytes.NewReader(data), int64(len(data)), imgData)
+}
+
+func TestDecode(t *testing.T) {
+	exif := fixture.TIFF(binary.BigEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon")}})
+	packet := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="2"/></rdf:RDF>`
+	modified := time.Date(2024, time.May, 1, 10, 11, 12, 0, time.UTC)
+	gamma := 0.45455
+	intent := uint8(0)
+
+	data := image(
+		chunk("tEXt", []byte("Author\x00Ren\xe9e")),
+		chunk("zTXt", fixture.Concat([]byte("Comment\x00\x00"), deflate("made in Z\xfcrich"))),
+		chunk("iTXt", []byte("Title\x00\x00\x00de\x00Titel\x00Sonnenuntergang")),
+		chunk("iTXt", fixture.Concat([]byte("Description\x00\x01\x00en\x00\x00"), deflate("Sunset"))),
+		chunk("iTXt", []byte(xmpKeyword+"\x00\x00\x00\x00\x00"+packet)),
+		chunk("tIME", []byte{0x07, 0xE8, 5, 1, 10, 11, 12}),
+		chunk("pHYs", fixture.Concat(fixture.BE32(2835), fixture.BE32(2835), []byte{1})),
+		chunk("gAMA", fixture.BE32(45455)),
+		chunk("sRGB", []byte{0}),
+		chunk("eXIf", exif),
+		chunk("prVt", []byte("private data")),
+	)
+	imgData, err := decode(t, data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := &metadata.PNG{
+		Width: 640, Height: 480, BitDepth: 8, ColorType: 6,
+		Text: []metadata.PNGText{
+			{Chunk: "tEXt", Keyword: "Author", Text: "Renée"},
+			{Chunk: "zTXt", Keyword: "Comment", Text: "made in Zürich"},
+			{Chunk: "iTXt", Keyword: "Title", Language: "de", TranslatedKeyword: "Titel", Text: "Sonnenuntergang"},
+			{Chunk: "iTXt", Keyword: "Description", Language: "en", Text: "Sunset"},
+		},
+		Time:       &modified,
+		Phys:       &metadata.PNGPhys{PixelsPerUnitX: 2835, PixelsPerUnitY: 2835, Unit: 1},
+		Gamma:      &gamma,
+		SRGBIntent: &intent,
+	}
+	if !reflect.DeepEqual(imgData.PNG, want) {
+		t.Errorf("PNG = %+v, want %+v", imgData.PNG, want)
+	}
+	if rating, _ := imgData.XMP.Get(xmp.NSXMP, "Rating"); rating.Value != "2" {
+		t.Errorf("xmp:Rating = %q, want 2", rating.Value)
+	}
+	if len(imgData.MetaData.MainTags) != 1 || imgData.MetaData.MainTags[0].Data != "Canon" {
+		t.Errorf("eXIf MainTags = %v, want Make Canon", imgData.MetaData.MainTags)
+	}
+	if len(imgData.Warnings) != 0 {
+		t.Errorf("got warnings %v", imgData.Warnings)
+	}
+}
+
+func TestDecodeExifPrefix(t *testing.T) {
+	// some writers copy the JPEG APP1 identifier into eXIf
+	exif := fixture.TIFF(binary.LittleEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Nikon")}})
+	imgData, err := decode(t, image(chunk("eXIf", fixture.Concat([]byte("Exif\x00\x00"), exif))))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(imgData.MetaData.MainTags) != 1 || imgData.MetaData.MainTags[0].Data != "Nikon" {
+		t.Errorf("MainTags = %v, want Make Nikon", imgData.MetaData.MainTags)
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	bomb := deflate(string(make([]byte, maxInflatedSize+1)))
+	valid := image()
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantErr      bool
+		wantWarnings int
+	}{
+		{name: "ancillary CRC mismatch", data: image(badCRC("tEXt", []byte("Author\x00Jane"))), wantWarnings: 1},
+		{name: "zTXt not zlib", data: image(chunk("zTXt", []byte("Comment\x00\x00not zlib"))), wantWarnings: 1},
+		{name: "zTXt unknown method", data: image(chunk("zTXt", fixture.Concat([]byte("Comment\x00\x01"), deflate("x")))), wantWarnings: 1},
+		{name: "zTXt bomb", data: image(chunk("zTXt", fixture.Concat([]byte("Comment\x00\x00"), bomb))), wantWarnings: 1},
+		{name: "tEXt without separator", data: image(chunk("tEXt", []byte("Author"))), wantWarnings: 1},
+		{name: "iTXt bad XMP", data: image(chunk("iTXt", []byte(xmpKeyword+"\x00\x00\x00\x00\x00<x/>"))), wantWarnings: 1},
+		{name: "short pHYs", data: image(chunk("pHYs", []byte{1, 2})), wantWarnings: 1},
+		{name: "short tIME and gAMA", data: image(chunk("tIME", []byte{7}), chunk("gAMA", []byte{1})), wantWarnings: 2},
+		{name: "bad eXIf", data: image(chunk("eXIf", []byte("XX*\x00"))), wantWarnings: 1},
+		{name: "critical CRC mismatch", data: fixture.Concat([]byte(signature), badCRC("IHDR", make([]byte, 13))), wantErr: true},
+		{name: "critical chunk too short", data: fixture.Concat([]byte(signature), chunk("IHDR", make([]byte, 4)), chunk("IEND", nil)), wantErr: true},
+		{name: "length past the end", data: valid[:len(valid)-6], wantErr: true},
+		{name: "missing IEND", data: valid[:len(valid)-12], wantErr: true},
+		{name: "bad signature", data: []byte("\x89PNG\r\n\x1a\x00"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData, err := decode(t, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+			if !tt.wantErr && imgData.PNG.Width != 640 {
+				t.Errorf("Width = %d, want 640", imgData.PNG.Width)
+			}
+		})
+	}
+}
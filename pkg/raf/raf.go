@@ -0,0 +1,155 @@
+package raf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/justikun/metadata-viewer/pkg/jpg"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+const (
+	magic      = "FUJIFILMCCD-RAW "
+	headerSize = 108
+	// maxCFAHeader limits how much of the CFA header is loaded into memory
+	maxCFAHeader = 1 << 20
+)
+
+// CFA header record tags
+const (
+	tagRawImageFullSize    = 0x0100
+	tagRawImageCropTopLeft = 0x0110
+	tagRawImageCroppedSize = 0x0111
+	tagWBLevels            = 0x2FF0
+)
+
+var recordNames = map[uint16]string{
+	0x0100: "Raw Image Full Size",
+	0x0110: "Raw Image Crop Top Left",
+	0x0111: "Raw Image Cropped Size",
+	0x0115: "Raw Image Aspect Ratio",
+	0x0121: "Raw Image Size",
+	0x0130: "Fuji Layout",
+	0x0131: "X-Trans Layout",
+	0x2000: "WB GRGB Levels Auto",
+	0x2FF0: "WB GRGB Levels",
+	0x9200: "Relative Exposure",
+	0x9650: "Raw Exposure Bias",
+	0xC000: "RAF Data",
+}
+
+func init() {
+	metadata.RegisterFormat(metadata.FormatRAF, magic, Decode)
+}
+
+// Decode reads the RAF header, the Exif and XMP of the embedded JPEG and the
+// records of the CFA header. An embedded JPEG that fails to decode is recorded
+// in imgData.Warnings.
+func Decode(r io.ReaderAt, size int64, imgData *metadata.ImageData) error {
+	header := make([]byte, headerSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("failed to read RAF header: %w", err)
+	}
+	if string(header[:16]) != magic {
+		return errors.New("missing RAF magic")
+	}
+	raf := &metadata.RAF{
+		FormatVersion:    string(header[16:20]),
+		CameraID:         cstring(header[20:28]),
+		Model:            cstring(header[28:60]),
+		DirectoryVersion: string(header[60:64]),
+		// 20 bytes of unknown purpose precede the directory
+		JPEGOffset:      binary.BigEndian.Uint32(header[84:]),
+		JPEGLength:      binary.BigEndian.Uint32(header[88:]),
+		CFAHeaderOffset: binary.BigEndian.Uint32(header[92:]),
+		CFAHeaderLength: binary.BigEndian.Uint32(header[96:]),
+		CFAOffset:       binary.BigEndian.Uint32(header[100:]),
+		CFALength:       binary.BigEndian.Uint32(header[104:]),
+	}
+	imgData.RAF = raf
+
+	if raf.JPEGLength > 0 {
+		offset, length := int64(raf.JPEGOffset), int64(raf.JPEGLength)
+		if offset+length > size {
+			return fmt.Errorf("embedded JPEG at %d runs past the end of the file", offset)
+		}
+		if err := jpg.DecodeEmbedded(r, offset, length, imgData); err != nil {
+			// the header and the CFA records do not depend on the JPEG
+			imgData.AddWarning(fmt.Errorf("embedded JPEG at %d: %w", offset, err))
+		} else {
+			imgData.Previews = append(imgData.Previews, metadata.Preview{Source: "RAF JPEG", Offset: offset, Length: length})
+		}
+	}
+	if raf.CFAHeaderLength > 0 {
+		if err := readRecords(r, size, raf); err != nil {
+			return fmt.Errorf("CFA header at %d: %w", raf.CFAHeaderOffset, err)
+		}
+	}
+	return nil
+}
+
+// readRecords reads the CFA header: a record count followed by records of a
+// tag, a data size and the data.
+func readRecords(r io.ReaderAt, size int64, raf *metadata.RAF) error {
+	offset, length := int64(raf.CFAHeaderOffset), int64(raf.CFAHeaderLength)
+	if offset+length > size {
+		return errors.New("runs past the end of the file")
+	}
+	if length > maxCFAHeader {
+		return fmt.Errorf("larger than %d bytes", maxCFAHeader)
+	}
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, offset); err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	if len(data) < 4 {
+		return errors.New("missing record count")
+	}
+	count := binary.BigEndian.Uint32(data)
+	pos := 4
+	for i := range count {
+		if pos+4 > len(data) {
+			return fmt.Errorf("record %d runs past the end of the header", i)
+		}
+		tag := binary.BigEndian.Uint16(data[pos:])
+		n := int(binary.BigEndian.Uint16(data[pos+2:]))
+		pos += 4
+		if pos+n > len(data) {
+			return fmt.Errorf("record 0x%04X runs past the end of the header", tag)
+		}
+		record := metadata.RAFRecord{Tag: tag, Name: recordNames[tag], Data: data[pos : pos+n]}
+		raf.Records = append(raf.Records, record)
+		pos += n
+
+		values := uint16s(record.Data)
+		switch {
+		case tag == tagRawImageFullSize && len(values) >= 2:
+			raf.RawHeight, raf.RawWidth = values[0], values[1]
+		case tag == tagRawImageCropTopLeft && len(values) >= 2:
+			raf.CropTop, raf.CropLeft = values[0], values[1]
+		case tag == tagRawImageCroppedSize && len(values) >= 2:
+			raf.CroppedHeight, raf.CroppedWidth = values[0], values[1]
+		case tag == tagWBLevels:
+			raf.WBLevels = values
+		}
+	}
+	return nil
+}
+
+// uint16s splits big endian record data into 16 bit values.
+func uint16s(data []byte) []uint16 {
+	values := make([]uint16, len(data)/2)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(data[2*i:])
+	}
+	return values
+}
+
+// cstring returns b up to the first NUL.
+func cstring(b []byte) string {
+	s, _, _ := bytes.Cut(b, []byte{0})
+	return string(s)
+}
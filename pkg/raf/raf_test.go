@@ -0,0 +1,129 @@
+package raf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+// padded returns s padded with NULs to n bytes.
+func padded(s string, n int) []byte {
+	return append([]byte(s), make([]byte, n-len(s))...)
+}
+
+// file returns a RAF file with the JPEG and the CFA header stored after the
+// header, the directory pointing at them.
+func file(jpeg, cfaHeader []byte) []byte {
+	jpegOffset := uint32(headerSize)
+	cfaHeaderOffset := jpegOffset + uint32(len(jpeg))
+	header := fixture.Concat([]byte(magic), []byte("0201"), padded("FF129502", 8), padded("X-T5", 32), []byte("0100"), make([]byte, 20),
+		fixture.BE32(jpegOffset), fixture.BE32(uint32(len(jpeg))),
+		fixture.BE32(cfaHeaderOffset), fixture.BE32(uint32(len(cfaHeader))),
+		fixture.BE32(0), fixture.BE32(0))
+	return fixture.Concat(header, jpeg, cfaHeader)
+}
+
+// records returns a CFA header holding count and records.
+func records(count uint32, records ...[]byte) []byte {
+	return fixture.Concat(fixture.BE32(count), fixture.Concat(records...))
+}
+
+// record returns one CFA header record of 16 bit values.
+func record(tag uint16, values ...uint16) []byte {
+	data := fixture.Concat(fixture.BE16(tag), fixture.BE16(uint16(2*len(values))))
+	for _, v := range values {
+		data = fixture.Concat(data, fixture.BE16(v))
+	}
+	return data
+}
+
+func TestDecode(t *testing.T) {
+	exif := fixture.Exif(fixture.TIFF(binary.BigEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "FUJIFILM")}}))
+	jpeg := fixture.JPEG(160, 120, exif)
+	cfaHeader := records(5,
+		record(tagRawImageFullSize, 5214, 7752),
+		record(tagRawImageCropTopLeft, 6, 24),
+		record(tagRawImageCroppedSize, 5152, 7728),
+		record(tagWBLevels, 302, 521, 302, 652),
+		record(0x9999, 1))
+	data := file(jpeg, cfaHeader)
+
+	imgData, err := metadata.Decode(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if imgData.Format != metadata.FormatRAF {
+		t.Errorf("Format = %q, want %q", imgData.Format, metadata.FormatRAF)
+	}
+	raf := imgData.RAF
+	want := metadata.RAF{
+		FormatVersion: "0201", CameraID: "FF129502", Model: "X-T5", DirectoryVersion: "0100",
+		JPEGOffset: headerSize, JPEGLength: uint32(len(jpeg)),
+		CFAHeaderOffset: headerSize + uint32(len(jpeg)), CFAHeaderLength: uint32(len(cfaHeader)),
+		RawWidth: 7752, RawHeight: 5214, CropTop: 6, CropLeft: 24, CroppedWidth: 7728, CroppedHeight: 5152,
+		WBLevels: []uint16{302, 521, 302, 652},
+	}
+	got := *raf
+	got.Records = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RAF = %+v, want %+v", got, want)
+	}
+	if len(raf.Records) != 5 || raf.Records[0].Name != "Raw Image Full Size" || raf.Records[4].Name != "" {
+		t.Errorf("Records = %+v, want 5 with an unnamed last one", raf.Records)
+	}
+
+	// the Exif comes from the embedded JPEG
+	if len(imgData.MetaData.MainTags) != 1 || imgData.MetaData.MainTags[0].Data != "FUJIFILM" {
+		t.Errorf("MainTags = %v, want Make FUJIFILM", imgData.MetaData.MainTags)
+	}
+	wantPreview := []metadata.Preview{{Source: "RAF JPEG", Offset: headerSize, Length: int64(len(jpeg))}}
+	if !reflect.DeepEqual(imgData.Previews, wantPreview) {
+		t.Errorf("Previews = %+v, want %+v", imgData.Previews, wantPreview)
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	jpeg := fixture.JPEG(16, 16)
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantErr      bool
+		wantWarnings int
+	}{
+		{name: "short header", data: []byte(magic), wantErr: true},
+		{name: "JPEG past the end", data: file(jpeg, nil)[:headerSize+4], wantErr: true},
+		{name: "CFA header past the end", data: file(jpeg, records(1, record(tagWBLevels, 1, 2)))[:headerSize+len(jpeg)+4], wantErr: true},
+		{name: "no record count", data: file(jpeg, []byte{0, 0}), wantErr: true},
+		{name: "more records than stored", data: file(jpeg, records(3, record(tagRawImageFullSize, 1, 2))), wantErr: true},
+		{name: "record past the header", data: file(jpeg, records(1, fixture.Concat(fixture.BE16(tagWBLevels), fixture.BE16(100)))), wantErr: true},
+		{name: "CFA header too large", data: file(jpeg, records(0, make([]byte, maxCFAHeader))), wantErr: true},
+		// the header and the CFA records do not depend on the JPEG
+		{name: "not a JPEG", data: file([]byte("not a JPEG stream"), records(1, record(tagWBLevels, 1, 2))), wantWarnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData := &metadata.ImageData{}
+			err := Decode(bytes.NewReader(tt.data), int64(len(tt.data)), imgData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if imgData.RAF.Model != "X-T5" || len(imgData.RAF.Records) != 1 {
+				t.Errorf("RAF = %+v, want model X-T5 and 1 record", imgData.RAF)
+			}
+			if len(imgData.Previews) != 0 {
+				t.Errorf("Previews = %+v, want none", imgData.Previews)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package tiff
+
+import (
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+// header versions of the RAW formats that replace the TIFF magic 42
+const (
+	versionORF  = 0x4F52 // "RO", Olympus ORF
+	versionORFS = 0x5352 // "RS", ORF of some Olympus E-series models
+	versionRW2  = 0x0055 // Panasonic RW2
+)
+
+// tags that identify a RAW flavour
+const (
+	makeTag        = 0x010F // Make
+	compressionTag = 0x0103 // Compression
+	photometricTag = 0x0106 // Photometric Interpretation
+	dngVersionTag  = 0xC612 // DNG Version
+)
+
+func init() {
+	metadata.RegisterFormat(metadata.FormatORF, "IIRO", Decode)
+	metadata.RegisterFormat(metadata.FormatORF, "IIRS", Decode)
+	metadata.RegisterFormat(metadata.FormatORF, "MMOR", Decode)
+	metadata.RegisterFormat(metadata.FormatRW2, "IIU\x00", Decode)
+}
+
+// rawMakes maps the Make prefix of the TIFF based RAW formats that share the
+// plain TIFF header to their format.
+var rawMakes = []struct {
+	prefix string
+	format metadata.Format
+}{
+	{"NIKON", metadata.FormatNEF},
+	{"SONY", metadata.FormatARW},
+	{"PENTAX", metadata.FormatPEF},
+	{"RICOH IMAGING", metadata.FormatPEF},
+}
+
+// detectRAW returns the RAW flavour of a file that starts with a plain TIFF
+// header, or format when it is an ordinary TIFF.
+func detectRAW(r io.ReaderAt, imgData *metadata.ImageData, format metadata.Format) metadata.Format {
+	// CR2 adds "CR", major version 2 and the raw IFD offset to the header
+	header := make([]byte, 11)
+	if _, err := r.ReadAt(header, 0); err == nil && string(header[8:10]) == "CR" && header[10] == 2 {
+		return metadata.FormatCR2
+	}
+	if _, ok := findTag(imgData.MetaData.MainTags, dngVersionTag); ok {
+		return metadata.FormatDNG
+	}
+	if !hasRawImage(imgData) {
+		return format
+	}
+	tag, _ := findTag(imgData.MetaData.MainTags, makeTag)
+	cameraMake, _ := tag.Data.(string)
+	for _, raw := range rawMakes {
+		if strings.HasPrefix(strings.ToUpper(cameraMake), raw.prefix) {
+			return raw.format
+		}
+	}
+	return format
+}
+
+// hasRawImage reports whether an IFD or SubIFD holds sensor data rather than
+// an RGB image: a CFA or linear raw photometric interpretation, or one of the
+// vendor compression schemes.
+func hasRawImage(imgData *metadata.ImageData) bool {
+	for _, ifd := range slices.Concat(imgData.MetaData.IFDs, imgData.MetaData.SubIFDs) {
+		if tag, ok := findTag(ifd.Tags, photometricTag); ok {
+			switch firstValue(tag) {
+			case 32803, 34892: // CFA, Linear Raw
+				return true
+			}
+		}
+		if tag, ok := findTag(ifd.Tags, compressionTag); ok {
+			switch firstValue(tag) {
+			case 32767, 34713, 65535: // Sony ARW, Nikon NEF, Pentax PEF
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renamePanasonic gives the IFD0 tags of an RW2 file their Panasonic names.
+func renamePanasonic(imgData *metadata.ImageData) {
+	if len(imgData.MetaData.IFDs) == 0 {
+		return
+	}
+	tags := slices.Clone(imgData.MetaData.IFDs[0].Tags)
+	for i, tag := range tags {
+		if def, ok := metadata.LookupTag(metadata.IFDPANASONIC, tag.ID); ok {
+			tags[i].Name = def.Name
+		}
+	}
+	imgData.MetaData.IFDs[0].Tags = tags
+	imgData.MetaData.MainTags = tags
+}
+
+func findTag(tags []metadata.IFDtag, id uint16) (metadata.IFDtag, bool) {
+	for _, tag := range tags {
+		if tag.ID == id {
+			return tag, true
+		}
+	}
+	return metadata.IFDtag{}, false
+}
+
+// firstValue returns the first value of a SHORT or LONG tag.
+func firstValue(tag metadata.IFDtag) uint64 {
+	offsets := pointerOffsets(tag)
+	if len(offsets) == 0 {
+		return 0
+	}
+	return offsets[0]
+}
@@ -0,0 +1,92 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+)
+
+func TestDecodeRAW(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	// ifd0 returns an IFD0 with Make and extra entries
+	ifd0 := func(cameraMake string, entries ...fixture.Entry) *fixture.IFD {
+		return &fixture.IFD{Entries: append([]fixture.Entry{fixture.ASCII(makeTag, cameraMake)}, entries...)}
+	}
+	cfa := &fixture.IFD{Entries: []fixture.Entry{fixture.Short(photometricTag, 32803)}}
+
+	tests := []struct {
+		name string
+		data []byte
+		want metadata.Format
+	}{
+		{name: "plain TIFF", data: fixture.TIFF(le, ifd0("Canon", fixture.Short(photometricTag, 2))), want: metadata.FormatTIFF},
+		{name: "CR2", data: fixture.CR2(le, ifd0("Canon")), want: metadata.FormatCR2},
+		{name: "DNG", data: fixture.TIFF(le, ifd0("Leica", fixture.Bytes(dngVersionTag, 1, []byte{1, 4, 0, 0}))), want: metadata.FormatDNG},
+		{name: "NEF with a CFA SubIFD", data: fixture.TIFF(be, ifd0("NIKON CORPORATION", fixture.Subs(0x014A, cfa))), want: metadata.FormatNEF},
+		{name: "ARW", data: fixture.TIFF(le, ifd0("SONY", fixture.Short(compressionTag, 32767))), want: metadata.FormatARW},
+		{name: "PEF", data: fixture.TIFF(le, ifd0("RICOH IMAGING COMPANY, LTD.", fixture.Short(compressionTag, 65535))), want: metadata.FormatPEF},
+		// a scanner TIFF of a camera maker holds an RGB image
+		{name: "Nikon RGB TIFF", data: fixture.TIFF(le, ifd0("Nikon", fixture.Short(photometricTag, 2))), want: metadata.FormatTIFF},
+		{name: "ORF", data: fixture.TIFFVersion(le, versionORF, ifd0("OLYMPUS IMAGING CORP.")), want: metadata.FormatORF},
+		{name: "ORF big-endian", data: fixture.TIFFVersion(be, versionORF, ifd0("OLYMPUS CORPORATION")), want: metadata.FormatORF},
+		{name: "ORF E-series", data: fixture.TIFFVersion(le, versionORFS, ifd0("OLYMPUS OPTICAL CO.,LTD")), want: metadata.FormatORF},
+		{name: "RW2", data: fixture.TIFFVersion(le, versionRW2, ifd0("Panasonic")), want: metadata.FormatRW2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData, err := metadata.Decode(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if imgData.Format != tt.want {
+				t.Errorf("Format = %q, want %q", imgData.Format, tt.want)
+			}
+			if tagString(imgData.MetaData.MainTags, makeTag) == "" {
+				t.Error("Make is missing from MainTags")
+			}
+		})
+	}
+}
+
+func TestParseRAWVersion(t *testing.T) {
+	// the RAW header versions are only valid at the start of a file, not
+	// for the TIFF structure of an Exif segment
+	for _, version := range []uint16{versionORF, versionORFS, versionRW2} {
+		data := fixture.TIFFVersion(binary.LittleEndian, version, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(makeTag, "X")}})
+		if _, err := parse(t, data); err == nil {
+			t.Errorf("Parse() of header version %#04x succeeded", version)
+		}
+	}
+}
+
+func TestRenamePanasonic(t *testing.T) {
+	data := fixture.TIFFVersion(binary.LittleEndian, versionRW2, &fixture.IFD{Entries: []fixture.Entry{
+		fixture.Undefined(0x0001, []byte("0310")),
+		fixture.Short(0x0002, 5200),
+		fixture.Short(0x0003, 3904),
+		fixture.ASCII(makeTag, "Panasonic"),
+	}})
+	imgData, err := metadata.Decode(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	names := func(tags []metadata.IFDtag) []string {
+		var names []string
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		return names
+	}
+	want := []string{"Panasonic Raw Version", "Sensor Width", "Sensor Height", "Make"}
+	if got := names(imgData.MetaData.MainTags); !slices.Equal(got, want) {
+		t.Errorf("MainTags names = %q, want %q", got, want)
+	}
+	// the IFD list and the main tags must agree
+	if got := names(imgData.MetaData.IFDs[0].Tags); !slices.Equal(got, want) {
+		t.Errorf("IFDs[0] names = %q, want %q", got, want)
+	}
+}
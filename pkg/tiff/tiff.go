@@ -2,21 +2,484 @@ package tiff
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 
+	"github.com/justikun/metadata-viewer/pkg/iptc"
 	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/photoshop"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
 )
 
+// tags of IFD0 that need more than a dictionary lookup
+const (
+	subIFDsTag   = 0x014A // Sub IFDs
+	xmpTag       = 0x02BC // Application Notes, the XMP packet
+	iptcTag      = 0x83BB // IPTC Data, the IIM datasets
+	photoshopTag = 0x8649 // Photoshop Settings, the image resource blocks
+)
+
+func init() {
+	metadata.RegisterFormat(metadata.FormatTIFF, "II*\x00", Decode)
+	metadata.RegisterFormat(metadata.FormatTIFF, "MM\x00*", Decode)
+	metadata.RegisterFormat(metadata.FormatBigTIFF, "II+\x00", Decode)
+	metadata.RegisterFormat(metadata.FormatBigTIFF, "MM\x00+", Decode)
+}
+
+// header version numbers
+const (
+	versionClassic = 42
+	versionBig     = 43 // BigTIFF, 64 bit offsets and counts
+)
+
+// Decode reads a standalone TIFF or BigTIFF file, or one of the TIFF based
+// camera RAW formats. The header sits at the start of r, so every offset in
+// the file is already absolute. The XMP packet, IPTC datasets and Photoshop
+// image resources that TIFF writers store in IFD0 are parsed as well, damaged
+// ones are recorded in imgData.Warnings.
+// imgData.Format is set to the RAW flavour when the file turns out to be one.
+func Decode(r io.ReaderAt, size int64, imgData *metadata.ImageData) error {
+	byteOrder := make([]byte, 2)
+	if _, err := r.ReadAt(byteOrder, 0); err != nil {
+		return fmt.Errorf("failed to read byte order: %w", err)
+	}
+	if err := parseHeader(imgData, io.NewSectionReader(r, 0, size), 0, metadata.IFDMAIN, true); err != nil {
+		return err
+	}
+	switch imgData.Format {
+	case metadata.FormatTIFF:
+		imgData.Format = detectRAW(r, imgData, imgData.Format)
+	case metadata.FormatRW2:
+		renamePanasonic(imgData)
+	}
+	for _, tag := range imgData.MetaData.MainTags {
+		switch tag.ID {
+		case xmpTag:
+			packet, ok := tag.Data.([]uint8)
+			if !ok {
+				imgData.AddWarning(fmt.Errorf("XMP tag has unexpected type %s", tag.DataType))
+				continue
+			}
+			xmpPacket, err := xmp.Parse(packet)
+			if err != nil {
+				imgData.AddWarning(fmt.Errorf("XMP: %w", err))
+				continue
+			}
+			imgData.XMP = xmpPacket
+		case iptcTag:
+			data, ok := tagBytes(tag, string(byteOrder) == "II")
+			if !ok {
+				imgData.AddWarning(fmt.Errorf("IPTC tag has unexpected type %s", tag.DataType))
+				continue
+			}
+			iptcData, err := iptc.Parse(data)
+			if err != nil {
+				imgData.AddWarning(fmt.Errorf("IPTC: %w", err))
+				continue
+			}
+			imgData.IPTC = iptcData
+		case photoshopTag:
+			data, ok := tagBytes(tag, string(byteOrder) == "II")
+			if !ok {
+				imgData.AddWarning(fmt.Errorf("Photoshop tag has unexpected type %s", tag.DataType))
+				continue
+			}
+			if err := photoshop.Decode(data, tag.Offset, imgData); err != nil {
+				imgData.AddWarning(fmt.Errorf("Photoshop resources: %w", err))
+			}
+		}
+	}
+	return nil
+}
+
+// tagBytes returns the raw bytes of an UNDEFINED, BYTE or LONG tag. Writers
+// often declare byte blobs such as IPTC Data as LONG, which the decoder has
+// already split into byte order dependent values.
+func tagBytes(tag metadata.IFDtag, littleEndian bool) ([]byte, bool) {
+	switch v := tag.Data.(type) {
+	case []uint8:
+		return v, true
+	case uint8:
+		return []byte{v}, true
+	case []uint32:
+		var order binary.ByteOrder = binary.BigEndian
+		if littleEndian {
+			order = binary.LittleEndian
+		}
+		data := make([]byte, 4*len(v))
+		for i, value := range v {
+			order.PutUint32(data[4*i:], value)
+		}
+		return data, true
+	}
+	return nil, false
+}
+
+// Parse reads the TIFF header found at tiffHeaderStart in r and parses the
+// IFD it points to. All offsets inside the TIFF structure are relative to
+// tiffHeaderStart. Both classic TIFF and BigTIFF headers are accepted.
+func Parse(imgData *metadata.ImageData, r io.ReadSeeker, tiffHeaderStart int64) error {
+	return ParseAs(imgData, r, tiffHeaderStart, metadata.IFDMAIN)
+}
+
+// exifIdentifier is the identifier of the JPEG APP1 Exif segment.
+const exifIdentifier = "Exif\x00\x00"
+
+// ParseExif parses the Exif block of length bytes at offset in r, as stored by
+// the PNG eXIf, WebP EXIF and HEIF Exif items: a TIFF structure, optionally
+// preceded by the "Exif\0\0" identifier some writers copy from JPEG APP1.
+// Reads are bounded to the block, offsets are kept absolute to r.
+func ParseExif(imgData *metadata.ImageData, r io.ReaderAt, offset int64, length int64) error {
+	prefix := make([]byte, min(length, int64(len(exifIdentifier))))
+	if _, err := r.ReadAt(prefix, offset); err != nil {
+		return fmt.Errorf("failed to read Exif block: %w", err)
+	}
+	tiffHeaderStart := offset
+	if string(prefix) == exifIdentifier {
+		tiffHeaderStart += int64(len(exifIdentifier))
+	}
+	return Parse(imgData, io.NewSectionReader(r, 0, offset+length), tiffHeaderStart)
+}
+
+// ParseAs is Parse for TIFF structures whose first IFD is not IFD0, e.g. the
+// CR3 boxes that each hold one of the Exif, GPS or MakerNote IFDs.
+func ParseAs(imgData *metadata.ImageData, r io.ReadSeeker, tiffHeaderStart int64, ifdType metadata.IFDtype) error {
+	return parseHeader(imgData, r, tiffHeaderStart, ifdType, false)
+}
+
+// parseHeader implements ParseAs. The ORF and RW2 header versions are only
+// accepted with raw set, for standalone files; embedded Exif must use 42 or 43.
+func parseHeader(imgData *metadata.ImageData, r io.ReadSeeker, tiffHeaderStart int64, ifdType metadata.IFDtype, raw bool) error {
+	if _, err := r.Seek(tiffHeaderStart, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to tiff header: %w", err)
+	}
+	tiffHeader := make([]byte, 8)
+	if _, err := io.ReadFull(r, tiffHeader); err != nil {
+		return fmt.Errorf("Error reading tiff header: %w", err)
+	}
+
+	// check endianness (byte order)
+	var endian binary.ByteOrder
+	switch string(tiffHeader[:2]) {
+	case "MM":
+		endian = binary.BigEndian
+	case "II":
+		endian = binary.LittleEndian
+	default:
+		return errors.New("Endianess not found")
+	}
+	br := metadata.NewBinaryReader(r, endian)
+
+	// check version - TIFF magic number 42, BigTIFF 43
+	var ifdOffset uint64
+	var big bool
+	switch version := endian.Uint16(tiffHeader[2:4]); {
+	case version == versionClassic, raw && (version == versionORF || version == versionORFS || version == versionRW2):
+		// ORF and RW2 only change the magic number
+		ifdOffset = uint64(endian.Uint32(tiffHeader[4:8]))
+	case version == versionBig:
+		// offset size (always 8) and a reserved 0, then the 8 byte IFD offset
+		if endian.Uint16(tiffHeader[4:6]) != 8 || endian.Uint16(tiffHeader[6:8]) != 0 {
+			return errors.New("invalid BigTIFF header")
+		}
+		offset, err := br.ReadUint64()
+		if err != nil {
+			return fmt.Errorf("Error reading BigTIFF header: %w", err)
+		}
+		ifdOffset = offset
+		big = true
+	default:
+		return errors.New("invalid version number")
+	}
+
+	// move to the first IFD (Image File Directory)
+	ifdStart := tiffHeaderStart + int64(ifdOffset)
+	if _, err := br.Seek(ifdStart, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to offset %d: %v", ifdOffset, err)
+	}
+	p, err := newIFDParser(imgData, br, tiffHeaderStart, endian)
+	if err != nil {
+		return err
+	}
+	p.big = big
+	return p.run(ifdStart, ifdType)
+}
+
+const (
+	// maxIFDDepth limits how deep sub-IFD pointers are followed.
+	maxIFDDepth = 4
+	// maxIFDChain limits how many directories the next-IFD offsets may link.
+	maxIFDChain = 1024
+)
+
+// subIFDPointers maps the pointer tags of each IFD to the IFD they point to.
+var subIFDPointers = map[metadata.IFDtype]map[uint16]metadata.IFDtype{
+	metadata.IFDMAIN: {
+		0x8769: metadata.IFDEXIF, // Exif IFD Pointer
+		0x8825: metadata.IFDGPS,  // GPS Info IFD Pointer
+	},
+	metadata.IFDEXIF: {
+		0xA005: metadata.IFDINTROP, // Interop Offset
+	},
+}
+
+type ifdParser struct {
+	imgData         *metadata.ImageData
+	br              *metadata.BinaryReader
+	tiffHeaderStart int64
+	endian          binary.ByteOrder
+	big             bool  // BigTIFF layout: 8 byte offsets and counts, 20 byte entries
+	end             int64 // end of the readable data
+	visited         map[int64]bool
+}
+
+func newIFDParser(imgData *metadata.ImageData, br *metadata.BinaryReader, tiffHeaderStart int64, endian binary.ByteOrder) (*ifdParser, error) {
+	current, err := br.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get IFD position: %w", err)
+	}
+	end, err := br.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get data size: %w", err)
+	}
+	if _, err := br.Seek(current, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &ifdParser{
+		imgData:         imgData,
+		br:              br,
+		tiffHeaderStart: tiffHeaderStart,
+		endian:          endian,
+		end:             end,
+		visited:         map[int64]bool{},
+	}, nil
+}
+
+// ParseIFD parses the IFD at the current position of br and every sub-IFD
+// (Exif, GPS, Interoperability, SubIFDs) it points to. For metadata.IFDMAIN the whole
+// IFD0 -> IFD1 -> ... chain is walked using the next-IFD offsets.
+// The IFD must use the classic TIFF layout, Parse detects BigTIFF.
 func ParseIFD(imgData *metadata.ImageData, br *metadata.BinaryReader, tiffHeaderStart int64, ifdType metadata.IFDtype, endian binary.ByteOrder) error {
+	ifdStart, err := br.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("Failed to get IFD position: %w", err)
+	}
+	p, err := newIFDParser(imgData, br, tiffHeaderStart, endian)
+	if err != nil {
+		return err
+	}
+	return p.run(ifdStart, ifdType)
+}
+
+// run parses the IFD at ifdStart, walking the whole chain for metadata.IFDMAIN.
+func (p *ifdParser) run(ifdStart int64, ifdType metadata.IFDtype) error {
+	if ifdType != metadata.IFDMAIN {
+		_, err := p.parse(ifdStart, ifdType, 0)
+		return err
+	}
+	return p.parseChain(ifdStart)
+}
+
+// parseChain walks IFD0, IFD1 (thumbnail) and any further pages of a multi-page TIFF.
+// Only a broken IFD0 is an error. A next-IFD offset that points outside the
+// data or back into the chain ends it, keeping the directories read so far.
+func (p *ifdParser) parseChain(ifdStart int64) error {
+	for index := 0; ; index++ {
+		if index >= maxIFDChain {
+			p.imgData.AddWarning(fmt.Errorf("IFD chain longer than %d directories, stopped", maxIFDChain))
+			return nil
+		}
+		if index > 0 && (ifdStart >= p.end || p.visited[ifdStart]) {
+			p.imgData.AddWarning(fmt.Errorf("IFD%d offset %d is out of range or forms a loop, chain stopped", index, ifdStart))
+			return nil
+		}
+		ifd, next, err := p.parseDir(ifdStart, metadata.IFDMAIN, 0)
+		if err != nil && index > 0 {
+			p.imgData.AddWarning(fmt.Errorf("IFD%d: %w, chain stopped", index, err))
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("IFD%d: %w", index, err)
+		}
+		ifd.Index = index
+		if index == 0 {
+			p.imgData.MetaData.MainTags = ifd.Tags
+		}
+		p.imgData.MetaData.IFDs = append(p.imgData.MetaData.IFDs, ifd)
+
+		p.followPointers(ifd.Tags, metadata.IFDMAIN, 0, index == 0)
+		if next == 0 {
+			return nil
+		}
+		ifdStart = p.tiffHeaderStart + int64(next)
+	}
+}
+
+// parse reads a single sub-IFD, stores its tags and follows the pointers it holds.
+func (p *ifdParser) parse(ifdStart int64, ifdType metadata.IFDtype, depth int) (uint64, error) {
+	ifd, next, err := p.parseDir(ifdStart, ifdType, depth)
+	if err != nil {
+		return 0, err
+	}
+
+	switch ifdType {
+	case metadata.IFDMAIN:
+		p.imgData.MetaData.MainTags = ifd.Tags
+	case metadata.IFDEXIF:
+		p.imgData.MetaData.ExifTags = ifd.Tags
+	case metadata.IFDINTROP:
+		p.imgData.MetaData.IntropTags = ifd.Tags
+	case metadata.IFDGPS:
+		p.imgData.MetaData.GPStags = ifd.Tags
+	case metadata.IFDCANON:
+		p.imgData.MetaData.MakerNoteTags = ifd.Tags
+	case metadata.IFDMPF:
+		p.imgData.MetaData.MPFTags = ifd.Tags
+	}
+	p.followPointers(ifd.Tags, ifdType, depth, true)
+	return next, nil
+}
+
+// parseDir reads the directory at ifdStart and returns it with its next-IFD offset.
+func (p *ifdParser) parseDir(ifdStart int64, ifdType metadata.IFDtype, depth int) (metadata.IFD, uint64, error) {
+	if depth > maxIFDDepth {
+		return metadata.IFD{}, 0, fmt.Errorf("%s IFD nested deeper than %d levels", ifdType, maxIFDDepth)
+	}
+	if p.visited[ifdStart] {
+		return metadata.IFD{}, 0, fmt.Errorf("%s IFD at %d was already parsed (loop)", ifdType, ifdStart)
+	}
+	p.visited[ifdStart] = true
+
+	if _, err := p.br.Seek(ifdStart, io.SeekStart); err != nil {
+		return metadata.IFD{}, 0, fmt.Errorf("failed to seek to %s IFD at %d: %w", ifdType, ifdStart, err)
+	}
+	ifdTags, err := p.readTags(ifdType)
+	if err != nil {
+		return metadata.IFD{}, 0, fmt.Errorf("%s IFD: %w", ifdType, err)
+	}
+
+	// the entries are followed by the offset of the next IFD (0 ends the chain)
+	next, err := p.readOffset()
+	if err != nil {
+		// some writers truncate the final offset, treat it as the end of the chain
+		next = 0
+	}
+	return metadata.IFD{Type: ifdType, Offset: ifdStart, Base: p.tiffHeaderStart, Tags: ifdTags}, next, nil
+}
+
+// followPointers parses every sub-IFD that the tags of an ifdType directory
+// point to. The Exif and GPS IFDs describe the image as a whole, so for
+// IFDMAIN they are only followed from IFD0 (primary); the pointers of further
+// pages and of SubIFDs would otherwise replace them. A sub-IFD that fails to
+// parse is recorded in imgData.Warnings, the directory pointing to it is kept.
+func (p *ifdParser) followPointers(ifdTags []metadata.IFDtag, ifdType metadata.IFDtype, depth int, primary bool) {
+	for _, tag := range ifdTags {
+		if ifdType == metadata.IFDMAIN && tag.ID == subIFDsTag {
+			p.parseSubIFDs(tag, depth)
+			continue
+		}
+		if ifdType == metadata.IFDMAIN && !primary {
+			continue
+		}
+		subType, ok := subIFDPointers[ifdType][tag.ID]
+		if !ok {
+			continue
+		}
+		offset, ok := pointerOffset(tag)
+		if !ok {
+			continue
+		}
+		ifdStart := p.tiffHeaderStart + int64(offset)
+		if p.visited[ifdStart] {
+			// a pointer back to an IFD already read, e.g. an Exif IFD pointing to itself
+			p.imgData.AddWarning(fmt.Errorf("%s pointer 0x%04X to the IFD at %d forms a loop, skipped", ifdType, tag.ID, ifdStart))
+			continue
+		}
+		if _, err := p.parse(ifdStart, subType, depth+1); err != nil {
+			p.imgData.AddWarning(fmt.Errorf("%s pointer 0x%04X: %w", ifdType, tag.ID, err))
+		}
+	}
+}
+
+// parseSubIFDs parses the child IFDs listed by a Sub IFDs tag. They hold
+// further images of the same page and use the IFD0 dictionary.
+func (p *ifdParser) parseSubIFDs(tag metadata.IFDtag, depth int) {
+	for _, offset := range pointerOffsets(tag) {
+		index := len(p.imgData.MetaData.SubIFDs)
+		ifd, _, err := p.parseDir(p.tiffHeaderStart+int64(offset), metadata.IFDMAIN, depth+1)
+		if err != nil {
+			p.imgData.AddWarning(fmt.Errorf("SubIFD%d: %w", index, err))
+			continue
+		}
+		ifd.Index = index
+		p.imgData.MetaData.SubIFDs = append(p.imgData.MetaData.SubIFDs, ifd)
+
+		p.followPointers(ifd.Tags, metadata.IFDMAIN, depth+1, false)
+	}
+}
+
+// pointerOffsets returns every IFD offset stored in a pointer tag.
+func pointerOffsets(tag metadata.IFDtag) []uint64 {
+	switch v := tag.Data.(type) {
+	case []uint64:
+		return v
+	case []uint32:
+		return widen(v)
+	case []uint16:
+		return widen(v)
+	}
+	return nil
+}
+
+func widen[T uint16 | uint32](v []T) []uint64 {
+	offsets := make([]uint64, len(v))
+	for i, offset := range v {
+		offsets[i] = uint64(offset)
+	}
+	return offsets
+}
+
+// pointerOffset returns the IFD offset stored in a pointer tag.
+func pointerOffset(tag metadata.IFDtag) (uint64, bool) {
+	offsets := pointerOffsets(tag)
+	if len(offsets) == 0 {
+		return 0, false
+	}
+	return offsets[0], true
+}
+
+// readOffset reads a 4 byte offset, or an 8 byte one in BigTIFF.
+func (p *ifdParser) readOffset() (uint64, error) {
+	if p.big {
+		return p.br.ReadUint64()
+	}
+	v, err := p.br.ReadUint32()
+	return uint64(v), err
+}
+
+// readTags reads the tag count and every entry of the IFD at the current
+// position. Entries are 12 bytes long, 20 bytes in BigTIFF.
+func (p *ifdParser) readTags(ifdType metadata.IFDtype) ([]metadata.IFDtag, error) {
+	br, endian, tiffHeaderStart := p.br, p.endian, p.tiffHeaderStart
 	ifdTags := []metadata.IFDtag{}
 
 	// count of tags
-	tagInBytes, err := br.ReadBytes(2)
-	if err != nil {
-		return err
+	var tagCount uint64
+	if p.big {
+		count, err := br.ReadUint64()
+		if err != nil {
+			return nil, err
+		}
+		tagCount = count
+	} else {
+		tagInBytes, err := br.ReadBytes(2)
+		if err != nil {
+			return nil, err
+		}
+		tagCount = uint64(endian.Uint16(tagInBytes))
 	}
-	tagCount := int(endian.Uint16(tagInBytes))
 
 	for range tagCount {
 		tag := metadata.IFDtag{}
@@ -24,79 +487,93 @@ func ParseIFD(imgData *metadata.ImageData, br *metadata.BinaryReader, tiffHeader
 		// set id
 		idBytes, err := br.ReadBytes(2)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		tag.ID = endian.Uint16(idBytes)
 
 		// set tag name
-		tagName, err := metadata.GetNameFromID(endian.Uint16(idBytes))
-		tag.Name = tagName
+		tag.Name = metadata.GetNameFromID(ifdType, tag.ID)
 
 		// set data type
 		dataTypeBytes, err := br.ReadBytes(2)
 		if err != nil {
-			return fmt.Errorf("Failed to read dataTypeB\n")
+			return nil, fmt.Errorf("Failed to read dataTypeB\n")
 		}
 		dataType, err := metadata.GetDataType(dataTypeBytes, endian)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		tag.DataType = dataType
 
 		// set count of data
-		dataCountBytes, err := br.ReadBytes(4)
+		dataCount, err := p.readOffset()
 		if err != nil {
-			return fmt.Errorf("Failed to read count of data in bytes\n")
+			return nil, fmt.Errorf("Failed to read count of data in bytes\n")
+		}
+		if dataCount > math.MaxUint32 {
+			return nil, fmt.Errorf("tag 0x%04X has too many values: %d", tag.ID, dataCount)
 		}
-		dataCount := endian.Uint32(dataCountBytes)
-		tag.DataCount = dataCount
+		tag.DataCount = uint32(dataCount)
 
 		// check data size
 		dataTypeSize, err := dataType.ByteSize()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		totalTagDataSize := uint32(dataTypeSize) * tag.DataCount
+		totalTagDataSize := uint64(dataTypeSize) * dataCount
 
-		dataOrOffset, err := br.ReadBytes(4)
+		fieldSize := 4
+		if p.big {
+			fieldSize = 8
+		}
+		dataOrOffset, err := br.ReadBytes(fieldSize)
 		if err != nil {
-			return fmt.Errorf("failed to read dataOrOffset")
+			return nil, fmt.Errorf("failed to read dataOrOffset")
 		}
 
-		if totalTagDataSize > 4 {
+		if totalTagDataSize > uint64(fieldSize) {
 			// set absolute data offset
-			offset := endian.Uint32(dataOrOffset)
+			var offset uint64
+			if p.big {
+				offset = endian.Uint64(dataOrOffset)
+			} else {
+				offset = uint64(endian.Uint32(dataOrOffset))
+			}
 			absDataOffset := tiffHeaderStart + int64(offset)
+			if offset > uint64(p.end) || absDataOffset > p.end || totalTagDataSize > uint64(p.end-absDataOffset) {
+				return nil, fmt.Errorf("data of tag 0x%04X at %d runs past the end of the data", tag.ID, absDataOffset)
+			}
 
 			// save current pos
 			currentPos, err := br.Seek(0, io.SeekCurrent)
 			if err != nil {
-				return fmt.Errorf("Failed to save current position\n")
+				return nil, fmt.Errorf("Failed to save current position\n")
 			}
 
 			// jump to offset
 			_, err = br.Seek(absDataOffset, io.SeekStart)
 			if err != nil {
-				return fmt.Errorf("Failed to seek to app1 data tag offset\n")
+				return nil, fmt.Errorf("Failed to seek to app1 data tag offset\n")
 			}
 
 			// read data in bytes
 			dataInBytes, err := br.ReadBytes(int(totalTagDataSize))
 			if err != nil {
-				return fmt.Errorf("Failed to read dataInBytes\n")
+				return nil, fmt.Errorf("Failed to read dataInBytes\n")
 			}
 
 			// decode data
-			dataValue, err := metadata.DecodeTagData(dataInBytes, dataType, dataCount, endian)
+			dataValue, err := metadata.DecodeTagData(dataInBytes, dataType, tag.DataCount, endian)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode data for tag 0x%04X: %w", tag.ID, err)
+			}
 			tag.Data = dataValue
-
-			fmt.Println("Name: ", tag.Name)
-			fmt.Println("Value: ", tag.DataString())
+			tag.Offset = absDataOffset
 
 			// set reader back to original pos
 			_, err = br.Seek(currentPos, io.SeekStart)
 			if err != nil {
-				return fmt.Errorf("Failed to seek to app1 data tag offset\n")
+				return nil, fmt.Errorf("Failed to seek to app1 data tag offset\n")
 			}
 			ifdTags = append(ifdTags, tag)
 		} else {
@@ -105,24 +582,12 @@ func ParseIFD(imgData *metadata.ImageData, br *metadata.BinaryReader, tiffHeader
 			// Decode in line data
 			decodedData, err := metadata.DecodeTagData(actualInLineData, tag.DataType, tag.DataCount, endian)
 			if err != nil {
-				return fmt.Errorf("failed to decode inline data for tag 0x%04X: %w", tag.ID, err)
+				return nil, fmt.Errorf("failed to decode inline data for tag 0x%04X: %w", tag.ID, err)
 			}
 			tag.Data = decodedData
 			ifdTags = append(ifdTags, tag)
 		}
 	}
 
-	switch ifdType {
-	case metadata.IFDMAIN:
-		imgData.MetaData.MainTags = ifdTags
-	case metadata.IFDEXIF:
-		imgData.MetaData.ExifTags = ifdTags
-	case metadata.IFDINTROP:
-		imgData.MetaData.IntropTags = ifdTags
-	case metadata.IFDGPS:
-		imgData.MetaData.GPStags = ifdTags
-
-	}
-	fmt.Println("END OF PARSE IFD")
-	return nil
+	return ifdTags, nil
 }
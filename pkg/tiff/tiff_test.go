@@ -0,0 +1,472 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// parse runs Parse on data, which starts with the TIFF header.
+func parse(t *testing.T, data []byte) (*metadata.ImageData, error) {
+	t.Helper()
+	imgData := &metadata.ImageData{}
+	return imgData, Parse(imgData, bytes.NewReader(data), 0)
+}
+
+// tagString returns the ASCII value of tag id, "" when it is missing.
+func tagString(tags []metadata.IFDtag, id uint16) string {
+	tag, ok := findTag(tags, id)
+	if !ok {
+		return ""
+	}
+	s, _ := tag.Data.(string)
+	return s
+}
+
+func TestParsePointers(t *testing.T) {
+	exifIFD := func() *fixture.IFD {
+		return &fixture.IFD{Entries: []fixture.Entry{
+			fixture.Rational(0x829A, 1, 250),
+			fixture.ASCII(0x9003, "2024:05:01 10:11:12"),
+			fixture.Sub(0xA005, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x0001, "R98")}}),
+		}}
+	}
+	gpsIFD := &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x0001, "N")}}
+
+	selfExif := &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x9003, "2024:05:01 10:11:12")}}
+	selfExif.Entries = append(selfExif.Entries, fixture.Sub(0xA005, selfExif))
+
+	ifd0 := &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon")}}
+	ifd0.Entries = append(ifd0.Entries, fixture.Sub(0x8769, &fixture.IFD{Entries: []fixture.Entry{fixture.Sub(0xA005, ifd0)}}))
+
+	tests := []struct {
+		name         string
+		root         *fixture.IFD
+		wantExif     int
+		wantGPS      string
+		wantInterop  string
+		wantWarnings int
+	}{
+		{
+			name: "exif gps interop",
+			root: &fixture.IFD{Entries: []fixture.Entry{
+				fixture.ASCII(0x010F, "Canon"), fixture.Sub(0x8769, exifIFD()), fixture.Sub(0x8825, gpsIFD),
+			}},
+			wantExif: 3, wantGPS: "N", wantInterop: "R98",
+		},
+		{
+			name:         "interop pointing to its own exif IFD",
+			root:         &fixture.IFD{Entries: []fixture.Entry{fixture.Sub(0x8769, selfExif)}},
+			wantExif:     2,
+			wantWarnings: 1,
+		},
+		{
+			name:         "interop pointing back to IFD0",
+			root:         ifd0,
+			wantExif:     1,
+			wantWarnings: 1,
+		},
+		// a broken pointer loses only its own IFD
+		{
+			name:         "GPS pointer past the end",
+			root:         &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon"), fixture.Sub(0x8769, exifIFD()), fixture.RawOffset(0x8825, 1<<20)}},
+			wantExif:     3,
+			wantInterop:  "R98",
+			wantWarnings: 1,
+		},
+		{
+			name:         "Exif pointer past the end",
+			root:         &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon"), fixture.RawOffset(0x8769, 1<<20), fixture.Sub(0x8825, gpsIFD)}},
+			wantGPS:      "N",
+			wantWarnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			t.Run(tt.name+"/"+order.String(), func(t *testing.T) {
+				imgData, err := parse(t, fixture.TIFF(order, tt.root))
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				md := imgData.MetaData
+				if len(md.MainTags) != len(tt.root.Entries) {
+					t.Errorf("got %d IFD0 tags, want %d", len(md.MainTags), len(tt.root.Entries))
+				}
+				if len(md.ExifTags) != tt.wantExif {
+					t.Errorf("got %d Exif tags, want %d", len(md.ExifTags), tt.wantExif)
+				}
+				if got := tagString(md.GPStags, 0x0001); got != tt.wantGPS {
+					t.Errorf("GPSLatitudeRef = %q, want %q", got, tt.wantGPS)
+				}
+				if got := tagString(md.IntropTags, 0x0001); got != tt.wantInterop {
+					t.Errorf("InteropIndex = %q, want %q", got, tt.wantInterop)
+				}
+				if len(imgData.Warnings) != tt.wantWarnings {
+					t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+				}
+			})
+		}
+	}
+}
+
+func TestParseChain(t *testing.T) {
+	page := func(maker string) *fixture.IFD {
+		return &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, maker)}}
+	}
+	chain := func(ifds ...*fixture.IFD) *fixture.IFD {
+		for i := 1; i < len(ifds); i++ {
+			ifds[i-1].Next = ifds[i]
+		}
+		return ifds[0]
+	}
+
+	selfLoop := page("Canon")
+	selfLoop.Next = selfLoop
+
+	backLoop := chain(page("Canon"), page("Nikon"))
+	backLoop.Next.Next = backLoop
+
+	badNext := page("Canon")
+	badNext.NextOffset = fixture.Offset(1 << 20)
+
+	// every page carries an Exif IFD, only the one of IFD0 describes the image
+	exifPage := func(maker, date string) *fixture.IFD {
+		return &fixture.IFD{Entries: []fixture.Entry{
+			fixture.ASCII(0x010F, maker),
+			fixture.Sub(0x8769, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x9003, date)}}),
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		root         *fixture.IFD
+		wantMakes    []string
+		wantDate     string
+		wantWarnings int
+	}{
+		{name: "single IFD", root: page("Canon"), wantMakes: []string{"Canon"}},
+		{name: "three pages", root: chain(page("Canon"), page("Nikon"), page("Sony")), wantMakes: []string{"Canon", "Nikon", "Sony"}},
+		{name: "IFD0 pointing to itself", root: selfLoop, wantMakes: []string{"Canon"}, wantWarnings: 1},
+		{name: "IFD1 pointing back to IFD0", root: backLoop, wantMakes: []string{"Canon", "Nikon"}, wantWarnings: 1},
+		{name: "next offset past the end", root: badNext, wantMakes: []string{"Canon"}, wantWarnings: 1},
+		{
+			name:      "Exif of later pages ignored",
+			root:      chain(exifPage("Canon", "2024:01:01 00:00:00"), exifPage("Nikon", "1999:12:31 23:59:59")),
+			wantMakes: []string{"Canon", "Nikon"},
+			wantDate:  "2024:01:01 00:00:00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData, err := parse(t, fixture.TIFF(binary.LittleEndian, tt.root))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			md := imgData.MetaData
+			var makes []string
+			for i, ifd := range md.IFDs {
+				if ifd.Index != i {
+					t.Errorf("IFDs[%d].Index = %d", i, ifd.Index)
+				}
+				makes = append(makes, tagString(ifd.Tags, 0x010F))
+			}
+			if !slices.Equal(makes, tt.wantMakes) {
+				t.Errorf("IFD makes = %q, want %q", makes, tt.wantMakes)
+			}
+			if got := tagString(md.MainTags, 0x010F); got != tt.wantMakes[0] {
+				t.Errorf("MainTags Make = %q, want %q", got, tt.wantMakes[0])
+			}
+			if got := tagString(md.ExifTags, 0x9003); got != tt.wantDate {
+				t.Errorf("DateTimeOriginal = %q, want %q", got, tt.wantDate)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseBrokenIFD0(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "bad byte order", data: []byte("XX*\x00\x08\x00\x00\x00")},
+		{name: "bad version", data: []byte("II\x2b\x01\x08\x00\x00\x00")},
+		{name: "IFD0 past the end", data: []byte("II*\x00\xff\x00\x00\x00")},
+		{name: "truncated entries", data: []byte("II*\x00\x08\x00\x00\x00\x05\x00\x0f\x01")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parse(t, tt.data); err == nil {
+				t.Error("Parse() succeeded")
+			}
+		})
+	}
+}
+
+func TestParseExif(t *testing.T) {
+	exif := fixture.TIFF(binary.LittleEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon")}})
+
+	tests := []struct {
+		name    string
+		block   []byte
+		length  int
+		wantErr bool
+	}{
+		{name: "bare TIFF", block: exif, length: len(exif)},
+		{name: "Exif identifier", block: fixture.Concat([]byte("Exif\x00\x00"), exif), length: 6 + len(exif)},
+		{name: "identifier only", block: []byte("Exif\x00\x00"), length: 6, wantErr: true},
+		// reads stop at the end of the block
+		{name: "block cut short", block: exif, length: 8, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the block sits inside a container, after a chunk header
+			data := fixture.Concat([]byte("EXIF"), tt.block)
+			imgData := &metadata.ImageData{}
+			err := ParseExif(imgData, bytes.NewReader(data), 4, int64(tt.length))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExif() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tagString(imgData.MetaData.MainTags, 0x010F) != "Canon" {
+				t.Errorf("MainTags = %v, want Make Canon", imgData.MetaData.MainTags)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	packet := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="4"/></rdf:RDF></x:xmpmeta>`
+	subIFD := func(width uint32) *fixture.IFD {
+		return &fixture.IFD{Entries: []fixture.Entry{fixture.Long(0x0100, width)}}
+	}
+
+	tests := []struct {
+		name          string
+		root          *fixture.IFD
+		wantSubWidths []uint32
+		wantRating    string
+		wantWarnings  int
+	}{
+		{
+			name: "plain",
+			root: &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon"), fixture.Long(0x0100, 640)}},
+		},
+		{
+			name: "SubIFDs",
+			root: &fixture.IFD{Entries: []fixture.Entry{
+				fixture.ASCII(0x010F, "Canon"), fixture.Subs(0x014A, subIFD(4000), subIFD(160)),
+			}},
+			wantSubWidths: []uint32{4000, 160},
+		},
+		{
+			name:       "XMP packet",
+			root:       &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon"), fixture.Bytes(0x02BC, 1, []byte(packet))}},
+			wantRating: "4",
+		},
+		// damaged embedded metadata keeps IFD0
+		{
+			name:         "broken XMP packet",
+			root:         &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon"), fixture.Bytes(0x02BC, 1, []byte("<x:xmpmeta"))}},
+			wantWarnings: 1,
+		},
+		{
+			name: "unexpected types",
+			root: &fixture.IFD{Entries: []fixture.Entry{
+				fixture.ASCII(0x010F, "Canon"), fixture.ASCII(0x02BC, "xmp"), fixture.ASCII(0x83BB, "iptc"), fixture.ASCII(0x8649, "8BIM"),
+			}},
+			wantWarnings: 3,
+		},
+	}
+	for _, tt := range tests {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			t.Run(tt.name+"/"+order.String(), func(t *testing.T) {
+				data := fixture.TIFF(order, tt.root)
+				imgData, err := metadata.Decode(bytes.NewReader(data), int64(len(data)))
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if imgData.Format != metadata.FormatTIFF {
+					t.Errorf("Format = %q, want %q", imgData.Format, metadata.FormatTIFF)
+				}
+				if got := tagString(imgData.MetaData.MainTags, 0x010F); got != "Canon" {
+					t.Errorf("Make = %q, want Canon", got)
+				}
+				var widths []uint32
+				for i, ifd := range imgData.MetaData.SubIFDs {
+					if ifd.Index != i {
+						t.Errorf("SubIFDs[%d].Index = %d", i, ifd.Index)
+					}
+					tag, _ := findTag(ifd.Tags, 0x0100)
+					widths = append(widths, uint32(firstValue(tag)))
+				}
+				if !slices.Equal(widths, tt.wantSubWidths) {
+					t.Errorf("SubIFD widths = %v, want %v", widths, tt.wantSubWidths)
+				}
+				var rating string
+				if imgData.XMP != nil {
+					prop, _ := imgData.XMP.Get(xmp.NSXMP, "Rating")
+					rating = prop.Value
+				}
+				if rating != tt.wantRating {
+					t.Errorf("xmp:Rating = %q, want %q", rating, tt.wantRating)
+				}
+				if len(imgData.Warnings) != tt.wantWarnings {
+					t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeIPTC(t *testing.T) {
+	// 16 bytes, so the LONG variant holds whole values
+	iptc := fixture.Concat(fixture.Dataset(2, 25, "lake"), fixture.Dataset(2, 5, "xx"))
+	long := func(data []byte) fixture.Entry {
+		// writers declare the blob as LONG, the count is in 4 byte units
+		e := fixture.Bytes(iptcTag, 4, data)
+		e.Count /= 4
+		return e
+	}
+
+	tests := []struct {
+		name         string
+		entry        fixture.Entry
+		wantKeyword  string
+		wantWarnings int
+	}{
+		{name: "UNDEFINED", entry: fixture.Undefined(iptcTag, iptc), wantKeyword: "lake"},
+		{name: "LONG", entry: long(iptc), wantKeyword: "lake"},
+		{name: "broken", entry: fixture.Undefined(iptcTag, []byte{0x1C, 2, 25, 0, 9}), wantWarnings: 1},
+	}
+	for _, tt := range tests {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			t.Run(tt.name+"/"+order.String(), func(t *testing.T) {
+				data := fixture.TIFF(order, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon"), tt.entry}})
+				imgData, err := metadata.Decode(bytes.NewReader(data), int64(len(data)))
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				var keyword string
+				if imgData.IPTC != nil {
+					dataset, _ := imgData.IPTC.Get(2, 25)
+					keyword = dataset.Values[0]
+				}
+				if keyword != tt.wantKeyword {
+					t.Errorf("keyword = %q, want %q", keyword, tt.wantKeyword)
+				}
+				// a broken IPTC blob does not hide the tags
+				if len(imgData.Warnings) != tt.wantWarnings || tagString(imgData.MetaData.MainTags, 0x010F) != "Canon" {
+					t.Errorf("got warnings %v, want %d and Make Canon", imgData.Warnings, tt.wantWarnings)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodePhotoshop(t *testing.T) {
+	resources := fixture.Concat(
+		fixture.Resource(0x040B, "", []byte("https://example.com")),
+		fixture.Resource(0x0404, "", fixture.Dataset(2, 25, "lake")),
+	)
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantURL      string
+		wantIPTC     bool
+		wantWarnings int
+	}{
+		{name: "resources", data: resources, wantURL: "https://example.com", wantIPTC: true},
+		{name: "broken", data: []byte("8BIM\x04"), wantWarnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := fixture.TIFF(binary.BigEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Canon"), fixture.Undefined(photoshopTag, tt.data)}})
+			imgData, err := metadata.Decode(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			var url string
+			if imgData.Photoshop != nil {
+				url = imgData.Photoshop.URL
+			}
+			if url != tt.wantURL || (imgData.IPTC != nil) != tt.wantIPTC {
+				t.Errorf("URL, IPTC = %q, %v, want %q, IPTC %v", url, imgData.IPTC, tt.wantURL, tt.wantIPTC)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings || tagString(imgData.MetaData.MainTags, 0x010F) != "Canon" {
+				t.Errorf("got warnings %v, want %d and Make Canon", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseBigTIFF(t *testing.T) {
+	exif := &fixture.IFD{Entries: []fixture.Entry{fixture.Rational(0x829A, 1, 250)}}
+	root := &fixture.IFD{
+		Entries: []fixture.Entry{
+			fixture.ASCII(0x010F, "Canon"),
+			fixture.ASCII(0x0110, "Canon EOS R5"), // longer than the 8 byte value field
+			fixture.Long8(0x0100, 1<<33),
+			fixture.Sub(0x8769, exif),
+		},
+		Next: &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Nikon")}},
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			data := fixture.BigTIFF(order, root)
+			imgData, err := metadata.Decode(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if imgData.Format != metadata.FormatBigTIFF {
+				t.Errorf("Format = %q, want %q", imgData.Format, metadata.FormatBigTIFF)
+			}
+			md := imgData.MetaData
+			if len(md.IFDs) != 2 {
+				t.Fatalf("got %d IFDs, want 2", len(md.IFDs))
+			}
+			if got := tagString(md.MainTags, 0x0110); got != "Canon EOS R5" {
+				t.Errorf("Model = %q, want Canon EOS R5", got)
+			}
+			width, _ := findTag(md.MainTags, 0x0100)
+			if width.DataType != metadata.TypeLong8 || firstValue(width) != 1<<33 {
+				t.Errorf("ImageWidth = %s %v, want LONG8 %d", width.DataType, width.Data, uint64(1<<33))
+			}
+			if len(md.ExifTags) != 1 {
+				t.Errorf("got %d Exif tags, want 1", len(md.ExifTags))
+			}
+			if got := tagString(md.IFDs[1].Tags, 0x010F); got != "Nikon" {
+				t.Errorf("IFD1 Make = %q, want Nikon", got)
+			}
+		})
+	}
+}
+
+func TestParseBigTIFFHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"offset size 4", "II+\x00\x04\x00\x00\x00\x10\x00\x00\x00\x00\x00\x00\x00"},
+		{"reserved not 0", "II+\x00\x08\x00\x01\x00\x10\x00\x00\x00\x00\x00\x00\x00"},
+		{"IFD offset cut short", "MM\x00+\x00\x08\x00\x00\x00\x00"},
+		{"IFD0 past the end", "II+\x00\x08\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parse(t, []byte(tt.data)); err == nil {
+				t.Error("Parse() succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/justikun/metadata-viewer/pkg/icc"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/tiff"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// VP8X feature flags
+const (
+	flagAnimation = 0x02
+	flagXMP       = 0x04
+	flagEXIF      = 0x08
+	flagAlpha     = 0x10
+	flagICC       = 0x20
+)
+
+// metadataChunks are the chunks whose parse errors Decode records as
+// warnings instead of failing the whole file.
+var metadataChunks = map[string]bool{"EXIF": true, "ICCP": true, "XMP ": true}
+
+func init() {
+	metadata.RegisterFormat(metadata.FormatWebP, "RIFF????WEBP", Decode)
+}
+
+// Decode walks the RIFF chunks of the WebP file in r and fills imgData from
+// the chunks it understands. EXIF, ICCP and XMP chunks that fail to parse are
+// recorded in imgData.Warnings; WebP.ICCProfile keeps the raw profile.
+func Decode(r io.ReaderAt, size int64, imgData *metadata.ImageData) error {
+	header := make([]byte, 12)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("failed to read RIFF header: %w", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return errors.New("missing RIFF WEBP header")
+	}
+	// the RIFF size counts everything after the size field
+	end := min(8+int64(binary.LittleEndian.Uint32(header[4:8])), size)
+	imgData.WebP = &metadata.WebP{}
+
+	offset := int64(12)
+	for offset+8 <= end {
+		chunkHeader := make([]byte, 8)
+		if _, err := r.ReadAt(chunkHeader, offset); err != nil {
+			return fmt.Errorf("failed to read chunk header at %d: %w", offset, err)
+		}
+		fourCC := string(chunkHeader[0:4])
+		length := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+		dataStart := offset + 8
+		if length > end-dataStart {
+			return fmt.Errorf("%q chunk at %d: length %d runs past the end of the file", fourCC, offset, length)
+		}
+		if err := parseChunk(r, fourCC, dataStart, length, imgData); err != nil {
+			err = fmt.Errorf("%q chunk at %d: %w", fourCC, offset, err)
+			if !metadataChunks[fourCC] {
+				return err
+			}
+			// a damaged metadata chunk loses only its own metadata
+			imgData.AddWarning(err)
+		}
+		// chunks are padded to an even size
+		offset = dataStart + length + length%2
+	}
+	return nil
+}
+
+// parseChunk reads the chunks that carry metadata or the image size.
+func parseChunk(r io.ReaderAt, fourCC string, dataStart int64, length int64, imgData *metadata.ImageData) error {
+	webp := imgData.WebP
+	switch fourCC {
+	case "EXIF":
+		return tiff.ParseExif(imgData, r, dataStart, length)
+	case "ANMF":
+		// frame data is not needed, only count the frames
+		webp.Frames++
+		return nil
+	case "VP8X", "VP8 ", "VP8L", "ANIM", "ICCP", "XMP ":
+	default:
+		return nil
+	}
+
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, dataStart); err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+	switch fourCC {
+	case "VP8X":
+		if len(data) < 10 {
+			return errors.New("VP8X shorter than 10 bytes")
+		}
+		flags := data[0]
+		webp.Extended = true
+		webp.Features = metadata.WebPFeatures{
+			ICC:       flags&flagICC != 0,
+			Alpha:     flags&flagAlpha != 0,
+			EXIF:      flags&flagEXIF != 0,
+			XMP:       flags&flagXMP != 0,
+			Animation: flags&flagAnimation != 0,
+		}
+		// canvas width and height minus one, 24 bit little endian
+		webp.Width = uint24(data[4:7]) + 1
+		webp.Height = uint24(data[7:10]) + 1
+	case "VP8 ":
+		if webp.Extended {
+			// the canvas size of VP8X wins
+			return nil
+		}
+		// frame tag (3 bytes), start code 9d 01 2a, 14 bit width and height
+		if len(data) < 10 || data[3] != 0x9D || data[4] != 0x01 || data[5] != 0x2A {
+			return errors.New("invalid VP8 key frame header")
+		}
+		webp.Width = uint32(binary.LittleEndian.Uint16(data[6:8]) & 0x3FFF)
+		webp.Height = uint32(binary.LittleEndian.Uint16(data[8:10]) & 0x3FFF)
+	case "VP8L":
+		if webp.Extended {
+			return nil
+		}
+		// signature 0x2f, then 14 bit width and height minus one
+		if len(data) < 5 || data[0] != 0x2F {
+			return errors.New("invalid VP8L header")
+		}
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		webp.Width = bits&0x3FFF + 1
+		webp.Height = (bits>>14)&0x3FFF + 1
+	case "ANIM":
+		if len(data) < 6 {
+			return errors.New("ANIM shorter than 6 bytes")
+		}
+		// background color (4 bytes) and loop count
+		loopCount := binary.LittleEndian.Uint16(data[4:6])
+		webp.LoopCount = &loopCount
+	case "ICCP":
+		webp.ICCProfile = data
+		profile, err := icc.Parse(data)
+		if err != nil {
+			return fmt.Errorf("ICC profile: %w", err)
+		}
+		imgData.ICC = profile
+	case "XMP ":
+		xmpPacket, err := xmp.Parse(data)
+		if err != nil {
+			return err
+		}
+		imgData.XMP = xmpPacket
+	}
+	return nil
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
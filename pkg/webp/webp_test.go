@@ -0,0 +1,153 @@
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/justikun/metadata-viewer/internal/fixture"
+	"github.com/justikun/metadata-viewer/pkg/metadata"
+	"github.com/justikun/metadata-viewer/pkg/xmp"
+)
+
+// chunk returns a RIFF chunk, padded to an even size.
+func chunk(fourCC string, data []byte) []byte {
+	b := fixture.Concat([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(data))), data)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// riff returns a WebP file holding chunks.
+func riff(chunks ...[]byte) []byte {
+	data := fixture.Concat(chunks...)
+	return fixture.Concat([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(4+len(data))), []byte("WEBP"), data)
+}
+
+// vp8x returns a VP8X chunk with flags and a width x height canvas.
+func vp8x(flags byte, width, height uint32) []byte {
+	data := []byte{flags, 0, 0, 0}
+	for _, v := range []uint32{width - 1, height - 1} {
+		data = append(data, byte(v), byte(v>>8), byte(v>>16))
+	}
+	return chunk("VP8X", data)
+}
+
+// vp8 is a lossy key frame header of 320x240.
+var vp8 = chunk("VP8 ", []byte{0x50, 0x01, 0x00, 0x9D, 0x01, 0x2A, 0x40, 0x01, 0xF0, 0x00})
+
+func decode(t *testing.T, data []byte) (*metadata.ImageData, error) {
+	t.Helper()
+	imgData := &metadata.ImageData{}
+	return imgData, Decode(bytes.NewReader(data), int64(len(data)), imgData)
+}
+
+func TestDecode(t *testing.T) {
+	exif := fixture.TIFF(binary.LittleEndian, &fixture.IFD{Entries: []fixture.Entry{fixture.ASCII(0x010F, "Google")}})
+	packet := []byte(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="4"/></rdf:RDF>`)
+	// VP8L: signature, then 14 bit width-1 = 99 and height-1 = 49
+	bits := uint32(99) | uint32(49)<<14
+	loop := uint16(3)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     metadata.WebP
+		wantMake string
+		wantXMP  bool
+	}{
+		{
+			name: "simple lossy",
+			data: riff(vp8),
+			want: metadata.WebP{Width: 320, Height: 240},
+		},
+		{
+			name: "simple lossless",
+			data: riff(chunk("VP8L", binary.LittleEndian.AppendUint32([]byte{0x2F}, bits))),
+			want: metadata.WebP{Width: 100, Height: 50},
+		},
+		{
+			name:     "extended with EXIF and XMP",
+			data:     riff(vp8x(flagEXIF|flagXMP|flagAlpha, 4000, 3000), vp8, chunk("EXIF", exif), chunk("XMP ", packet)),
+			want:     metadata.WebP{Extended: true, Width: 4000, Height: 3000, Features: metadata.WebPFeatures{Alpha: true, EXIF: true, XMP: true}},
+			wantMake: "Google",
+			wantXMP:  true,
+		},
+		{
+			name:     "EXIF with the JPEG identifier",
+			data:     riff(vp8x(flagEXIF, 16, 16), chunk("EXIF", fixture.Concat([]byte("Exif\x00\x00"), exif))),
+			want:     metadata.WebP{Extended: true, Width: 16, Height: 16, Features: metadata.WebPFeatures{EXIF: true}},
+			wantMake: "Google",
+		},
+		{
+			name: "animation",
+			data: riff(vp8x(flagAnimation, 64, 64), chunk("ANIM", []byte{0, 0, 0, 0, 3, 0}), chunk("ANMF", make([]byte, 17)), chunk("ANMF", make([]byte, 17))),
+			want: metadata.WebP{Extended: true, Width: 64, Height: 64, Features: metadata.WebPFeatures{Animation: true}, Frames: 2, LoopCount: &loop},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData, err := decode(t, tt.data)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(*imgData.WebP, tt.want) {
+				t.Errorf("WebP = %+v, want %+v", *imgData.WebP, tt.want)
+			}
+			if tt.wantMake != "" && (len(imgData.MetaData.MainTags) != 1 || imgData.MetaData.MainTags[0].Data != tt.wantMake) {
+				t.Errorf("MainTags = %v, want Make %q", imgData.MetaData.MainTags, tt.wantMake)
+			}
+			if tt.wantXMP {
+				if rating, _ := imgData.XMP.Get(xmp.NSXMP, "Rating"); rating.Value != "4" {
+					t.Errorf("xmp:Rating = %q, want 4", rating.Value)
+				}
+			}
+			if len(imgData.Warnings) != 0 {
+				t.Errorf("got warnings %v", imgData.Warnings)
+			}
+		})
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	valid := riff(vp8x(0, 8, 8), vp8)
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantErr      bool
+		wantWarnings int
+	}{
+		{name: "bad EXIF", data: riff(vp8, chunk("EXIF", []byte("not a TIFF header"))), wantWarnings: 1},
+		{name: "bad ICCP", data: riff(vp8, chunk("ICCP", []byte("short"))), wantWarnings: 1},
+		{name: "bad XMP", data: riff(vp8, chunk("XMP ", []byte("<x/>"))), wantWarnings: 1},
+		{name: "every metadata chunk bad", data: riff(vp8, chunk("EXIF", nil), chunk("ICCP", nil), chunk("XMP ", nil)), wantWarnings: 3},
+		{name: "unknown chunk", data: riff(vp8, chunk("ALPH", []byte{1, 2, 3})), wantWarnings: 0},
+		{name: "short VP8X", data: riff(chunk("VP8X", make([]byte, 4))), wantErr: true},
+		{name: "bad VP8 start code", data: riff(chunk("VP8 ", make([]byte, 10))), wantErr: true},
+		{name: "bad VP8L signature", data: riff(chunk("VP8L", make([]byte, 5))), wantErr: true},
+		{name: "chunk past the end", data: valid[:len(valid)-2], wantErr: true},
+		{name: "not WebP", data: []byte("RIFF\x04\x00\x00\x00WAVE"), wantErr: true},
+		{name: "short header", data: []byte("RIFF"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgData, err := decode(t, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(imgData.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", imgData.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+
+	// the raw profile survives a profile that fails to parse
+	imgData, _ := decode(t, riff(vp8, chunk("ICCP", []byte("short"))))
+	if string(imgData.WebP.ICCProfile) != "short" || imgData.ICC != nil {
+		t.Errorf("ICCProfile = %q, ICC = %v, want the raw profile only", imgData.WebP.ICCProfile, imgData.ICC)
+	}
+}
@@ -0,0 +1,381 @@
+package xmp
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Common namespaces
+const (
+	NSRDF       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	NSXML       = "http://www.w3.org/XML/1998/namespace"
+	NSX         = "adobe:ns:meta/"
+	NSDC        = "http://purl.org/dc/elements/1.1/"
+	NSXMP       = "http://ns.adobe.com/xap/1.0/"
+	NSXMPRights = "http://ns.adobe.com/xap/1.0/rights/"
+	NSXMPMM     = "http://ns.adobe.com/xap/1.0/mm/"
+	NSXMPNote   = "http://ns.adobe.com/xmp/note/"
+	NSPhotoshop = "http://ns.adobe.com/photoshop/1.0/"
+	NSTIFF      = "http://ns.adobe.com/tiff/1.0/"
+	NSEXIF      = "http://ns.adobe.com/exif/1.0/"
+	NSCRS       = "http://ns.adobe.com/camera-raw-settings/1.0/"
+)
+
+type Kind int
+
+const (
+	Simple Kind = iota // plain text value
+	Struct             // named fields
+	Bag                // unordered array
+	Seq                // ordered array
+	Alt                // alternatives, usually language alternatives
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Simple:
+		return "simple"
+	case Struct:
+		return "struct"
+	case Bag:
+		return "bag"
+	case Seq:
+		return "seq"
+	case Alt:
+		return "alt"
+	default:
+		return ""
+	}
+}
+
+// Property is one node of the XMP property tree.
+type Property struct {
+	Namespace string // namespace URI
+	Prefix    string // prefix the packet declared for Namespace
+	Name      string // local name, empty for array items
+	Kind      Kind
+	Value     string     // value of a Simple property
+	Lang      string     // xml:lang qualifier
+	Fields    []Property // fields of a Struct
+	Items     []Property // items of a Bag, Seq or Alt
+}
+
+// QualifiedName returns prefix:name.
+func (p Property) QualifiedName() string {
+	if p.Prefix == "" {
+		return p.Name
+	}
+	return p.Prefix + ":" + p.Name
+}
+
+// Field returns the struct field with the given namespace and local name.
+func (p Property) Field(namespace, name string) (Property, bool) {
+	for _, field := range p.Fields {
+		if field.Namespace == namespace && field.Name == name {
+			return field, true
+		}
+	}
+	return Property{}, false
+}
+
+// String renders the value as text. Language alternatives yield the
+// x-default item and arrays are joined with ", ".
+func (p Property) String() string {
+	switch p.Kind {
+	case Simple:
+		return p.Value
+	case Alt:
+		for _, item := range p.Items {
+			if item.Lang == "x-default" {
+				return item.String()
+			}
+		}
+		if len(p.Items) > 0 {
+			return p.Items[0].String()
+		}
+		return ""
+	case Bag, Seq:
+		parts := make([]string, len(p.Items))
+		for i, item := range p.Items {
+			parts[i] = item.String()
+		}
+		return strings.Join(parts, ", ")
+	default:
+		parts := make([]string, len(p.Fields))
+		for i, field := range p.Fields {
+			parts[i] = field.QualifiedName() + "=" + field.String()
+		}
+		return "{" + strings.Join(parts, " ") + "}"
+	}
+}
+
+// Packet is a parsed XMP packet.
+type Packet struct {
+	// Namespaces maps namespace URIs to the prefix declared in the packet.
+	Namespaces map[string]string
+	Properties []Property
+}
+
+// Get returns the top level property with the given namespace and local name.
+func (p *Packet) Get(namespace, name string) (Property, bool) {
+	for _, prop := range p.Properties {
+		if prop.Namespace == namespace && prop.Name == name {
+			return prop, true
+		}
+	}
+	return Property{}, false
+}
+
+// Parse decodes the RDF/XML of an XMP packet. The <?xpacket?> wrapper and
+// x:xmpmeta element are optional.
+func Parse(data []byte) (*Packet, error) {
+	root, namespaces, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	rdf := root.find(NSRDF, "RDF")
+	if rdf == nil {
+		return nil, errors.New("xmp: no rdf:RDF element")
+	}
+
+	packet := &Packet{Namespaces: namespaces}
+	for _, desc := range rdf.children {
+		if !desc.is(NSRDF, "Description") {
+			continue
+		}
+		packet.Properties = append(packet.Properties, packet.describe(desc)...)
+	}
+	return packet, nil
+}
+
+// describe returns the properties of an rdf:Description, both the attribute
+// shorthand and the child elements.
+func (p *Packet) describe(desc *node) []Property {
+	var props []Property
+	for _, attr := range desc.attrs {
+		if isSyntaxAttr(attr.Name) {
+			continue
+		}
+		props = append(props, p.newProperty(attr.Name, Simple, attr.Value))
+	}
+	for _, child := range desc.children {
+		props = append(props, p.property(child))
+	}
+	return props
+}
+
+// property converts a property element into a Property.
+func (p *Packet) property(n *node) Property {
+	prop := p.newProperty(n.name, Simple, "")
+	prop.Lang = n.attr(NSXML, "lang")
+
+	if resource := n.attr(NSRDF, "resource"); resource != "" {
+		prop.Value = resource
+		return prop
+	}
+	if n.attr(NSRDF, "parseType") == "Resource" {
+		prop.Kind = Struct
+		for _, child := range n.children {
+			prop.Fields = append(prop.Fields, p.property(child))
+		}
+		return prop
+	}
+
+	if len(n.children) == 0 {
+		// attributes other than the RDF syntax are the fields of a struct
+		var fields []Property
+		for _, attr := range n.attrs {
+			if isSyntaxAttr(attr.Name) {
+				continue
+			}
+			fields = append(fields, p.newProperty(attr.Name, Simple, attr.Value))
+		}
+		if len(fields) > 0 {
+			prop.Kind = Struct
+			prop.Fields = fields
+			return prop
+		}
+		prop.Value = strings.TrimSpace(n.text)
+		return prop
+	}
+
+	child := n.children[0]
+	switch {
+	case child.is(NSRDF, "Bag"), child.is(NSRDF, "Seq"), child.is(NSRDF, "Alt"):
+		prop.Kind = map[string]Kind{"Bag": Bag, "Seq": Seq, "Alt": Alt}[child.name.Local]
+		for _, li := range child.children {
+			if !li.is(NSRDF, "li") {
+				continue
+			}
+			item := p.property(li)
+			item.Namespace, item.Prefix, item.Name = "", "", ""
+			prop.Items = append(prop.Items, item)
+		}
+	case child.is(NSRDF, "Description"):
+		prop.Kind = Struct
+		prop.Fields = p.describe(child)
+	default:
+		// a struct written without rdf:Description
+		prop.Kind = Struct
+		for _, field := range n.children {
+			prop.Fields = append(prop.Fields, p.property(field))
+		}
+	}
+	return prop
+}
+
+func (p *Packet) newProperty(name xml.Name, kind Kind, value string) Property {
+	return Property{
+		Namespace: name.Space,
+		Prefix:    p.Namespaces[name.Space],
+		Name:      name.Local,
+		Kind:      kind,
+		Value:     value,
+	}
+}
+
+// isSyntaxAttr reports attributes that are part of the RDF/XML syntax rather than properties.
+func isSyntaxAttr(name xml.Name) bool {
+	return name.Space == NSRDF || name.Space == NSXML || name.Space == "xmlns" ||
+		(name.Space == "" && name.Local == "xmlns")
+}
+
+// MarshalJSON writes the packet as an object keyed by prefix:name. Simple
+// values are strings, structs are objects, Bag and Seq are arrays and
+// language alternatives are objects keyed by language.
+func (p *Packet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(propertiesJSON(p.Properties))
+}
+
+func propertiesJSON(props []Property) map[string]any {
+	obj := make(map[string]any, len(props))
+	for _, prop := range props {
+		obj[prop.QualifiedName()] = propertyJSON(prop)
+	}
+	return obj
+}
+
+func propertyJSON(prop Property) any {
+	switch prop.Kind {
+	case Simple:
+		return prop.Value
+	case Struct:
+		return propertiesJSON(prop.Fields)
+	case Alt:
+		langs := map[string]any{}
+		for _, item := range prop.Items {
+			if item.Lang == "" {
+				langs = nil
+				break
+			}
+			langs[item.Lang] = propertyJSON(item)
+		}
+		if langs != nil {
+			return langs
+		}
+	}
+	items := make([]any, len(prop.Items))
+	for i, item := range prop.Items {
+		items[i] = propertyJSON(item)
+	}
+	return items
+}
+
+// node is a minimal XML element tree.
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*node
+	text     string
+}
+
+func (n *node) is(space, local string) bool {
+	return n.name.Space == space && n.name.Local == local
+}
+
+func (n *node) attr(space, local string) string {
+	for _, a := range n.attrs {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// find returns the first element with the given name in depth first order.
+func (n *node) find(space, local string) *node {
+	if n.is(space, local) {
+		return n
+	}
+	for _, child := range n.children {
+		if found := child.find(space, local); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// parseXML builds the element tree and collects the namespace prefixes.
+func parseXML(data []byte) (*node, map[string]string, error) {
+	// XMP in JPEG may be padded with trailing NULs or whitespace
+	data = bytes.TrimRight(data, "\x00")
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	namespaces := map[string]string{NSXML: "xml"}
+
+	root := &node{}
+	stack := []*node{root}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("xmp: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" {
+					if _, seen := namespaces[a.Value]; !seen {
+						namespaces[a.Value] = a.Name.Local
+					}
+				}
+			}
+			n := &node{name: t.Name, attrs: t.Attr}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			stack[len(stack)-1].text += string(t)
+		}
+	}
+	return root, namespaces, nil
+}
+
+// Merge adds the namespaces and top level properties of other to p.
+// Properties p already holds are kept.
+func (p *Packet) Merge(other *Packet) {
+	for uri, prefix := range other.Namespaces {
+		if _, ok := p.Namespaces[uri]; !ok {
+			p.Namespaces[uri] = prefix
+		}
+	}
+	for _, prop := range other.Properties {
+		if _, ok := p.Get(prop.Namespace, prop.Name); ok {
+			continue
+		}
+		p.Properties = append(p.Properties, prop)
+	}
+}
@@ -0,0 +1,137 @@
+package xmp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// wrap puts the rdf:Description children and attributes into a full packet.
+func wrap(attrs, body string) string {
+	return `<?xpacket begin="` + "\uFEFF" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:exif="http://ns.adobe.com/exif/1.0/" ` + attrs + `>` + body + `</rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // JSON of the packet
+	}{
+		{
+			name: "attribute shorthand",
+			data: wrap(`xmp:Rating="5" xmp:CreatorTool="GIMP"`, ""),
+			want: `{"xmp:CreatorTool":"GIMP","xmp:Rating":"5"}`,
+		},
+		{
+			name: "arrays",
+			data: wrap("", `<dc:creator><rdf:Seq><rdf:li>Jane Doe</rdf:li><rdf:li>John Doe</rdf:li></rdf:Seq></dc:creator>
+				<dc:subject><rdf:Bag><rdf:li>sunset</rdf:li></rdf:Bag></dc:subject>`),
+			want: `{"dc:creator":["Jane Doe","John Doe"],"dc:subject":["sunset"]}`,
+		},
+		{
+			name: "language alternative",
+			data: wrap("", `<dc:title><rdf:Alt><rdf:li xml:lang="x-default">Sunset</rdf:li><rdf:li xml:lang="de">Sonnenuntergang</rdf:li></rdf:Alt></dc:title>`),
+			want: `{"dc:title":{"de":"Sonnenuntergang","x-default":"Sunset"}}`,
+		},
+		{
+			name: "structs",
+			data: wrap("", `<exif:Flash rdf:parseType="Resource"><exif:Fired>False</exif:Fired><exif:Mode>2</exif:Mode></exif:Flash>
+				<xmp:Thumb><rdf:Description xmp:Format="JPEG"/></xmp:Thumb>`),
+			want: `{"exif:Flash":{"exif:Fired":"False","exif:Mode":"2"},"xmp:Thumb":{"xmp:Format":"JPEG"}}`,
+		},
+		{
+			name: "rdf:RDF without wrapper",
+			data: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:dc="http://purl.org/dc/elements/1.1/">
+				<rdf:Description><dc:format>image/jpeg</dc:format></rdf:Description></rdf:RDF>`,
+			want: `{"dc:format":"image/jpeg"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packet, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := json.Marshal(packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"no rdf:RDF", `<x:xmpmeta xmlns:x="adobe:ns:meta/"></x:xmpmeta>`},
+		{"not XML", "\x00\x01\x02"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.data)); err == nil {
+			t.Errorf("%s: Parse() succeeded", tt.name)
+		}
+	}
+}
+
+func TestGetAndString(t *testing.T) {
+	packet, err := Parse([]byte(wrap(`xmp:Rating="5"`,
+		`<dc:title><rdf:Alt><rdf:li xml:lang="de">Sonne</rdf:li><rdf:li xml:lang="x-default">Sun</rdf:li></rdf:Alt></dc:title>
+		<dc:subject><rdf:Bag><rdf:li>a</rdf:li><rdf:li>b</rdf:li></rdf:Bag></dc:subject>`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		namespace, name string
+		want            string
+		wantOK          bool
+	}{
+		{NSXMP, "Rating", "5", true},
+		{NSDC, "title", "Sun", true},
+		{NSDC, "subject", "a, b", true},
+		{NSDC, "creator", "", false},
+		{NSEXIF, "Rating", "", false},
+	}
+	for _, tt := range tests {
+		prop, ok := packet.Get(tt.namespace, tt.name)
+		if ok != tt.wantOK || prop.String() != tt.want {
+			t.Errorf("Get(%s, %s) = %q, %v, want %q, %v", tt.namespace, tt.name, prop.String(), ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	main, err := Parse([]byte(wrap(`xmp:Rating="5"`, "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extended, err := Parse([]byte(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+		xmlns:xap="http://ns.adobe.com/xap/1.0/" xmlns:photoshop="http://ns.adobe.com/photoshop/1.0/">
+		<rdf:Description xap:Rating="1" photoshop:City="Berlin"/></rdf:RDF>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	main.Merge(extended)
+
+	got, err := json.Marshal(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// properties already present are kept, the main packet's prefix wins
+	if want := `{"photoshop:City":"Berlin","xmp:Rating":"5"}`; string(got) != want {
+		t.Errorf("Merge() = %s, want %s", got, want)
+	}
+}